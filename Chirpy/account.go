@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/auth"
+	"Chirpy/internal/database"
+	"Chirpy/internal/export"
+	"Chirpy/internal/problem"
+)
+
+// syncExportMaxChirps is the largest account, by chirp count, whose export is
+// built while the client waits. Larger accounts get a queued export.
+const syncExportMaxChirps = 1000
+
+type DataExport struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Status      string     `json:"status"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	URL         string     `json:"url"`
+}
+
+func mapDataExport(job database.DataExport) DataExport {
+	res := DataExport{
+		ID:        job.ID,
+		CreatedAt: job.CreatedAt,
+		Status:    job.Status,
+		Error:     job.Error.String,
+		URL:       "/api/users/me/exports/" + job.ID.String(),
+	}
+	if job.CompletedAt.Valid {
+		res.CompletedAt = &job.CompletedAt.Time
+	}
+	if job.ExpiresAt.Valid {
+		res.ExpiresAt = &job.ExpiresAt.Time
+	}
+	return res
+}
+
+// requestAccountDeletion revokes every session and schedules the account for
+// deletion once the grace period is over. Logging in again and cancelling
+// within the grace period keeps the account.
+func (cfg *apiConfig) requestAccountDeletion(ctx context.Context, userID uuid.UUID) (database.User, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.User{}, err
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+
+	user, err := q.RequestUserDeletion(ctx, userID)
+	if err != nil {
+		return database.User{}, err
+	}
+	err = q.RevokeRefreshTokensByUserID(ctx, userID)
+	if err != nil {
+		return database.User{}, err
+	}
+	return user, tx.Commit()
+}
+
+func (cfg *apiConfig) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+
+	type response struct {
+		DeletionScheduledFor time.Time `json:"deletion_scheduled_for"`
+	}
+
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "User no longer exists")
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	if params.Password == "" {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "Password is required")
+		return
+	}
+	// A stolen access token alone must not be enough to delete an account.
+	err = auth.CheckPasswordHash(r.Context(), params.Password, user.HashedPassword)
+	if err != nil {
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "Incorrect password")
+		return
+	}
+
+	// Asking again does not push the deletion date back.
+	if !user.DeletionRequestedAt.Valid {
+		user, err = cfg.requestAccountDeletion(r.Context(), user.ID)
+		if err != nil {
+			respondWithInternalError(w, r, err)
+			return
+		}
+	}
+
+	respondWithJSON(w, 202, response{DeletionScheduledFor: user.DeletionRequestedAt.Time.Add(cfg.deletionGrace)})
+}
+
+func (cfg *apiConfig) cancelAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	_, err := cfg.dbQueries.CancelUserDeletion(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 404, problem.CodeNotFound, "No account deletion is pending")
+		return
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// exportAccount returns the archive straight away for small accounts. Large
+// accounts, or clients asking with ?async=true, get a queued export to poll.
+func (cfg *apiConfig) exportAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	count, err := cfg.dbQueries.CountChirpsByAuthorID(r.Context(), userID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+
+	if count <= syncExportMaxChirps && r.URL.Query().Get("async") != "true" {
+		archive, err := export.Build(r.Context(), cfg.dbQueries, cfg.media, userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, r, 401, problem.CodeUnauthorized, "User no longer exists")
+			return
+		}
+		if err != nil {
+			respondWithInternalError(w, r, err)
+			return
+		}
+		writeExportArchive(w, archive, time.Now())
+		return
+	}
+
+	// Only one export per user is queued at a time.
+	job, err := cfg.dbQueries.GetActiveDataExport(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		job, err = cfg.dbQueries.CreateDataExport(r.Context(), userID)
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	res := mapDataExport(job)
+	w.Header().Set("Location", res.URL)
+	respondWithJSON(w, 202, res)
+}
+
+// getAccountExport returns the archive once the export is ready, and its
+// status until then.
+func (cfg *apiConfig) getAccountExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathUUID(w, r, "exportID")
+	if !ok {
+		return
+	}
+	job, err := cfg.dbQueries.GetDataExport(r.Context(), database.GetDataExportParams{ID: id, UserID: userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Export not found")
+		return
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if job.Status == export.StatusReady {
+		writeExportArchive(w, job.Archive, job.CompletedAt.Time)
+		return
+	}
+
+	status := 202
+	if job.Status == export.StatusFailed {
+		status = 200
+	}
+	respondWithJSON(w, status, mapDataExport(job))
+}
+
+func writeExportArchive(w http.ResponseWriter, archive []byte, createdAt time.Time) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chirpy-export-%s.zip"`, createdAt.UTC().Format("2006-01-02")))
+	w.WriteHeader(200)
+	w.Write(archive)
+}
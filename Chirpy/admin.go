@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"Chirpy/internal/auth"
+	"Chirpy/internal/config"
+	"Chirpy/internal/database"
+	"Chirpy/internal/retention"
+	"Chirpy/internal/webhooks"
+)
+
+// The admin commands go through the same queries and helpers as the
+// handlers, so an account created or changed here is indistinguishable from
+// one changed through the API.
+
+// runUser implements "chirpy user create|promote|disable".
+func runUser(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: chirpy user create|promote|disable")
+	}
+	switch args[0] {
+	case "create":
+		return runUserCreate(ctx, cfg, args[1:])
+	case "promote":
+		return runUserPromote(ctx, cfg, args[1:])
+	case "disable":
+		return runUserDisable(ctx, cfg, args[1:])
+	default:
+		return errors.New("usage: chirpy user create|promote|disable")
+	}
+}
+
+func runUserCreate(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("user create", flag.ContinueOnError)
+	email := flags.String("email", "", "email address of the account")
+	handle := flags.String("handle", "", "handle of the account, derived from the email when empty")
+	password := flags.String("password", "", "password of the account, read from stdin when empty")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *email == "" || flags.NArg() != 0 {
+		return errors.New("usage: chirpy user create -email EMAIL [-handle HANDLE] [-password PASSWORD]")
+	}
+	if *password == "" {
+		*password, err = readLine(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("read password: %w", err)
+		}
+	}
+	if *password == "" {
+		return errors.New("password is required")
+	}
+	hashedPass, err := auth.HashedPassword(ctx, *password)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return errors.New("password must be at most 72 bytes")
+	}
+	if err != nil {
+		return err
+	}
+
+	admin, err := openAdmin(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer admin.db.Close()
+	if *handle != "" {
+		*handle, err = normalizeHandle(*handle)
+		if err != nil {
+			return err
+		}
+	} else {
+		*handle, err = admin.availableHandle(ctx, handleFromEmail(*email))
+		if err != nil {
+			return err
+		}
+	}
+	user, err := admin.dbQueries.CreateUser(ctx, database.CreateUserParams{
+		Email:          *email,
+		HashedPassword: hashedPass,
+		Handle:         *handle,
+	})
+	if isUniqueViolation(err) {
+		return errors.New("email or handle is already registered")
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created user %s (@%s)\n", user.ID, user.Handle)
+	return nil
+}
+
+// readLine reads one line from r without its line ending, so a password can
+// be piped in rather than left in the shell history.
+func readLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func runUserPromote(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("user promote", flag.ContinueOnError)
+	toAdmin := flags.Bool("admin", false, "make the user an admin instead of giving them Chirpy Red")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: chirpy user promote [-admin] USER")
+	}
+	admin, err := openAdmin(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer admin.db.Close()
+	user, err := admin.lookupUser(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *toAdmin {
+		_, err = admin.dbQueries.UpdateUserAdminByID(ctx, database.UpdateUserAdminByIDParams{IsAdmin: true, ID: user.ID})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("@%s is now an admin\n", user.Handle)
+		return nil
+	}
+	// As when Polka reports an upgrade.
+	_, err = admin.dbQueries.UpdateUserChirpyRedByID(ctx, database.UpdateUserChirpyRedByIDParams{IsChirpyRed: true, ID: user.ID})
+	if err != nil {
+		return err
+	}
+	admin.publishWebhook(ctx, webhooks.EventUserUpgraded, webhooks.UserData{UserID: user.ID})
+	fmt.Printf("@%s now has Chirpy Red\n", user.Handle)
+	return nil
+}
+
+func runUserDisable(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: chirpy user disable USER")
+	}
+	admin, err := openAdmin(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer admin.db.Close()
+	user, err := admin.lookupUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if user.DisabledAt.Valid {
+		return fmt.Errorf("@%s was already disabled at %s", user.Handle, user.DisabledAt.Time.UTC().Format(time.RFC3339))
+	}
+	err = admin.disableUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("disabled @%s\n", user.Handle)
+	return nil
+}
+
+// disableUser stops a user logging in and revokes their refresh tokens, so
+// their sessions end once the current access token expires.
+func (cfg *apiConfig) disableUser(ctx context.Context, userID uuid.UUID) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+
+	_, err = q.DisableUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = q.RevokeRefreshTokensByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runTokens implements "chirpy tokens revoke -user USER".
+func runTokens(ctx context.Context, cfg *config.Config, args []string) error {
+	const usage = "usage: chirpy tokens revoke -user USER"
+	if len(args) == 0 || args[0] != "revoke" {
+		return errors.New(usage)
+	}
+	flags := flag.NewFlagSet("tokens revoke", flag.ContinueOnError)
+	ref := flags.String("user", "", "email address, @handle or ID of the user")
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if *ref == "" || flags.NArg() != 0 {
+		return errors.New(usage)
+	}
+	admin, err := openAdmin(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer admin.db.Close()
+	user, err := admin.lookupUser(ctx, *ref)
+	if err != nil {
+		return err
+	}
+	err = admin.dbQueries.RevokeRefreshTokensByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("revoked the refresh tokens of @%s\n", user.Handle)
+	return nil
+}
+
+// runChirps implements "chirpy chirps purge", the retention worker's chirp
+// purge run on demand.
+func runChirps(ctx context.Context, cfg *config.Config, args []string) error {
+	const usage = "usage: chirpy chirps purge [-older-than DURATION]"
+	if len(args) == 0 || args[0] != "purge" {
+		return errors.New(usage)
+	}
+	flags := flag.NewFlagSet("chirps purge", flag.ContinueOnError)
+	olderThan := flags.Duration("older-than", cfg.Retention.ChirpRetention, "purge chirps deleted longer ago than this")
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New(usage)
+	}
+	if *olderThan < 0 {
+		return errors.New("-older-than must not be negative")
+	}
+	admin, err := openAdmin(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer admin.db.Close()
+	n := retention.NewPurger(admin.dbQueries, admin.media, *olderThan, admin.deletionGrace).Purge(ctx)
+	fmt.Printf("purged %d chirps deleted more than %s ago\n", n, *olderThan)
+	return nil
+}
+
+// seedPassword is the password of every seeded account.
+const seedPassword = "chirpy-dev-password"
+
+var seedUsers = []struct {
+	email  string
+	chirps []string
+}{
+	{"alice@example.com", []string{"Hello, Chirpy!", "Anyone else up this early?"}},
+	{"bob@example.com", []string{"First chirp. Be gentle.", "Coffee is a personality trait."}},
+	{"carol@example.com", []string{"Shipping something new today.", "It is always DNS."}},
+}
+
+// runSeed implements "chirpy seed", which fills a dev database with sample
+// accounts and chirps. Accounts that already exist are left alone.
+func runSeed(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: chirpy seed")
+	}
+	if cfg.Platform != "dev" {
+		return errors.New("seed is only available on the dev platform")
+	}
+	admin, err := openAdmin(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer admin.db.Close()
+	hashedPass, err := auth.HashedPassword(ctx, seedPassword)
+	if err != nil {
+		return err
+	}
+	for _, s := range seedUsers {
+		_, err := admin.dbQueries.GetUserByEmail(ctx, s.email)
+		if err == nil {
+			fmt.Printf("skipped %s, which already exists\n", s.email)
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		handle, err := admin.availableHandle(ctx, handleFromEmail(s.email))
+		if err != nil {
+			return err
+		}
+		user, err := admin.dbQueries.CreateUser(ctx, database.CreateUserParams{
+			Email:          s.email,
+			HashedPassword: hashedPass,
+			Handle:         handle,
+		})
+		if err != nil {
+			return err
+		}
+		for _, body := range s.chirps {
+			chirp, _, err := admin.createChirpWithAttachments(ctx, database.CreateChirpParams{Body: cleanChirpBody(body), UserID: user.ID}, sql.NullTime{}, nil)
+			if err != nil {
+				return err
+			}
+			admin.chirpPublished(ctx, chirp)
+		}
+		fmt.Printf("created %s (@%s) with %d chirps\n", s.email, user.Handle, len(s.chirps))
+	}
+	fmt.Printf("seeded accounts log in with the password %q\n", seedPassword)
+	return nil
+}
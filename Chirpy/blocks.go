@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/auth"
+	"Chirpy/internal/database"
+	"Chirpy/internal/notifications"
+	"Chirpy/internal/problem"
+)
+
+// viewerID returns the user making the request, or uuid.Nil for anonymous
+// requests and invalid tokens.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.UUID {
+	authToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil
+	}
+	userID, err := auth.ValidateJWT(r.Context(), authToken, []byte(cfg.token))
+	if err != nil {
+		return uuid.Nil
+	}
+	setRequestUser(r, userID)
+	return userID
+}
+
+// hiddenAuthors returns the users whose chirps the viewer should not see:
+// anyone they blocked or muted, and anyone who blocked them.
+func (cfg *apiConfig) hiddenAuthors(ctx context.Context, viewerID uuid.UUID) (map[uuid.UUID]bool, error) {
+	hidden := make(map[uuid.UUID]bool)
+	if viewerID == uuid.Nil {
+		return hidden, nil
+	}
+	ids, err := cfg.dbQueries.ListHiddenAuthorIDs(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		hidden[id] = true
+	}
+	return hidden, nil
+}
+
+func filterHiddenChirps(chirps []database.Chirp, hidden map[uuid.UUID]bool) []database.Chirp {
+	if len(hidden) == 0 {
+		return chirps
+	}
+	var visible []database.Chirp
+	for _, chirp := range chirps {
+		if !hidden[chirp.UserID] {
+			visible = append(visible, chirp)
+		}
+	}
+	return visible
+}
+
+// hideOriginals applies hidden to the chirps embedded in res by
+// withOriginals: a rechirp of a hidden author's chirp is dropped, and a quote
+// of one loses its original but keeps its own text.
+func hideOriginals(res []database.Res, hidden map[uuid.UUID]bool) []database.Res {
+	if len(hidden) == 0 {
+		return res
+	}
+	visible := res[:0]
+	for _, c := range res {
+		if c.Original != nil && hidden[c.Original.UserID] {
+			if c.RechirpOfID != nil {
+				continue
+			}
+			c.Original = nil
+		}
+		visible = append(visible, c)
+	}
+	return visible
+}
+
+// rechirpsHidden reports whether chirp rechirps a chirp by a hidden author.
+// Stream events carry no original, so it is looked up; when that fails the
+// rechirp is treated as hidden.
+func (cfg *apiConfig) rechirpsHidden(ctx context.Context, chirp database.Res, hidden map[uuid.UUID]bool) bool {
+	if len(hidden) == 0 || chirp.RechirpOfID == nil {
+		return false
+	}
+	original, err := cfg.dbQueries.ListChirp(ctx, *chirp.RechirpOfID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "blocks: look up rechirped chirp", "err", err)
+		return true
+	}
+	return hidden[original.UserID]
+}
+
+// mentionedBlocker returns the handle of the first user mentioned in body who
+// has blocked author, or "" if there is none.
+func (cfg *apiConfig) mentionedBlocker(ctx context.Context, author uuid.UUID, body string) (string, error) {
+	for _, mention := range notifications.Mentions(body) {
+		user, err := cfg.dbQueries.GetUserByHandle(ctx, mention)
+		if err != nil {
+			continue
+		}
+		blocked, err := cfg.dbQueries.IsBlocked(ctx, database.IsBlockedParams{BlockerID: user.ID, BlockedID: author})
+		if err != nil {
+			return "", err
+		}
+		if blocked {
+			return user.Handle, nil
+		}
+	}
+	return "", nil
+}
+
+// relationTarget authenticates the request and resolves the {userID} path
+// value, writing the error response itself when either fails.
+func (cfg *apiConfig) relationTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	targetID, ok := pathUUID(w, r, "userID")
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	if targetID == userID {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "You cannot block or mute yourself")
+		return uuid.Nil, uuid.Nil, false
+	}
+	_, err := cfg.dbQueries.GetUserByID(r.Context(), targetID)
+	if err != nil {
+		respondWithError(w, r, 404, problem.CodeNotFound, "User not found")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, targetID, true
+}
+
+func (cfg *apiConfig) blockUser(w http.ResponseWriter, r *http.Request) {
+	userID, blockedID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.BlockUser(r.Context(), database.BlockUserParams{BlockerID: userID, BlockedID: blockedID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, blockedID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	n, err := cfg.dbQueries.UnblockUser(r.Context(), database.UnblockUserParams{BlockerID: userID, BlockedID: blockedID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, r, 404, problem.CodeNotFound, "User is not blocked")
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) muteUser(w http.ResponseWriter, r *http.Request) {
+	userID, mutedID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.MuteUser(r.Context(), database.MuteUserParams{MuterID: userID, MutedID: mutedID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unmuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, mutedID, ok := cfg.relationTarget(w, r)
+	if !ok {
+		return
+	}
+	n, err := cfg.dbQueries.UnmuteUser(r.Context(), database.UnmuteUserParams{MuterID: userID, MutedID: mutedID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, r, 404, problem.CodeNotFound, "User is not muted")
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) listBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	cfg.listRelatedUsers(w, r, func(ctx context.Context, userID uuid.UUID, limit, offset int32) ([]database.User, error) {
+		return cfg.dbQueries.ListBlockedUsers(ctx, database.ListBlockedUsersParams{BlockerID: userID, Limit: limit, Offset: offset})
+	})
+}
+
+func (cfg *apiConfig) listMutedUsers(w http.ResponseWriter, r *http.Request) {
+	cfg.listRelatedUsers(w, r, func(ctx context.Context, userID uuid.UUID, limit, offset int32) ([]database.User, error) {
+		return cfg.dbQueries.ListMutedUsers(ctx, database.ListMutedUsersParams{MuterID: userID, Limit: limit, Offset: offset})
+	})
+}
+
+func (cfg *apiConfig) listRelatedUsers(w http.ResponseWriter, r *http.Request, list func(ctx context.Context, userID uuid.UUID, limit, offset int32) ([]database.User, error)) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pageParams(w, r, 50, 200)
+	if !ok {
+		return
+	}
+	users, err := list(r.Context(), userID, limit, offset)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	res := []Profile{}
+	for _, u := range users {
+		res = append(res, cfg.mapProfile(u))
+	}
+	respondWithJSON(w, 200, res)
+}
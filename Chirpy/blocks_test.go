@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+)
+
+func TestHideOriginals(t *testing.T) {
+	hiddenUser, visibleUser := uuid.New(), uuid.New()
+	hiddenChirp := &database.Res{ID: uuid.New(), UserID: hiddenUser}
+	visibleChirp := &database.Res{ID: uuid.New(), UserID: visibleUser}
+	hidden := map[uuid.UUID]bool{hiddenUser: true}
+
+	tests := []struct {
+		name         string
+		chirp        database.Res
+		wantKept     bool
+		wantOriginal bool
+	}{
+		{"plain chirp", database.Res{UserID: visibleUser}, true, false},
+		{"rechirp of hidden author", database.Res{UserID: visibleUser, RechirpOfID: &hiddenChirp.ID, Original: hiddenChirp}, false, false},
+		{"quote of hidden author", database.Res{UserID: visibleUser, QuoteOfID: &hiddenChirp.ID, Original: hiddenChirp}, true, false},
+		{"rechirp of visible author", database.Res{UserID: visibleUser, RechirpOfID: &visibleChirp.ID, Original: visibleChirp}, true, true},
+		{"quote of visible author", database.Res{UserID: visibleUser, QuoteOfID: &visibleChirp.ID, Original: visibleChirp}, true, true},
+		{"rechirp of deleted chirp", database.Res{UserID: visibleUser, RechirpOfID: &hiddenChirp.ID}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.chirp.ID = uuid.New()
+			got := hideOriginals([]database.Res{tt.chirp}, hidden)
+			if !tt.wantKept {
+				if len(got) != 0 {
+					t.Errorf("kept %+v, want it dropped", got[0])
+				}
+				return
+			}
+			if len(got) != 1 || got[0].ID != tt.chirp.ID {
+				t.Fatalf("got %+v, want the chirp kept", got)
+			}
+			if hasOriginal := got[0].Original != nil; hasOriginal != tt.wantOriginal {
+				t.Errorf("has original = %v, want %v", hasOriginal, tt.wantOriginal)
+			}
+		})
+	}
+
+	if got := hideOriginals([]database.Res{{RechirpOfID: &hiddenChirp.ID, Original: hiddenChirp}}, nil); len(got) != 1 {
+		t.Errorf("with nothing hidden, got %d chirps, want 1", len(got))
+	}
+}
+
+func TestRechirpsHidden(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "db.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &apiConfig{dbQueries: db}
+	newChirp := func(email string) database.Chirp {
+		t.Helper()
+		user, err := db.CreateUser(ctx, database.CreateUserParams{Email: email, Handle: email[:5]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		chirp, err := db.CreateChirp(ctx, database.CreateChirpParams{Body: "hello", UserID: user.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return chirp
+	}
+	blockedChirp, otherChirp := newChirp("block@example.com"), newChirp("other@example.com")
+	missing := uuid.New()
+	hidden := map[uuid.UUID]bool{blockedChirp.UserID: true}
+
+	tests := []struct {
+		name  string
+		chirp database.Res
+		want  bool
+	}{
+		{"plain chirp", database.Res{UserID: otherChirp.UserID}, false},
+		{"rechirp of hidden author", database.Res{RechirpOfID: &blockedChirp.ID}, true},
+		{"rechirp of visible author", database.Res{RechirpOfID: &otherChirp.ID}, false},
+		{"rechirp of deleted chirp", database.Res{RechirpOfID: &missing}, false},
+		{"quote of hidden author", database.Res{QuoteOfID: &blockedChirp.ID}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.rechirpsHidden(ctx, tt.chirp, hidden); got != tt.want {
+				t.Errorf("rechirpsHidden = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/problem"
+	"Chirpy/internal/scheduler"
+)
+
+const maxCollectionNameLength = 50
+
+type BookmarkCollection struct {
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Name          string    `json:"name"`
+	BookmarkCount int64     `json:"bookmark_count"`
+}
+
+type Bookmark struct {
+	ID           uuid.UUID    `json:"id"`
+	CreatedAt    time.Time    `json:"created_at"`
+	CollectionID *uuid.UUID   `json:"collection_id,omitempty"`
+	Chirp        database.Res `json:"chirp"`
+}
+
+func mapBookmarkCollection(c database.BookmarkCollection) BookmarkCollection {
+	return BookmarkCollection{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Name:      c.Name,
+	}
+}
+
+// bookmarkResponses embeds the bookmarked chirps, keeping the bookmark order.
+func (cfg *apiConfig) bookmarkResponses(r *http.Request, bookmarks []database.Bookmark) ([]Bookmark, error) {
+	ids := make([]uuid.UUID, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		ids = append(ids, b.ChirpID)
+	}
+	chirps, err := cfg.dbQueries.ListChirpsByIDs(r.Context(), ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]database.Res)
+	for _, c := range cfg.chirpResponses(r.Context(), chirps) {
+		byID[c.ID] = c
+	}
+	res := []Bookmark{}
+	for _, b := range bookmarks {
+		bookmark := Bookmark{
+			ID:        b.ID,
+			CreatedAt: b.CreatedAt,
+			Chirp:     byID[b.ChirpID],
+		}
+		if b.CollectionID.Valid {
+			bookmark.CollectionID = &b.CollectionID.UUID
+		}
+		res = append(res, bookmark)
+	}
+	return res, nil
+}
+
+func collectionName(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	return name, name != "" && utf8.RuneCountInString(name) <= maxCollectionNameLength
+}
+
+func (cfg *apiConfig) createBookmarkCollection(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name string `json:"name"`
+	}
+
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	name, ok := collectionName(params.Name)
+	if !ok {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "Collection names must be 1-50 characters")
+		return
+	}
+	collection, err := cfg.dbQueries.CreateBookmarkCollection(r.Context(), database.CreateBookmarkCollectionParams{UserID: userID, Name: name})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 409, problem.CodeConflict, "A collection with that name already exists")
+		return
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 201, mapBookmarkCollection(collection))
+}
+
+func (cfg *apiConfig) listBookmarkCollections(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	rows, err := cfg.dbQueries.ListBookmarkCollections(r.Context(), userID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	res := []BookmarkCollection{}
+	for _, row := range rows {
+		res = append(res, BookmarkCollection{
+			ID:            row.ID,
+			CreatedAt:     row.CreatedAt,
+			UpdatedAt:     row.UpdatedAt,
+			Name:          row.Name,
+			BookmarkCount: row.BookmarkCount,
+		})
+	}
+	respondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) renameBookmarkCollection(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name string `json:"name"`
+	}
+
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathUUID(w, r, "collectionID")
+	if !ok {
+		return
+	}
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	name, ok := collectionName(params.Name)
+	if !ok {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "Collection names must be 1-50 characters")
+		return
+	}
+	collection, err := cfg.dbQueries.RenameBookmarkCollection(r.Context(), database.RenameBookmarkCollectionParams{Name: name, ID: id, UserID: userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Collection not found")
+		return
+	}
+	if isUniqueViolation(err) {
+		respondWithError(w, r, 409, problem.CodeConflict, "A collection with that name already exists")
+		return
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, mapBookmarkCollection(collection))
+}
+
+// deleteBookmarkCollection removes a collection. Its bookmarks are kept and
+// become uncollected.
+func (cfg *apiConfig) deleteBookmarkCollection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathUUID(w, r, "collectionID")
+	if !ok {
+		return
+	}
+	n, err := cfg.dbQueries.DeleteBookmarkCollection(r.Context(), database.DeleteBookmarkCollectionParams{ID: id, UserID: userID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Collection not found")
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// addBookmark bookmarks a chirp. Bookmarking it again moves it to the given
+// collection.
+func (cfg *apiConfig) addBookmark(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChirpID      uuid.UUID  `json:"chirp_id"`
+		CollectionID *uuid.UUID `json:"collection_id"`
+	}
+
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	chirp, err := cfg.dbQueries.ListChirp(r.Context(), params.ChirpID)
+	if err != nil || chirp.Status != scheduler.StatusPublished {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "Chirp does not exist")
+		return
+	}
+	collectionID := uuid.NullUUID{}
+	if params.CollectionID != nil {
+		collection, err := cfg.dbQueries.GetBookmarkCollection(r.Context(), database.GetBookmarkCollectionParams{ID: *params.CollectionID, UserID: userID})
+		if err != nil {
+			respondWithError(w, r, 400, problem.CodeValidationFailed, "Collection does not exist")
+			return
+		}
+		collectionID = uuid.NullUUID{UUID: collection.ID, Valid: true}
+	}
+
+	bookmark, err := cfg.dbQueries.UpsertBookmark(r.Context(), database.UpsertBookmarkParams{UserID: userID, ChirpID: chirp.ID, CollectionID: collectionID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	res, err := cfg.bookmarkResponses(r, []database.Bookmark{bookmark})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 201, res[0])
+}
+
+func (cfg *apiConfig) removeBookmark(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	chirpID, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+	n, err := cfg.dbQueries.DeleteBookmark(r.Context(), database.DeleteBookmarkParams{UserID: userID, ChirpID: chirpID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Bookmark not found")
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// listBookmarks lists the user's bookmarks, newest first, optionally limited
+// to one collection with ?collection_id=.
+func (cfg *apiConfig) listBookmarks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pageParams(w, r, 50, 200)
+	if !ok {
+		return
+	}
+
+	var bookmarks []database.Bookmark
+	if c := r.URL.Query().Get("collection_id"); c != "" {
+		collectionID, err := uuid.Parse(c)
+		if err != nil {
+			respondWithError(w, r, 400, problem.CodeInvalidQuery, "collection_id must be a UUID")
+			return
+		}
+		_, err = cfg.dbQueries.GetBookmarkCollection(r.Context(), database.GetBookmarkCollectionParams{ID: collectionID, UserID: userID})
+		if err != nil {
+			respondWithError(w, r, 404, problem.CodeNotFound, "Collection not found")
+			return
+		}
+		bookmarks, err = cfg.dbQueries.ListBookmarksInCollection(r.Context(), database.ListBookmarksInCollectionParams{
+			UserID:       userID,
+			CollectionID: uuid.NullUUID{UUID: collectionID, Valid: true},
+			Limit:        limit,
+			Offset:       offset,
+		})
+		if err != nil {
+			respondWithInternalError(w, r, err)
+			return
+		}
+	} else {
+		var err error
+		bookmarks, err = cfg.dbQueries.ListBookmarks(r.Context(), database.ListBookmarksParams{UserID: userID, Limit: limit, Offset: offset})
+		if err != nil {
+			respondWithInternalError(w, r, err)
+			return
+		}
+	}
+
+	res, err := cfg.bookmarkResponses(r, bookmarks)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, res)
+}
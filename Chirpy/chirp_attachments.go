@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/config"
+	"Chirpy/internal/database"
+	"Chirpy/internal/media"
+	"Chirpy/internal/problem"
+)
+
+const (
+	maxChirpAttachments = 4
+	maxChirpFormBytes   = maxChirpAttachments*media.MaxUploadBytes + 1<<20
+)
+
+var errTooManyAttachments = errors.New("too many attachments")
+
+type storedAttachment struct {
+	media.Processed
+	Key          string
+	ThumbnailKey string
+}
+
+// newMediaStorage returns the configured storage backend: local disk or an
+// S3-compatible bucket.
+func newMediaStorage(c config.Media) media.Storage {
+	if c.Storage == "s3" {
+		return media.NewS3Storage(
+			c.S3.Endpoint,
+			c.S3.Region,
+			c.S3.Bucket,
+			c.S3.AccessKeyID,
+			c.S3.SecretAccessKey,
+			c.S3.PublicURL,
+		)
+	}
+	return media.NewLocalStorage(c.Dir, c.BaseURL)
+}
+
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// decodeChirpForm reads a multipart chirp: the text in "body", an optional
+// "reply_to_id" and up to maxChirpAttachments files in "attachments".
+func decodeChirpForm(w http.ResponseWriter, r *http.Request) (string, *uuid.UUID, []*multipart.FileHeader, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxChirpFormBytes)
+	err := r.ParseMultipartForm(1 << 20)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	var replyToID *uuid.UUID
+	if v := r.FormValue("reply_to_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		replyToID = &id
+	}
+	files := r.MultipartForm.File["attachments"]
+	if len(files) > maxChirpAttachments {
+		return "", nil, nil, errTooManyAttachments
+	}
+	return r.FormValue("body"), replyToID, files, nil
+}
+
+// respondWithAttachmentError maps an upload error to its problem response.
+func respondWithAttachmentError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr), errors.Is(err, media.ErrTooLarge):
+		respondWithError(w, r, 413, problem.CodePayloadTooLarge, fmt.Sprintf("Images must be at most %d MB and %d megapixels, and GIFs at most %d frames and %[2]d megapixels in total", media.MaxUploadBytes>>20, media.MaxPixels/1_000_000, media.MaxGIFFrames))
+	case errors.Is(err, media.ErrUnsupportedType):
+		respondWithError(w, r, 415, problem.CodeUnsupportedMediaType, "Only JPEG, PNG, GIF and WebP images are supported")
+	case errors.Is(err, errTooManyAttachments):
+		respondWithError(w, r, 400, problem.CodeValidationFailed, fmt.Sprintf("A chirp can have at most %d attachments", maxChirpAttachments))
+	default:
+		respondWithInternalError(w, r, err)
+	}
+}
+
+// isAttachmentInputError reports whether err was caused by the client's
+// upload rather than by the server.
+func isAttachmentInputError(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr) ||
+		errors.Is(err, media.ErrTooLarge) ||
+		errors.Is(err, media.ErrUnsupportedType) ||
+		errors.Is(err, errTooManyAttachments)
+}
+
+// storeAttachments validates and processes every upload before storing any
+// of them, so a bad file does not leave orphaned objects behind.
+func (cfg *apiConfig) storeAttachments(ctx context.Context, files []*multipart.FileHeader) ([]storedAttachment, error) {
+	var processed []media.Processed
+	for _, fh := range files {
+		if fh.Size > media.MaxUploadBytes {
+			return nil, media.ErrTooLarge
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(f, media.MaxUploadBytes+1))
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		p, err := media.Process(data)
+		if err != nil {
+			return nil, err
+		}
+		processed = append(processed, p)
+	}
+
+	var stored []storedAttachment
+	for _, p := range processed {
+		name := uuid.New().String()
+		s := storedAttachment{
+			Processed:    p,
+			Key:          "chirps/" + name + p.Ext,
+			ThumbnailKey: "chirps/" + name + "_thumb.jpg",
+		}
+		err := cfg.media.Put(ctx, s.Key, p.ContentType, p.Data)
+		if err == nil {
+			err = cfg.media.Put(ctx, s.ThumbnailKey, media.ThumbnailContentType, p.Thumbnail)
+		}
+		if err != nil {
+			cfg.deleteStoredAttachments(ctx, append(stored, s))
+			return nil, err
+		}
+		stored = append(stored, s)
+	}
+	return stored, nil
+}
+
+func (cfg *apiConfig) deleteStoredAttachments(ctx context.Context, stored []storedAttachment) {
+	for _, s := range stored {
+		cfg.deleteMedia(ctx, s.Key, s.ThumbnailKey)
+	}
+}
+
+func (cfg *apiConfig) deleteMedia(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		err := cfg.media.Delete(ctx, key)
+		if err != nil {
+			slog.ErrorContext(ctx, "media: delete", "key", key, "err", err)
+		}
+	}
+}
+
+// createChirpWithAttachments inserts a chirp and its attachment rows in one
+// transaction. A valid publishAt creates the chirp in the scheduled state.
+func (cfg *apiConfig) createChirpWithAttachments(ctx context.Context, params database.CreateChirpParams, publishAt sql.NullTime, stored []storedAttachment) (database.Chirp, []database.ChirpAttachment, error) {
+	// A lone insert needs no transaction, which the JSON driver lacks.
+	if len(stored) == 0 {
+		chirp, err := insertChirp(ctx, cfg.dbQueries, params, publishAt)
+		return chirp, nil, err
+	}
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+
+	chirp, err := insertChirp(ctx, q, params, publishAt)
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+	var attachments []database.ChirpAttachment
+	for i, s := range stored {
+		a, err := q.CreateChirpAttachment(ctx, database.CreateChirpAttachmentParams{
+			ChirpID:      chirp.ID,
+			Position:     int32(i),
+			ContentType:  s.ContentType,
+			SizeBytes:    int64(len(s.Data)),
+			Width:        int32(s.Width),
+			Height:       int32(s.Height),
+			StorageKey:   s.Key,
+			ThumbnailKey: s.ThumbnailKey,
+		})
+		if err != nil {
+			return database.Chirp{}, nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return chirp, attachments, tx.Commit()
+}
+
+func insertChirp(ctx context.Context, q database.Querier, params database.CreateChirpParams, publishAt sql.NullTime) (database.Chirp, error) {
+	if !publishAt.Valid {
+		return q.CreateChirp(ctx, params)
+	}
+	return q.CreateScheduledChirp(ctx, database.CreateScheduledChirpParams{
+		Body:      params.Body,
+		UserID:    params.UserID,
+		ReplyToID: params.ReplyToID,
+		QuoteOfID: params.QuoteOfID,
+		PublishAt: publishAt,
+	})
+}
+
+// withAttachments fills in the attachments of every chirp in res.
+func (cfg *apiConfig) withAttachments(ctx context.Context, res []database.Res) []database.Res {
+	if len(res) == 0 {
+		return res
+	}
+	ids := make([]uuid.UUID, 0, len(res))
+	for _, c := range res {
+		ids = append(ids, c.ID)
+	}
+	attachments, err := cfg.dbQueries.ListAttachmentsByChirpIDs(ctx, ids)
+	if err != nil {
+		slog.ErrorContext(ctx, "media: list attachments", "err", err)
+		return res
+	}
+	byChirp := make(map[uuid.UUID][]database.ResAttachment)
+	for _, a := range attachments {
+		byChirp[a.ChirpID] = append(byChirp[a.ChirpID], database.MapSqlAttachmentToJsonAttachment(a, cfg.media.URL))
+	}
+	for i := range res {
+		res[i].Attachments = byChirp[res[i].ID]
+	}
+	return res
+}
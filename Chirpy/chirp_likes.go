@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/notifications"
+	"Chirpy/internal/problem"
+	"Chirpy/internal/scheduler"
+)
+
+func (cfg *apiConfig) likeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+	chirp, err := cfg.dbQueries.ListChirp(r.Context(), id)
+	if err != nil || chirp.Status != scheduler.StatusPublished {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Chirp not found")
+		return
+	}
+	added, err := cfg.dbQueries.LikeChirp(r.Context(), database.LikeChirpParams{ChirpID: chirp.ID, UserID: userID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	// Liking twice is a no-op and must not notify the author again.
+	if added > 0 {
+		cfg.notify(r.Context(), notifications.TypeLike, chirp.UserID, userID, chirp.ID)
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unlikeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.UnlikeChirp(r.Context(), database.UnlikeChirpParams{ChirpID: id, UserID: userID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(204)
+}
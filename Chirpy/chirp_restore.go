@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/config"
+	"Chirpy/internal/database"
+	"Chirpy/internal/problem"
+	"Chirpy/internal/scheduler"
+	"Chirpy/internal/stream"
+	"Chirpy/internal/webhooks"
+)
+
+// chirpRetention returns how long deleted chirps can be restored and how long
+// they are kept before being purged.
+func chirpRetention(c config.Retention) (time.Duration, time.Duration) {
+	window, retention := c.ChirpRestoreWindow, c.ChirpRetention
+	if window > retention {
+		slog.Warn("CHIRP_RESTORE_WINDOW is longer than CHIRP_RETENTION; using CHIRP_RETENTION", "window", window.String(), "retention", retention.String())
+		window = retention
+	}
+	return window, retention
+}
+
+// softDeleteChirp marks a chirp and its rechirps deleted with the same
+// timestamp, which is what restoreChirp uses to bring the rechirps back.
+func (cfg *apiConfig) softDeleteChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+
+	chirp, err := q.DeleteChirpById(ctx, id)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	err = q.SoftDeleteRechirpsOf(ctx, database.SoftDeleteRechirpsOfParams{
+		RechirpOfID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		DeletedAt:   chirp.DeletedAt,
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	return chirp, tx.Commit()
+}
+
+// restoreChirp undoes a deletion by the author or an admin, as long as it
+// happened within the restore window.
+func (cfg *apiConfig) restoreChirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+	chirp, err := cfg.dbQueries.GetDeletedChirp(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Deleted chirp not found")
+		return
+	}
+	if chirp.UserID != userID {
+		user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+		if err != nil || !user.IsAdmin {
+			respondWithError(w, r, 404, problem.CodeNotFound, "Deleted chirp not found")
+			return
+		}
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+	restored, err := q.RestoreChirp(r.Context(), database.RestoreChirpParams{
+		ID:            chirp.ID,
+		WindowSeconds: cfg.restoreWindow.Seconds(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 410, problem.CodeGone, "The restore window for this chirp has passed")
+		return
+	}
+	if isUniqueViolation(err) {
+		// A deleted rechirp whose author has rechirped the original again.
+		respondWithError(w, r, 409, problem.CodeConflict, "This chirp has already been rechirped again")
+		return
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	err = q.RestoreRechirpsOf(r.Context(), database.RestoreRechirpsOfParams{
+		RechirpOfID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		DeletedAt:   chirp.DeletedAt,
+	})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+
+	// Restoring is announced like a new chirp, without notifying mentions
+	// and replies a second time.
+	if restored.Status == scheduler.StatusPublished {
+		cfg.publishWebhook(r.Context(), webhooks.EventChirpCreated, webhooks.ChirpData{ID: restored.ID, UserID: restored.UserID, Body: restored.Body})
+		cfg.publishChirpEvent(r.Context(), stream.EventChirpCreated, restored)
+	}
+	respondWithJSON(w, 200, cfg.chirpResponses(r.Context(), []database.Chirp{restored})[0])
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/problem"
+	"Chirpy/internal/stream"
+)
+
+const streamReplayPageSize = 500
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// publishChirpEvent pushes a chirp event to stream subscribers. Failures are
+// logged rather than surfaced so they never fail the triggering request.
+func (cfg *apiConfig) publishChirpEvent(ctx context.Context, eventType string, chirp database.Chirp) {
+	if cfg.stream == nil {
+		return
+	}
+	err := cfg.stream.Publish(ctx, eventType, chirp)
+	if err != nil {
+		slog.ErrorContext(ctx, "stream: publish", "event", eventType, "err", err)
+	}
+}
+
+// streamParams reads the author_id filter, matching getChirps, and the
+// position to resume from, taken from the Last-Event-ID header or, for
+// clients that cannot set headers, the last_event_id query parameter.
+func streamParams(r *http.Request) (uuid.UUID, int64, error) {
+	query := r.URL.Query()
+	authorID := uuid.Nil
+	if a := query.Get("author_id"); a != "" {
+		id, err := uuid.Parse(a)
+		if err != nil {
+			return uuid.Nil, 0, err
+		}
+		authorID = id
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("last_event_id")
+	}
+	var lastID int64
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			return uuid.Nil, 0, err
+		}
+		lastID = id
+	}
+	return authorID, lastID, nil
+}
+
+// replayChirpEvents sends every stored event after lastID and returns the IDs
+// it sent, so the live subscription can skip duplicates.
+func (cfg *apiConfig) replayChirpEvents(ctx context.Context, authorID uuid.UUID, lastID int64, send func(stream.Event) error) (map[int64]struct{}, error) {
+	sent := make(map[int64]struct{})
+	for {
+		events, err := cfg.stream.Since(ctx, lastID, authorID, streamReplayPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, ev := range events {
+			err := send(ev)
+			if err != nil {
+				return nil, err
+			}
+			sent[ev.ID] = struct{}{}
+			lastID = ev.ID
+		}
+		if len(events) < streamReplayPageSize {
+			return sent, nil
+		}
+	}
+}
+
+func (cfg *apiConfig) streamChirps(w http.ResponseWriter, r *http.Request) {
+	authorID, lastID, err := streamParams(r)
+	if err != nil {
+		respondWithError(w, r, 400, problem.CodeInvalidQuery, "author_id must be a UUID and last_event_id an integer")
+		return
+	}
+	hidden, err := cfg.hiddenAuthors(r.Context(), cfg.viewerID(r))
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's read and write timeouts, which are
+	// meant for ordinary requests.
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	// Subscribe before replaying so nothing published in between is lost.
+	sub := cfg.stream.Hub().Subscribe(authorID)
+	defer cfg.stream.Hub().Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(200)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	send := func(ev stream.Event) error {
+		if hidden[ev.Chirp.UserID] || cfg.rechirpsHidden(r.Context(), ev.Chirp, hidden) {
+			return nil
+		}
+		dat, err := json.Marshal(ev.Chirp)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, dat)
+		return err
+	}
+
+	var replayed map[int64]struct{}
+	if lastID > 0 {
+		replayed, err = cfg.replayChirpEvents(r.Context(), authorID, lastID, send)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "stream: replay", "err", err)
+			return
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if _, dup := replayed[ev.ID]; dup {
+				continue
+			}
+			err = send(ev)
+		}
+		if err != nil || rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+func (cfg *apiConfig) streamChirpsWebSocket(w http.ResponseWriter, r *http.Request) {
+	authorID, lastID, err := streamParams(r)
+	if err != nil {
+		respondWithError(w, r, 400, problem.CodeInvalidQuery, "author_id must be a UUID and last_event_id an integer")
+		return
+	}
+	hidden, err := cfg.hiddenAuthors(r.Context(), cfg.viewerID(r))
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sub := cfg.stream.Hub().Subscribe(authorID)
+	defer cfg.stream.Hub().Unsubscribe(sub)
+
+	// The stream is server to client only; reading is needed to process
+	// control frames and notice when the client goes away.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	conn.SetReadLimit(512)
+	go func() {
+		defer cancel()
+		for {
+			_, _, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(ev stream.Event) error {
+		if hidden[ev.Chirp.UserID] || cfg.rechirpsHidden(ctx, ev.Chirp, hidden) {
+			return nil
+		}
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(ev)
+	}
+
+	var replayed map[int64]struct{}
+	if lastID > 0 {
+		replayed, err = cfg.replayChirpEvents(ctx, authorID, lastID, send)
+		if err != nil {
+			return
+		}
+	}
+
+	ping := time.NewTicker(30 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+		case ev, ok := <-sub.C:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscriber fell behind"), time.Now().Add(time.Second))
+				return
+			}
+			if _, dup := replayed[ev.ID]; dup {
+				continue
+			}
+			err = send(ev)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
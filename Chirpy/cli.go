@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/config"
+	"Chirpy/internal/database"
+)
+
+const usageText = `Usage: chirpy [flags] [command]
+
+Commands:
+  serve                                serve the API (the default)
+  migrate up|down|status               apply, roll back or list migrations
+  user create -email EMAIL [-handle HANDLE] [-password PASSWORD]
+                                       create an account, reading the password
+                                       from stdin when it is not given
+  user promote [-admin] USER           give USER Chirpy Red, or make them an admin
+  user disable USER                    lock USER out of the API and revoke
+                                       their refresh tokens
+  tokens revoke -user USER             revoke every refresh token of USER
+  chirps purge [-older-than DURATION]  remove chirps deleted longer ago than
+                                       DURATION, by default CHIRP_RETENTION
+  seed                                 add sample users and chirps (dev only)
+
+USER is an email address, an @handle or a user ID. Commands read the same
+configuration as the server.
+
+Flags:
+`
+
+func usage() {
+	fmt.Fprint(flag.CommandLine.Output(), usageText)
+	flag.PrintDefaults()
+}
+
+// commandName names the command args runs, for log messages.
+func commandName(args []string) string {
+	if len(args) == 0 {
+		return "serve"
+	}
+	return args[0]
+}
+
+// runCommand runs the command named by args[0], serving when there is none.
+func runCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return runServe(ctx, cfg)
+	}
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "serve":
+		if len(args) != 0 {
+			return errors.New("usage: chirpy serve")
+		}
+		return runServe(ctx, cfg)
+	case "migrate":
+		return runMigrate(ctx, cfg, args)
+	case "user":
+		return runUser(ctx, cfg, args)
+	case "tokens":
+		return runTokens(ctx, cfg, args)
+	case "chirps":
+		return runChirps(ctx, cfg, args)
+	case "seed":
+		return runSeed(ctx, cfg, args)
+	default:
+		return fmt.Errorf("unknown command %q; run \"chirpy -help\" for the list", cmd)
+	}
+}
+
+// openAdmin connects to the database for an admin command and returns the
+// same apiConfig the handlers use. The caller closes its db.
+func openAdmin(ctx context.Context, cfg *config.Config) (*apiConfig, error) {
+	db, err := openDB(ctx, cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	_, err = prepareDB(ctx, db, cfg.Database.AutoMigrate)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return newAPIConfig(cfg, db), nil
+}
+
+// lookupUser finds the user ref names: an @handle, an email address or an
+// ID.
+func (cfg *apiConfig) lookupUser(ctx context.Context, ref string) (database.User, error) {
+	var user database.User
+	var err error
+	switch {
+	case strings.HasPrefix(ref, "@"):
+		handle, herr := normalizeHandle(ref)
+		if herr != nil {
+			return database.User{}, fmt.Errorf("%q: %w", ref, herr)
+		}
+		user, err = cfg.dbQueries.GetUserByHandle(ctx, handle)
+	case strings.Contains(ref, "@"):
+		user, err = cfg.dbQueries.GetUserByEmail(ctx, ref)
+	default:
+		id, perr := uuid.Parse(ref)
+		if perr != nil {
+			return database.User{}, fmt.Errorf("%q is not an email address, @handle or user ID", ref)
+		}
+		user, err = cfg.dbQueries.GetUserByID(ctx, id)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, fmt.Errorf("no user %q", ref)
+	}
+	return user, err
+}
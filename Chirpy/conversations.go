@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/problem"
+)
+
+const (
+	maxConversationParticipants = 10
+	maxMessageLength            = 2000
+)
+
+type ConversationParticipant struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	LastReadAt *time.Time `json:"last_read_at,omitempty"`
+}
+
+type Conversation struct {
+	ID           uuid.UUID                 `json:"id"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+	IsGroup      bool                      `json:"is_group"`
+	Participants []ConversationParticipant `json:"participants"`
+}
+
+type Message struct {
+	ID             uuid.UUID   `json:"id"`
+	CreatedAt      time.Time   `json:"created_at"`
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	SenderID       uuid.UUID   `json:"sender_id"`
+	Body           string      `json:"body"`
+	ReadBy         []uuid.UUID `json:"read_by"`
+}
+
+func mapConversation(c database.Conversation, participants []database.ConversationParticipant) Conversation {
+	res := Conversation{
+		ID:           c.ID,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+		IsGroup:      c.IsGroup,
+		Participants: []ConversationParticipant{},
+	}
+	for _, p := range participants {
+		cp := ConversationParticipant{UserID: p.UserID, JoinedAt: p.JoinedAt}
+		if p.LastReadAt.Valid {
+			cp.LastReadAt = &p.LastReadAt.Time
+		}
+		res.Participants = append(res.Participants, cp)
+	}
+	return res
+}
+
+// mapMessage builds a message with its read receipts: every participant other
+// than the sender who has read the conversation since it was sent.
+func mapMessage(m database.Message, participants []database.ConversationParticipant) Message {
+	res := Message{
+		ID:             m.ID,
+		CreatedAt:      m.CreatedAt,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+		ReadBy:         []uuid.UUID{},
+	}
+	for _, p := range participants {
+		if p.UserID == m.SenderID || !p.LastReadAt.Valid {
+			continue
+		}
+		if !p.LastReadAt.Time.Before(m.CreatedAt) {
+			res.ReadBy = append(res.ReadBy, p.UserID)
+		}
+	}
+	return res
+}
+
+// conversationForParticipant loads the conversation named in the path if the
+// caller takes part in it. Non-participants get a 404 so the existence of
+// other people's conversations is not revealed.
+func (cfg *apiConfig) conversationForParticipant(w http.ResponseWriter, r *http.Request) (database.Conversation, uuid.UUID, bool) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return database.Conversation{}, uuid.Nil, false
+	}
+	id, ok := pathUUID(w, r, "conversationID")
+	if !ok {
+		return database.Conversation{}, uuid.Nil, false
+	}
+	_, err := cfg.dbQueries.GetConversationParticipant(r.Context(), database.GetConversationParticipantParams{ConversationID: id, UserID: userID})
+	if err != nil {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Conversation not found")
+		return database.Conversation{}, uuid.Nil, false
+	}
+	conversation, err := cfg.dbQueries.GetConversation(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Conversation not found")
+		return database.Conversation{}, uuid.Nil, false
+	}
+	return conversation, userID, true
+}
+
+// isBlockedWithAny reports whether userID has blocked, or been blocked by,
+// any of others.
+func (cfg *apiConfig) isBlockedWithAny(ctx context.Context, userID uuid.UUID, others []uuid.UUID) (bool, error) {
+	for _, other := range others {
+		if other == userID {
+			continue
+		}
+		blocked, err := cfg.dbQueries.IsBlockedEitherWay(ctx, database.IsBlockedEitherWayParams{UserA: userID, UserB: other})
+		if err != nil {
+			return false, err
+		}
+		if blocked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (cfg *apiConfig) createConversation(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ParticipantIDs []uuid.UUID `json:"participant_ids"`
+	}
+
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	seen := map[uuid.UUID]bool{userID: true}
+	others := []uuid.UUID{}
+	for _, id := range params.ParticipantIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		others = append(others, id)
+	}
+	if len(others) == 0 || len(others)+1 > maxConversationParticipants {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "A conversation needs between 2 and 10 participants")
+		return
+	}
+	for _, id := range others {
+		_, err := cfg.dbQueries.GetUserByID(r.Context(), id)
+		if err != nil {
+			respondWithError(w, r, 400, problem.CodeValidationFailed, "Participant does not exist")
+			return
+		}
+	}
+	blocked, err := cfg.isBlockedWithAny(r.Context(), userID, others)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if blocked {
+		respondWithError(w, r, 403, problem.CodeForbidden, "You cannot message one or more of these users")
+		return
+	}
+
+	isGroup := len(others) > 1
+	status := 201
+	var conversation database.Conversation
+	if !isGroup {
+		// One-to-one conversations are unique per pair of users.
+		existing, err := cfg.dbQueries.FindDirectConversation(r.Context(), database.FindDirectConversationParams{UserA: userID, UserB: others[0]})
+		if err == nil {
+			conversation = existing
+			status = 200
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			respondWithInternalError(w, r, err)
+			return
+		}
+	}
+	if status == 201 {
+		conversation, err = cfg.createConversationWithParticipants(r.Context(), isGroup, append([]uuid.UUID{userID}, others...))
+		if err != nil {
+			respondWithInternalError(w, r, err)
+			return
+		}
+	}
+
+	participants, err := cfg.dbQueries.ListConversationParticipants(r.Context(), conversation.ID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, status, mapConversation(conversation, participants))
+}
+
+func (cfg *apiConfig) createConversationWithParticipants(ctx context.Context, isGroup bool, userIDs []uuid.UUID) (database.Conversation, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Conversation{}, err
+	}
+	defer tx.Rollback()
+	q := cfg.withTx(tx)
+
+	conversation, err := q.CreateConversation(ctx, isGroup)
+	if err != nil {
+		return database.Conversation{}, err
+	}
+	for _, id := range userIDs {
+		err := q.AddConversationParticipant(ctx, database.AddConversationParticipantParams{ConversationID: conversation.ID, UserID: id})
+		if err != nil {
+			return database.Conversation{}, err
+		}
+	}
+	return conversation, tx.Commit()
+}
+
+func (cfg *apiConfig) listConversations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pageParams(w, r, 20, 100)
+	if !ok {
+		return
+	}
+
+	conversations, err := cfg.dbQueries.ListConversationsByUserID(r.Context(), database.ListConversationsByUserIDParams{UserID: userID, Limit: limit, Offset: offset})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	res := []Conversation{}
+	for _, c := range conversations {
+		participants, err := cfg.dbQueries.ListConversationParticipants(r.Context(), c.ID)
+		if err != nil {
+			respondWithInternalError(w, r, err)
+			return
+		}
+		res = append(res, mapConversation(c, participants))
+	}
+	respondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) listMessages(w http.ResponseWriter, r *http.Request) {
+	conversation, _, ok := cfg.conversationForParticipant(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pageParams(w, r, 50, 200)
+	if !ok {
+		return
+	}
+
+	messages, err := cfg.dbQueries.ListMessagesByConversationID(r.Context(), database.ListMessagesByConversationIDParams{ConversationID: conversation.ID, Limit: limit, Offset: offset})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	participants, err := cfg.dbQueries.ListConversationParticipants(r.Context(), conversation.ID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	res := []Message{}
+	for _, m := range messages {
+		res = append(res, mapMessage(m, participants))
+	}
+	respondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) sendMessage(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	conversation, userID, ok := cfg.conversationForParticipant(w, r)
+	if !ok {
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	if params.Body == "" || len(params.Body) > maxMessageLength {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "Message must be between 1 and 2000 characters")
+		return
+	}
+
+	participants, err := cfg.dbQueries.ListConversationParticipants(r.Context(), conversation.ID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	others := []uuid.UUID{}
+	for _, p := range participants {
+		others = append(others, p.UserID)
+	}
+	blocked, err := cfg.isBlockedWithAny(r.Context(), userID, others)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if blocked {
+		respondWithError(w, r, 403, problem.CodeForbidden, "You cannot message one or more of these users")
+		return
+	}
+
+	message, err := cfg.dbQueries.CreateMessage(r.Context(), database.CreateMessageParams{
+		ConversationID: conversation.ID,
+		SenderID:       userID,
+		Body:           params.Body,
+	})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	err = cfg.dbQueries.TouchConversation(r.Context(), conversation.ID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	// Sending a message implies the sender has read everything before it.
+	err = cfg.dbQueries.MarkConversationRead(r.Context(), database.MarkConversationReadParams{ConversationID: conversation.ID, UserID: userID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, 201, mapMessage(message, participants))
+}
+
+func (cfg *apiConfig) markConversationRead(w http.ResponseWriter, r *http.Request) {
+	conversation, userID, ok := cfg.conversationForParticipant(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.MarkConversationRead(r.Context(), database.MarkConversationReadParams{ConversationID: conversation.ID, UserID: userID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(204)
+}
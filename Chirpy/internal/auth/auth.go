@@ -1,10 +1,10 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
-	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -12,9 +12,15 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+
+	"Chirpy/internal/tracing"
 )
 
-func HashedPassword(password string) (string, error) {
+var tracer = tracing.Tracer("Chirpy/internal/auth")
+
+func HashedPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracer.Start(ctx, "auth.HashedPassword")
+	defer span.End()
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 1)
 	if err != nil {
 		return "", err
@@ -23,7 +29,9 @@ func HashedPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
-func CheckPasswordHash(password string, hash string) error {
+func CheckPasswordHash(ctx context.Context, password string, hash string) error {
+	_, span := tracer.Start(ctx, "auth.CheckPasswordHash")
+	defer span.End()
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	if err != nil {
 		return err
@@ -31,7 +39,9 @@ func CheckPasswordHash(password string, hash string) error {
 	return nil
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret []byte, expiresIn time.Duration) (string, error) {
+func MakeJWT(ctx context.Context, userID uuid.UUID, tokenSecret []byte, expiresIn time.Duration) (string, error) {
+	_, span := tracer.Start(ctx, "auth.MakeJWT")
+	defer span.End()
 	// Create a new token object, specifying signing method and the claims
 	// you would like it to contain.
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
@@ -55,7 +65,9 @@ type CustomClaims struct {
 	jwt.RegisteredClaims
 }
 
-func ValidateJWT(tokenString string, tokenSecret []byte) (uuid.UUID, error) {
+func ValidateJWT(ctx context.Context, tokenString string, tokenSecret []byte) (uuid.UUID, error) {
+	_, span := tracer.Start(ctx, "auth.ValidateJWT")
+	defer span.End()
 	claims := &CustomClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return tokenSecret, nil
@@ -69,13 +81,12 @@ func ValidateJWT(tokenString string, tokenSecret []byte) (uuid.UUID, error) {
 	}
 
 	id, err := claims.GetSubject()
-	log.Println("id", id)
 	if err != nil {
-		log.Println(err)
+		return uuid.Nil, err
 	}
 	uuidId, err := uuid.Parse(id)
 	if err != nil {
-		log.Println(err)
+		return uuid.Nil, err
 	}
 	return uuidId, nil
 }
@@ -0,0 +1,107 @@
+// Package certs serves a TLS certificate from disk, picking up renewals
+// without a restart.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader holds the certificate in CertFile and KeyFile and reloads it when
+// either file changes. A renewal that fails to load is logged and the current
+// certificate kept, so a half-written pair never takes the server down.
+type Reloader struct {
+	CertFile     string
+	KeyFile      string
+	PollInterval time.Duration
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewReloader loads the certificate, failing if the initial pair is unusable.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		PollInterval: time.Minute,
+	}
+	_, err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Reload loads the pair if either file changed since the last load, and
+// reports whether it did.
+func (r *Reloader) Reload() (bool, error) {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := r.cert != nil && !modTime.After(r.modTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+	if err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mu.Unlock()
+	return true, nil
+}
+
+func (r *Reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.CertFile, r.KeyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// Run checks for a renewed certificate every PollInterval until ctx is
+// cancelled.
+func (r *Reloader) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reloaded, err := r.Reload()
+		if err != nil {
+			slog.Error("certs: reload", "cert", r.CertFile, "err", err)
+			continue
+		}
+		if reloaded {
+			slog.Info("certs: reloaded certificate", "cert", r.CertFile)
+		}
+	}
+}
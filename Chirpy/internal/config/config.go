@@ -0,0 +1,258 @@
+// Package config loads Chirpy's settings and checks them at startup, so a
+// missing secret or a malformed value stops the server with a clear message
+// instead of surfacing later as a confusing failure.
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"Chirpy/internal/ratelimit"
+	"Chirpy/internal/storage"
+	"Chirpy/internal/tracing"
+)
+
+// Config is every setting Chirpy reads. Each field is named by its env tag in
+// the environment and by its yaml and toml tags, nested by section, in a
+// configuration file. Fields tagged secret are hidden by Print.
+type Config struct {
+	// Platform "dev" enables the reset endpoints and relaxes TOKEN_SECRET.
+	Platform  string     `env:"PLATFORM" yaml:"platform" toml:"platform"`
+	LogLevel  slog.Level `env:"LOG_LEVEL" yaml:"log_level" toml:"log_level"`
+	Server    Server     `yaml:"server" toml:"server"`
+	Database  Database   `yaml:"database" toml:"database"`
+	Auth      Auth       `yaml:"auth" toml:"auth"`
+	Media     Media      `yaml:"media" toml:"media"`
+	RateLimit RateLimit  `yaml:"rate_limit" toml:"rate_limit"`
+	Retention Retention  `yaml:"retention" toml:"retention"`
+	Tracing   Tracing    `yaml:"tracing" toml:"tracing"`
+}
+
+type Server struct {
+	Addr              string        `env:"ADDR" yaml:"addr" toml:"addr"`
+	ReadHeaderTimeout time.Duration `env:"READ_HEADER_TIMEOUT" yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `env:"READ_TIMEOUT" yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      time.Duration `env:"WRITE_TIMEOUT" yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `env:"IDLE_TIMEOUT" yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `env:"SHUTDOWN_TIMEOUT" yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// TLSCertFile and TLSKeyFile enable TLS; set both or neither.
+	TLSCertFile string `env:"TLS_CERT_FILE" yaml:"tls_cert_file" toml:"tls_cert_file"`
+	TLSKeyFile  string `env:"TLS_KEY_FILE" yaml:"tls_key_file" toml:"tls_key_file"`
+	// HTTP2Cleartext serves HTTP/2 without TLS, behind a proxy that
+	// terminates it.
+	HTTP2Cleartext bool `env:"HTTP2_CLEARTEXT" yaml:"http2_cleartext" toml:"http2_cleartext"`
+}
+
+type Database struct {
+	// Driver is "postgres", or "sqlite" or "json", for which URL is the path
+	// of the database file. The json driver only stores users, chirps and
+	// refresh tokens.
+	Driver string `env:"DB_DRIVER" yaml:"driver" toml:"driver"`
+	URL    string `env:"DB_URL" yaml:"url" toml:"url" secret:"url"`
+	// ConnectTimeout is how long startup keeps retrying an unreachable
+	// database before giving up.
+	ConnectTimeout time.Duration `env:"DB_CONNECT_TIMEOUT" yaml:"connect_timeout" toml:"connect_timeout"`
+	// AutoMigrate applies pending migrations at startup. Without it the
+	// server refuses to start until "chirpy migrate up" has been run.
+	AutoMigrate     bool          `env:"DB_AUTO_MIGRATE" yaml:"auto_migrate" toml:"auto_migrate"`
+	MaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+}
+
+type Auth struct {
+	TokenSecret     string        `env:"TOKEN_SECRET" yaml:"token_secret" toml:"token_secret" secret:"true"`
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+	// PolkaKey is the API key Polka sends with its webhooks.
+	PolkaKey string `env:"POLKA_KEY" yaml:"polka_key" toml:"polka_key" secret:"true"`
+}
+
+type Media struct {
+	// Storage is "local" or "s3".
+	Storage string `env:"MEDIA_STORAGE" yaml:"storage" toml:"storage"`
+	Dir     string `env:"MEDIA_DIR" yaml:"dir" toml:"dir"`
+	BaseURL string `env:"MEDIA_BASE_URL" yaml:"base_url" toml:"base_url"`
+	S3      S3     `yaml:"s3" toml:"s3"`
+}
+
+type S3 struct {
+	Endpoint        string `env:"S3_ENDPOINT" yaml:"endpoint" toml:"endpoint"`
+	Region          string `env:"S3_REGION" yaml:"region" toml:"region"`
+	Bucket          string `env:"S3_BUCKET" yaml:"bucket" toml:"bucket"`
+	AccessKeyID     string `env:"S3_ACCESS_KEY_ID" yaml:"access_key_id" toml:"access_key_id"`
+	SecretAccessKey string `env:"S3_SECRET_ACCESS_KEY" yaml:"secret_access_key" toml:"secret_access_key" secret:"true"`
+	PublicURL       string `env:"S3_PUBLIC_URL" yaml:"public_url" toml:"public_url"`
+}
+
+type RateLimit struct {
+	// Store is "memory", "postgres" to share limits between instances, or
+	// "off".
+	Store string `env:"RATE_LIMIT_STORE" yaml:"store" toml:"store"`
+	// Routes override the default route limits, keyed by mux pattern. In
+	// the environment they are written "PATTERN=n/window,...".
+	Routes map[string]ratelimit.Limit `env:"RATE_LIMITS" yaml:"routes" toml:"routes"`
+	// TrustForwardedFor takes the client IP from the last X-Forwarded-For
+	// hop, which is only safe behind a proxy that sets it.
+	TrustForwardedFor bool `env:"RATE_LIMIT_TRUST_FORWARDED_FOR" yaml:"trust_forwarded_for" toml:"trust_forwarded_for"`
+}
+
+type Retention struct {
+	// ChirpRestoreWindow is how long a deleted chirp can be restored, and
+	// ChirpRetention how long it is kept before being purged.
+	ChirpRestoreWindow   time.Duration `env:"CHIRP_RESTORE_WINDOW" yaml:"chirp_restore_window" toml:"chirp_restore_window"`
+	ChirpRetention       time.Duration `env:"CHIRP_RETENTION" yaml:"chirp_retention" toml:"chirp_retention"`
+	AccountDeletionGrace time.Duration `env:"ACCOUNT_DELETION_GRACE" yaml:"account_deletion_grace" toml:"account_deletion_grace"`
+}
+
+type Tracing struct {
+	// Exporter is "none", "otlp" or "console"; see tracing.Setup.
+	Exporter string `env:"OTEL_TRACES_EXPORTER" yaml:"exporter" toml:"exporter"`
+}
+
+// Default returns the settings used where nothing overrides them.
+func Default() *Config {
+	return &Config{
+		LogLevel: slog.LevelInfo,
+		Server: Server{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: Database{
+			Driver:          storage.Postgres,
+			ConnectTimeout:  30 * time.Second,
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Auth: Auth{
+			AccessTokenTTL:  time.Hour,
+			RefreshTokenTTL: 60 * 24 * time.Hour,
+		},
+		Media: Media{
+			Storage: "local",
+			Dir:     "media",
+			BaseURL: "/media/",
+		},
+		RateLimit: RateLimit{
+			Store: "memory",
+		},
+		Retention: Retention{
+			ChirpRestoreWindow:   7 * 24 * time.Hour,
+			ChirpRetention:       30 * 24 * time.Hour,
+			AccountDeletionGrace: 14 * 24 * time.Hour,
+		},
+		Tracing: Tracing{
+			Exporter: tracing.ExporterNone,
+		},
+	}
+}
+
+// Load reads the configuration and validates it. Later sources override
+// earlier ones:
+//
+//  1. the defaults,
+//  2. the YAML or TOML file named by CONFIG_FILE, if set,
+//  3. variables in ./.env,
+//  4. the process environment.
+//
+// An empty variable counts as unset. The returned error lists every problem
+// found, one per line.
+func Load() (*Config, error) {
+	// godotenv never overrides a variable that is already set, which gives
+	// the environment precedence over .env.
+	err := godotenv.Load()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf(".env: %w", err)
+	}
+
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		err := loadFile(path, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("CONFIG_FILE: %w", err)
+		}
+	}
+	err = loadEnv(reflect.ValueOf(cfg).Elem(), os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Print writes the effective configuration to w as NAME=value lines, with
+// secrets replaced by "[redacted]" and any password in DB_URL masked.
+func (c *Config) Print(w io.Writer) error {
+	var err error
+	walk(reflect.ValueOf(c).Elem(), func(v reflect.Value, f reflect.StructField) {
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(w, "%s=%s\n", f.Tag.Get("env"), redact(formatValue(v), f.Tag.Get("secret")))
+	})
+	return err
+}
+
+// redact hides the secret parts of s. A "url" secret keeps its shape, with
+// the passwords masked, whether it is written as a URL or as key=value pairs;
+// anything else is hidden whole.
+func redact(s, secret string) string {
+	switch {
+	case s == "" || secret == "":
+		return s
+	case secret == "url":
+		u, err := url.Parse(s)
+		if err == nil && u.Scheme != "" {
+			return redactURL(u)
+		}
+		if strings.Contains(s, "=") {
+			return dsnPassword.ReplaceAllString(s, "${1}"+redactedPassword)
+		}
+	}
+	return "[redacted]"
+}
+
+// redactedPassword is what url.URL.Redacted puts in place of a password.
+const redactedPassword = "xxxxx"
+
+// passwordParams are the connection parameters lib/pq reads a password from,
+// in a URL's query string as well as in its userinfo.
+var passwordParams = []string{"password", "sslpassword"}
+
+// dsnPassword matches a password in a key=value connection string, where a
+// value may be single-quoted with backslash escapes.
+var dsnPassword = regexp.MustCompile(`(?i)(\b(?:ssl)?password\s*=\s*)('(?:[^'\\]|\\.)*'|\S*)`)
+
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	masked := false
+	for _, name := range passwordParams {
+		if q.Has(name) {
+			q.Set(name, redactedPassword)
+			masked = true
+		}
+	}
+	if masked {
+		u.RawQuery = q.Encode()
+	}
+	return u.Redacted()
+}
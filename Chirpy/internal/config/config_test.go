@@ -0,0 +1,209 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Chirpy/internal/storage"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name, in, secret, want string
+	}{
+		{"not secret", "hunter2", "", "hunter2"},
+		{"empty", "", "true", ""},
+		{"secret", "hunter2", "true", "[redacted]"},
+		{"url without password", "postgres://chirpy@db/chirpy", "url", "postgres://chirpy@db/chirpy"},
+		{"url userinfo", "postgres://chirpy:hunter2@db/chirpy", "url", "postgres://chirpy:xxxxx@db/chirpy"},
+		{"url query", "postgres://chirpy@db/chirpy?password=hunter2", "url", "postgres://chirpy@db/chirpy?password=xxxxx"},
+		{"url query keeps others", "postgres://db/chirpy?sslmode=disable&sslpassword=hunter2", "url", "postgres://db/chirpy?sslmode=disable&sslpassword=xxxxx"},
+		{"url both", "postgres://chirpy:hunter2@db/chirpy?password=hunter3", "url", "postgres://chirpy:xxxxx@db/chirpy?password=xxxxx"},
+		{"dsn", "host=db user=chirpy password=hunter2 dbname=chirpy", "url", "host=db user=chirpy password=xxxxx dbname=chirpy"},
+		{"dsn quoted", `host=db password='hunter 2\' x' dbname=chirpy`, "url", "host=db password=xxxxx dbname=chirpy"},
+		{"dsn spaced", "user=chirpy PASSWORD = hunter2", "url", "user=chirpy PASSWORD = xxxxx"},
+		{"dsn sslpassword", "host=db sslpassword=hunter2", "url", "host=db sslpassword=xxxxx"},
+		{"dsn without password", "host=db user=chirpy", "url", "host=db user=chirpy"},
+		{"bare path", "chirpy.db", "url", "[redacted]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redact(tt.in, tt.secret)
+			if got != tt.want {
+				t.Errorf("redact(%q, %q) = %q, want %q", tt.in, tt.secret, got, tt.want)
+			}
+		})
+	}
+}
+
+// chdir changes into dir for the rest of the test, which Load needs to find
+// a .env file.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// unsetenv unsets key for the rest of the test. godotenv only fills in
+// variables that are unset, so an empty t.Setenv is not enough.
+func unsetenv(t *testing.T, key string) {
+	t.Helper()
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	err := os.WriteFile(path, []byte(data), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLoadPrecedence sets each setting at every level up to the one that
+// should win: defaults, then CONFIG_FILE, then .env, then the environment.
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+	writeFile(t, filepath.Join(dir, "chirpy.yaml"), `
+platform: file
+server:
+  addr: ":9000"
+media:
+  dir: file-media
+`)
+	writeFile(t, filepath.Join(dir, ".env"), "PLATFORM=dotenv\nMEDIA_DIR=dotenv-media\n")
+	t.Setenv("CONFIG_FILE", filepath.Join(dir, "chirpy.yaml"))
+	t.Setenv("DB_URL", "postgres://localhost/chirpy")
+	t.Setenv("MEDIA_DIR", "env-media")
+	for _, key := range []string{"PLATFORM", "ADDR", "READ_TIMEOUT"} {
+		unsetenv(t, key)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.ReadTimeout != Default().Server.ReadTimeout {
+		t.Errorf("READ_TIMEOUT = %s, want the default", cfg.Server.ReadTimeout)
+	}
+	if cfg.Server.Addr != ":9000" {
+		t.Errorf("ADDR = %q, want the file's :9000", cfg.Server.Addr)
+	}
+	if cfg.Platform != "dotenv" {
+		t.Errorf("PLATFORM = %q, want .env's dotenv", cfg.Platform)
+	}
+	if cfg.Media.Dir != "env-media" {
+		t.Errorf("MEDIA_DIR = %q, want the environment's env-media", cfg.Media.Dir)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"unparsable value", map[string]string{"DB_URL": "chirpy.db", "READ_TIMEOUT": "soon"}, `READ_TIMEOUT: invalid duration "soon"`},
+		{"failed validation", map[string]string{"DB_URL": ""}, "DB_URL: required"},
+		{"missing config file", map[string]string{"DB_URL": "chirpy.db", "CONFIG_FILE": "missing.yaml"}, "CONFIG_FILE: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chdir(t, t.TempDir())
+			t.Setenv("CONFIG_FILE", "")
+			t.Setenv("READ_TIMEOUT", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := Load()
+			checkError(t, "Load", err, tt.want)
+		})
+	}
+}
+
+// validConfig returns a configuration that passes Validate and
+// ValidateServer.
+func validConfig() *Config {
+	c := Default()
+	c.Database.URL = "postgres://localhost/chirpy"
+	c.Auth.TokenSecret = strings.Repeat("s", minTokenSecretLen)
+	c.Auth.PolkaKey = "polka"
+	return c
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		// want and wantServer are substrings of the errors of Validate
+		// and ValidateServer, or empty for none.
+		want, wantServer string
+	}{
+		{"valid", func(c *Config) {}, "", ""},
+		{"missing DB_URL", func(c *Config) { c.Database.URL = "" }, "DB_URL: required", ""},
+		{"bad postgres URL", func(c *Config) { c.Database.URL = "postgres://db/%zz" }, "DB_URL: not a valid postgres:// URL", ""},
+		{"sqlite path", func(c *Config) { c.Database.Driver, c.Database.URL = storage.SQLite, "chirpy.db" }, "", ""},
+		{"json driver", func(c *Config) { c.Database.Driver, c.Database.URL = storage.JSON, "chirpy.json" }, "", ""},
+		{"unknown driver", func(c *Config) { c.Database.Driver = "mysql" }, `DB_DRIVER: unknown driver "mysql"`, ""},
+		{"idle above open", func(c *Config) { c.Database.MaxOpenConns, c.Database.MaxIdleConns = 2, 3 }, "DB_MAX_IDLE_CONNS: must not exceed DB_MAX_OPEN_CONNS (2)", ""},
+		{"negative open", func(c *Config) { c.Database.MaxOpenConns = -1 }, "DB_MAX_OPEN_CONNS: must not be negative", ""},
+		{"zero duration", func(c *Config) { c.Server.ReadTimeout = 0 }, "READ_TIMEOUT: must be a positive duration", ""},
+		{"s3 without bucket", func(c *Config) { c.Media.Storage, c.Media.S3.Endpoint = "s3", "http://s3" }, "S3_BUCKET: required with s3 storage", ""},
+		{"unknown media storage", func(c *Config) { c.Media.Storage = "ftp" }, `MEDIA_STORAGE: unknown storage "ftp"`, ""},
+		{"missing TOKEN_SECRET", func(c *Config) { c.Auth.TokenSecret = "" }, "", "TOKEN_SECRET: required"},
+		{"short TOKEN_SECRET", func(c *Config) { c.Auth.TokenSecret = "short" }, "", "TOKEN_SECRET: must be at least 32 bytes"},
+		{"short TOKEN_SECRET in dev", func(c *Config) { c.Auth.TokenSecret, c.Platform = "short", "dev" }, "", ""},
+		{"missing POLKA_KEY", func(c *Config) { c.Auth.PolkaKey = "" }, "", "POLKA_KEY: required"},
+		{"bad ADDR", func(c *Config) { c.Server.Addr = "8080" }, "", `ADDR: "8080" is not of the form host:port`},
+		{"bad port", func(c *Config) { c.Server.Addr = ":http-alt" }, "", `ADDR: invalid port "http-alt"`},
+		{"cert without key", func(c *Config) { c.Server.TLSCertFile = "cert.pem" }, "", "TLS_KEY_FILE: required with TLS_CERT_FILE"},
+		{"key without cert", func(c *Config) { c.Server.TLSKeyFile = "key.pem" }, "", "TLS_CERT_FILE: required with TLS_KEY_FILE"},
+		{"h2c with TLS", func(c *Config) {
+			c.Server.TLSCertFile, c.Server.TLSKeyFile, c.Server.HTTP2Cleartext = "cert.pem", "key.pem", true
+		}, "", "HTTP2_CLEARTEXT: cannot be combined with TLS"},
+		{"postgres rate limits on sqlite", func(c *Config) {
+			c.Database.Driver, c.Database.URL, c.RateLimit.Store = storage.SQLite, "chirpy.db", "postgres"
+		}, "", "RATE_LIMIT_STORE: postgres requires DB_DRIVER=postgres"},
+		{"unknown rate limit store", func(c *Config) { c.RateLimit.Store = "redis" }, "", `RATE_LIMIT_STORE: unknown store "redis"`},
+		{"unknown exporter", func(c *Config) { c.Tracing.Exporter = "zipkin" }, "", `OTEL_TRACES_EXPORTER: unknown exporter "zipkin"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.mutate(c)
+			checkError(t, "Validate", c.Validate(), tt.want)
+			checkError(t, "ValidateServer", c.ValidateServer(), tt.wantServer)
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	c := validConfig()
+	c.Auth.TokenSecret = ""
+	c.Auth.PolkaKey = ""
+	err := c.ValidateServer()
+	checkError(t, "ValidateServer", err, "TOKEN_SECRET: required")
+	checkError(t, "ValidateServer", err, "POLKA_KEY: required")
+}
+
+func checkError(t *testing.T, name string, err error, want string) {
+	t.Helper()
+	switch {
+	case want == "" && err != nil:
+		t.Errorf("%s: unexpected error %v", name, err)
+	case want != "" && err == nil:
+		t.Errorf("%s: no error, want %q", name, want)
+	case want != "" && !strings.Contains(err.Error(), want):
+		t.Errorf("%s: error %q does not mention %q", name, err, want)
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// walk calls fn with every field of v that has an env tag, descending into
+// the section structs.
+func walk(v reflect.Value, fn func(reflect.Value, reflect.StructField)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("env") != "" {
+			fn(v.Field(i), f)
+		} else if f.Type.Kind() == reflect.Struct {
+			walk(v.Field(i), fn)
+		}
+	}
+}
+
+// loadEnv sets each field of v whose variable lookup returns a value.
+func loadEnv(v reflect.Value, lookup func(string) string) error {
+	var errs []error
+	walk(v, func(field reflect.Value, f reflect.StructField) {
+		name := f.Tag.Get("env")
+		s := strings.TrimSpace(lookup(name))
+		if s == "" {
+			return
+		}
+		err := setValue(field, s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// setValue parses s into field. Maps are written "key=value,key=value".
+func setValue(field reflect.Value, s string) error {
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(s)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Map:
+		m := reflect.MakeMap(field.Type())
+		for _, entry := range strings.Split(s, ",") {
+			key, value, ok := strings.Cut(entry, "=")
+			if !ok {
+				return fmt.Errorf("entry %q is not of the form KEY=value", entry)
+			}
+			elem := reflect.New(field.Type().Elem()).Elem()
+			err := setValue(elem, strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("%s: %w", strings.TrimSpace(key), err)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(key)), elem)
+		}
+		field.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// formatValue is the inverse of setValue.
+func formatValue(field reflect.Value) string {
+	if m, ok := field.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return fmt.Sprint(field.Interface())
+		}
+		return string(text)
+	}
+	if field.Kind() == reflect.Map {
+		entries := make([]string, 0, field.Len())
+		iter := field.MapRange()
+		for iter.Next() {
+			entries = append(entries, iter.Key().String()+"="+formatValue(iter.Value()))
+		}
+		sort.Strings(entries)
+		return strings.Join(entries, ",")
+	}
+	return fmt.Sprint(field.Interface())
+}
+
+// loadFile decodes a YAML or TOML file, chosen by its extension, over cfg.
+// Unknown keys are an error, so a misspelt setting is not silently ignored.
+func loadFile(path string, cfg *Config) error {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		err = dec.Decode(cfg)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.DecodeFile(path, cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown setting %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("%s: unsupported format %q, use .yaml, .yml or .toml", path, ext)
+	}
+	return nil
+}
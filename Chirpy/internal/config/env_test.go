@@ -0,0 +1,114 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"Chirpy/internal/ratelimit"
+)
+
+func TestSetValue(t *testing.T) {
+	type settings struct {
+		Duration time.Duration
+		String   string
+		Bool     bool
+		Int      int
+		Level    slog.Level
+		Limits   map[string]ratelimit.Limit
+		Float    float64
+	}
+	tests := []struct {
+		field, in string
+		want      any
+		wantErr   bool
+	}{
+		{"Duration", "90s", 90 * time.Second, false},
+		{"Duration", "90", nil, true},
+		{"String", "hello", "hello", false},
+		{"Bool", "true", true, false},
+		{"Bool", "0", false, false},
+		{"Bool", "yes", nil, true},
+		{"Int", "25", 25, false},
+		{"Int", "2.5", nil, true},
+		{"Level", "debug", slog.LevelDebug, false},
+		{"Level", "loud", nil, true},
+		{"Limits", "POST /api/login=5/1m, GET /api/chirps = 10/1s", map[string]ratelimit.Limit{
+			"POST /api/login": ratelimit.Every(5, time.Minute),
+			"GET /api/chirps": ratelimit.Every(10, time.Second),
+		}, false},
+		{"Limits", "POST /api/login", nil, true},
+		{"Limits", "POST /api/login=lots", nil, true},
+		{"Float", "1.5", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field+"="+tt.in, func(t *testing.T) {
+			var s settings
+			field := reflect.ValueOf(&s).Elem().FieldByName(tt.field)
+			err := setValue(field, tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("setValue(%q) = %v, want an error", tt.in, field.Interface())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("setValue(%q): %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(field.Interface(), tt.want) {
+				t.Errorf("setValue(%q) = %v, want %v", tt.in, field.Interface(), tt.want)
+			}
+			// formatValue writes what setValue reads back unchanged.
+			var again settings
+			formatted := formatValue(field)
+			err = setValue(reflect.ValueOf(&again).Elem().FieldByName(tt.field), formatted)
+			if err != nil {
+				t.Fatalf("setValue(formatValue = %q): %v", formatted, err)
+			}
+			if got := reflect.ValueOf(again).FieldByName(tt.field).Interface(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("round trip through %q = %v, want %v", formatted, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name, data string
+		wantErr    bool
+	}{
+		{"settings.yaml", "server:\n  addr: \":9000\"\n", false},
+		{"settings.yml", "", false},
+		{"settings.toml", "[server]\naddr = \":9000\"\n", false},
+		{"misspelt.yaml", "server:\n  adr: \":9000\"\n", true},
+		{"misspelt.toml", "[server]\nadr = \":9000\"\n", true},
+		{"unknown-section.yaml", "sever:\n  addr: \":9000\"\n", true},
+		{"settings.json", "{}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			err := os.WriteFile(path, []byte(tt.data), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := Default()
+			err = loadFile(path, cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("loadFile succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.data != "" && cfg.Server.Addr != ":9000" {
+				t.Errorf("ADDR = %q, want :9000", cfg.Server.Addr)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"Chirpy/internal/storage"
+	"Chirpy/internal/tracing"
+)
+
+// minTokenSecretLen is the shortest TOKEN_SECRET accepted outside dev: HS256
+// keys should be at least as long as the hash.
+const minTokenSecretLen = 32
+
+// Validate checks the settings every command uses and their combinations,
+// returning every problem found rather than stopping at the first. Settings
+// only the server reads are left to ValidateServer, so the admin commands
+// run without the server's secrets.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := failer(&errs)
+
+	walk(reflect.ValueOf(c).Elem(), func(v reflect.Value, f reflect.StructField) {
+		if f.Type == durationType && v.Int() <= 0 {
+			fail(f.Tag.Get("env"), "must be a positive duration")
+		}
+	})
+
+	switch {
+	case c.Database.URL == "":
+		fail("DB_URL", "required")
+	case c.Database.Driver == storage.Postgres && strings.Contains(c.Database.URL, "://"):
+		_, err := pq.ParseURL(c.Database.URL)
+		if err != nil {
+			// The error can quote the URL, password and all.
+			fail("DB_URL", "not a valid postgres:// URL")
+		}
+	}
+	switch c.Database.Driver {
+	case storage.Postgres, storage.SQLite, storage.JSON:
+	default:
+		fail("DB_DRIVER", "unknown driver %q, want postgres, sqlite or json", c.Database.Driver)
+	}
+	if c.Database.MaxOpenConns < 0 {
+		fail("DB_MAX_OPEN_CONNS", "must not be negative")
+	}
+	if c.Database.MaxIdleConns < 0 {
+		fail("DB_MAX_IDLE_CONNS", "must not be negative")
+	} else if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		fail("DB_MAX_IDLE_CONNS", "must not exceed DB_MAX_OPEN_CONNS (%d)", c.Database.MaxOpenConns)
+	}
+
+	switch c.Media.Storage {
+	case "local":
+		if c.Media.Dir == "" {
+			fail("MEDIA_DIR", "required with local storage")
+		}
+		if c.Media.BaseURL == "" {
+			fail("MEDIA_BASE_URL", "required with local storage")
+		}
+	case "s3":
+		if c.Media.S3.Endpoint == "" {
+			fail("S3_ENDPOINT", "required with s3 storage")
+		}
+		if c.Media.S3.Bucket == "" {
+			fail("S3_BUCKET", "required with s3 storage")
+		}
+	default:
+		fail("MEDIA_STORAGE", "unknown storage %q, want local or s3", c.Media.Storage)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateServer checks the settings only the server reads, in the manner of
+// Validate.
+func (c *Config) ValidateServer() error {
+	var errs []error
+	fail := failer(&errs)
+
+	host, port, err := net.SplitHostPort(c.Server.Addr)
+	if err != nil {
+		fail("ADDR", "%q is not of the form host:port", c.Server.Addr)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		fail("ADDR", "invalid port %q", port)
+	} else if strings.ContainsAny(host, "/ ") {
+		fail("ADDR", "invalid host %q", host)
+	}
+	if c.Server.TLSCertFile != "" && c.Server.TLSKeyFile == "" {
+		fail("TLS_KEY_FILE", "required with TLS_CERT_FILE")
+	}
+	if c.Server.TLSKeyFile != "" && c.Server.TLSCertFile == "" {
+		fail("TLS_CERT_FILE", "required with TLS_KEY_FILE")
+	}
+	if c.Server.HTTP2Cleartext && c.Server.TLSCertFile != "" {
+		fail("HTTP2_CLEARTEXT", "cannot be combined with TLS, which negotiates HTTP/2 itself")
+	}
+
+	switch {
+	case c.Auth.TokenSecret == "":
+		fail("TOKEN_SECRET", "required")
+	case len(c.Auth.TokenSecret) < minTokenSecretLen && c.Platform != "dev":
+		fail("TOKEN_SECRET", "must be at least %d bytes", minTokenSecretLen)
+	}
+	if c.Auth.PolkaKey == "" {
+		fail("POLKA_KEY", "required")
+	}
+
+	switch c.RateLimit.Store {
+	case "memory", "off":
+	case "postgres":
+		if c.Database.Driver != storage.Postgres {
+			fail("RATE_LIMIT_STORE", "postgres requires DB_DRIVER=postgres")
+		}
+	default:
+		fail("RATE_LIMIT_STORE", "unknown store %q, want memory, postgres or off", c.RateLimit.Store)
+	}
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterConsole:
+	default:
+		fail("OTEL_TRACES_EXPORTER", "unknown exporter %q, want none, otlp or console", c.Tracing.Exporter)
+	}
+
+	return errors.Join(errs...)
+}
+
+// failer returns a function that adds a problem with the named setting to
+// errs.
+func failer(errs *[]error) func(name, format string, args ...any) {
+	return func(name, format string, args ...any) {
+		*errs = append(*errs, fmt.Errorf("%s: %s", name, fmt.Sprintf(format, args...)))
+	}
+}
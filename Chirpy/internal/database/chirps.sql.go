@@ -7,29 +7,88 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countChirpsByAuthorID = `-- name: CountChirpsByAuthorID :one
+SELECT COUNT(*) FROM chirps WHERE user_id = $1
+`
+
+func (q *Queries) CountChirpsByAuthorID(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpsByAuthorID, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countRechirpsByChirpIDs = `-- name: CountRechirpsByChirpIDs :many
+SELECT rechirp_of_id, COUNT(*) AS rechirp_count FROM chirps
+WHERE deleted_at IS NULL AND rechirp_of_id = ANY($1::UUID[])
+GROUP BY rechirp_of_id
+`
+
+type CountRechirpsByChirpIDsRow struct {
+	RechirpOfID  uuid.NullUUID
+	RechirpCount int64
+}
+
+func (q *Queries) CountRechirpsByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]CountRechirpsByChirpIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, countRechirpsByChirpIDs, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountRechirpsByChirpIDsRow
+	for rows.Next() {
+		var i CountRechirpsByChirpIDsRow
+		if err := rows.Scan(
+			&i.RechirpOfID,
+			&i.RechirpCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to_id, quote_of_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
   )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	ReplyToID uuid.NullUUID
+	QuoteOfID uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ReplyToID,
+		arg.QuoteOfID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,21 +96,258 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ReplyToID,
+		&i.PublishAt,
+		&i.Status,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const deleteChirpById = `-- name: DeleteChirpById :exec
-DELETE FROM chirps WHERE id = $1
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
+    $2
+  )
+ON CONFLICT (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL AND deleted_at IS NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at
 `
 
-func (q *Queries) DeleteChirpById(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteChirpById, id)
-	return err
+type CreateRechirpParams struct {
+	UserID      uuid.UUID
+	RechirpOfID uuid.NullUUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOfID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.PublishAt,
+		&i.Status,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const createScheduledChirp = `-- name: CreateScheduledChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to_id, quote_of_id, publish_at, status)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    'scheduled'
+  )
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at
+`
+
+type CreateScheduledChirpParams struct {
+	Body      string
+	UserID    uuid.UUID
+	ReplyToID uuid.NullUUID
+	QuoteOfID uuid.NullUUID
+	PublishAt sql.NullTime
+}
+
+func (q *Queries) CreateScheduledChirp(ctx context.Context, arg CreateScheduledChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ReplyToID,
+		arg.QuoteOfID,
+		arg.PublishAt,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.PublishAt,
+		&i.Status,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const deleteChirpById = `-- name: DeleteChirpById :one
+UPDATE chirps SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at
+`
+
+func (q *Queries) DeleteChirpById(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, deleteChirpById, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.PublishAt,
+		&i.Status,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const deleteRechirp = `-- name: DeleteRechirp :one
+DELETE FROM chirps WHERE user_id = $1 AND rechirp_of_id = $2 AND deleted_at IS NULL RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at
+`
+
+type DeleteRechirpParams struct {
+	UserID      uuid.UUID
+	RechirpOfID uuid.NullUUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, deleteRechirp, arg.UserID, arg.RechirpOfID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.PublishAt,
+		&i.Status,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const deleteScheduledChirp = `-- name: DeleteScheduledChirp :execrows
+DELETE FROM chirps WHERE id = $1 AND status = 'scheduled'
+`
+
+func (q *Queries) DeleteScheduledChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteScheduledChirp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getDeletedChirp = `-- name: GetDeletedChirp :one
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) GetDeletedChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.PublishAt,
+		&i.Status,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getRechirp = `-- name: GetRechirp :one
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE user_id = $1 AND rechirp_of_id = $2 AND deleted_at IS NULL
+`
+
+type GetRechirpParams struct {
+	UserID      uuid.UUID
+	RechirpOfID uuid.NullUUID
+}
+
+func (q *Queries) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getRechirp, arg.UserID, arg.RechirpOfID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.PublishAt,
+		&i.Status,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const listAllChirpsByAuthorID = `-- name: ListAllChirpsByAuthorID :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE user_id = $1 ORDER BY created_at ASC
+`
+
+func (q *Queries) ListAllChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listAllChirpsByAuthorID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.PublishAt,
+			&i.Status,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const listChirp = `-- name: ListChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) ListChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -63,12 +359,18 @@ func (q *Queries) ListChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ReplyToID,
+		&i.PublishAt,
+		&i.Status,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const listChirpByAuthorID = `-- name: ListChirpByAuthorID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE user_id = $1 AND status = 'published' AND deleted_at IS NULL ORDER BY created_at ASC
 `
 
 func (q *Queries) ListChirpByAuthorID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
@@ -86,6 +388,12 @@ func (q *Queries) ListChirpByAuthorID(ctx context.Context, userID uuid.UUID) ([]
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ReplyToID,
+			&i.PublishAt,
+			&i.Status,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -101,7 +409,7 @@ func (q *Queries) ListChirpByAuthorID(ctx context.Context, userID uuid.UUID) ([]
 }
 
 const listChirpByAuthorIDDesc = `-- name: ListChirpByAuthorIDDesc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY created_at DESC
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE user_id = $1 AND status = 'published' AND deleted_at IS NULL ORDER BY created_at DESC
 `
 
 func (q *Queries) ListChirpByAuthorIDDesc(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
@@ -119,6 +427,12 @@ func (q *Queries) ListChirpByAuthorIDDesc(ctx context.Context, userID uuid.UUID)
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ReplyToID,
+			&i.PublishAt,
+			&i.Status,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -134,7 +448,7 @@ func (q *Queries) ListChirpByAuthorIDDesc(ctx context.Context, userID uuid.UUID)
 }
 
 const listChirps = `-- name: ListChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE status = 'published' AND deleted_at IS NULL ORDER BY created_at ASC
 `
 
 func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
@@ -152,6 +466,51 @@ func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ReplyToID,
+			&i.PublishAt,
+			&i.Status,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsByIDs = `-- name: ListChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE id = ANY($1::UUID[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) ListChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.PublishAt,
+			&i.Status,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -167,7 +526,7 @@ func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const listChirpsDesc = `-- name: ListChirpsDesc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at DESC
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE status = 'published' AND deleted_at IS NULL ORDER BY created_at DESC
 `
 
 func (q *Queries) ListChirpsDesc(ctx context.Context) ([]Chirp, error) {
@@ -185,6 +544,181 @@ func (q *Queries) ListChirpsDesc(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ReplyToID,
+			&i.PublishAt,
+			&i.Status,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPurgeableChirpIDs = `-- name: ListPurgeableChirpIDs :many
+SELECT id FROM chirps
+WHERE deleted_at < NOW() - make_interval(secs => $1::float8)
+ORDER BY deleted_at ASC
+LIMIT $2
+`
+
+type ListPurgeableChirpIDsParams struct {
+	RetentionSeconds float64
+	MaxRows          int32
+}
+
+func (q *Queries) ListPurgeableChirpIDs(ctx context.Context, arg ListPurgeableChirpIDsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listPurgeableChirpIDs, arg.RetentionSeconds, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRechirpsOf = `-- name: ListRechirpsOf :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE rechirp_of_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) ListRechirpsOf(ctx context.Context, rechirpOfID uuid.NullUUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listRechirpsOf, rechirpOfID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.PublishAt,
+			&i.Status,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledChirps = `-- name: ListScheduledChirps :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps
+WHERE user_id = $1 AND status = 'scheduled' AND deleted_at IS NULL
+ORDER BY publish_at ASC
+LIMIT $2 OFFSET $3
+`
+
+type ListScheduledChirpsParams struct {
+	UserID uuid.UUID
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListScheduledChirps(ctx context.Context, arg ListScheduledChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledChirps, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.PublishAt,
+			&i.Status,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const publishDueChirps = `-- name: PublishDueChirps :many
+UPDATE chirps SET status = 'published', created_at = NOW(), updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM chirps
+    WHERE status = 'scheduled' AND publish_at <= NOW() AND deleted_at IS NULL
+    ORDER BY publish_at ASC
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at
+`
+
+func (q *Queries) PublishDueChirps(ctx context.Context, limit int32) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, publishDueChirps, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.PublishAt,
+			&i.Status,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -199,6 +733,46 @@ func (q *Queries) ListChirpsDesc(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
+const purgeChirps = `-- name: PurgeChirps :execrows
+DELETE FROM chirps WHERE id = ANY($1::UUID[]) AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) PurgeChirps(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeChirps, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rescheduleChirp = `-- name: RescheduleChirp :one
+UPDATE chirps SET publish_at = $1, updated_at = NOW() WHERE id = $2 AND status = 'scheduled' AND deleted_at IS NULL RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at
+`
+
+type RescheduleChirpParams struct {
+	PublishAt sql.NullTime
+	ID        uuid.UUID
+}
+
+func (q *Queries) RescheduleChirp(ctx context.Context, arg RescheduleChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, rescheduleChirp, arg.PublishAt, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.PublishAt,
+		&i.Status,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const resetChirps = `-- name: ResetChirps :exec
 DELETE FROM chirps
 `
@@ -207,3 +781,87 @@ func (q *Queries) ResetChirps(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetChirps)
 	return err
 }
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps SET deleted_at = NULL WHERE id = $1 AND deleted_at > NOW() - make_interval(secs => $2::float8) RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at
+`
+
+type RestoreChirpParams struct {
+	ID            uuid.UUID
+	WindowSeconds float64
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, arg.ID, arg.WindowSeconds)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.PublishAt,
+		&i.Status,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const restoreRechirpsOf = `-- name: RestoreRechirpsOf :exec
+UPDATE chirps SET deleted_at = NULL WHERE rechirp_of_id = $1 AND deleted_at = $2
+`
+
+type RestoreRechirpsOfParams struct {
+	RechirpOfID uuid.NullUUID
+	DeletedAt   sql.NullTime
+}
+
+func (q *Queries) RestoreRechirpsOf(ctx context.Context, arg RestoreRechirpsOfParams) error {
+	_, err := q.db.ExecContext(ctx, restoreRechirpsOf, arg.RechirpOfID, arg.DeletedAt)
+	return err
+}
+
+const softDeleteRechirpsOf = `-- name: SoftDeleteRechirpsOf :exec
+UPDATE chirps SET deleted_at = $2 WHERE rechirp_of_id = $1 AND deleted_at IS NULL
+`
+
+type SoftDeleteRechirpsOfParams struct {
+	RechirpOfID uuid.NullUUID
+	DeletedAt   sql.NullTime
+}
+
+func (q *Queries) SoftDeleteRechirpsOf(ctx context.Context, arg SoftDeleteRechirpsOfParams) error {
+	_, err := q.db.ExecContext(ctx, softDeleteRechirpsOf, arg.RechirpOfID, arg.DeletedAt)
+	return err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps SET body = $1, updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at
+`
+
+type UpdateChirpBodyParams struct {
+	Body string
+	ID   uuid.UUID
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.Body, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.PublishAt,
+		&i.Status,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
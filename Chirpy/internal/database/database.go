@@ -1,118 +1,562 @@
 package database
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
-	"strconv"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// UserQuerier, ChirpQuerier and RefreshTokenQuerier are the parts of Querier
+// that DB supports.
+type UserQuerier interface {
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByHandle(ctx context.Context, handle string) (User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
+	UpdateUserByID(ctx context.Context, arg UpdateUserByIDParams) (User, error)
+	UpdateUserChirpyRedByID(ctx context.Context, arg UpdateUserChirpyRedByIDParams) (User, error)
+	ResetUsers(ctx context.Context) error
+}
+
+type ChirpQuerier interface {
+	CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error)
+	ListChirp(ctx context.Context, id uuid.UUID) (Chirp, error)
+	ListChirps(ctx context.Context) ([]Chirp, error)
+	ListChirpsDesc(ctx context.Context) ([]Chirp, error)
+	ListChirpByAuthorID(ctx context.Context, userID uuid.UUID) ([]Chirp, error)
+	ListChirpByAuthorIDDesc(ctx context.Context, userID uuid.UUID) ([]Chirp, error)
+	DeleteChirpById(ctx context.Context, id uuid.UUID) (Chirp, error)
+	ResetChirps(ctx context.Context) error
+}
+
+type RefreshTokenQuerier interface {
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error)
+	GetRefreshToken(ctx context.Context, token string) (RefreshToken, error)
+	GetUserByRefreshToken(ctx context.Context, token string) (uuid.UUID, error)
+	ListRefreshTokensByUserID(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error)
+	UpdateRefreshToken(ctx context.Context, token string) error
+	RevokeRefreshTokensByUserID(ctx context.Context, userID uuid.UUID) error
+}
+
+var (
+	_ UserQuerier         = Querier(nil)
+	_ ChirpQuerier        = Querier(nil)
+	_ RefreshTokenQuerier = Querier(nil)
+	_ Querier             = (*DB)(nil)
+)
+
+// DB returns these where Postgres would reject a write with a constraint
+// violation.
+var (
+	ErrUniqueViolation     = errors.New("database: unique constraint violation")
+	ErrForeignKeyViolation = errors.New("database: foreign key violation")
+)
+
+// ErrUnsupported is returned by the queries DB does not support.
+var ErrUnsupported = errors.New("database: query not supported by the JSON database")
+
+func unsupported(query string) error {
+	return fmt.Errorf("%w: %s", ErrUnsupported, query)
+}
+
+// DB is a database kept in a JSON file, for tests and demos that should run
+// without Postgres. It stores users, chirps and refresh tokens, and every
+// other query returns ErrUnsupported. It answers its queries as the SQL ones
+// do: sql.ErrNoRows when a :one query matches nothing, and
+// ErrUniqueViolation or ErrForeignKeyViolation where a constraint would fail.
+//
+// Every query reads the file, and every write replaces it with a temporary
+// file renamed over it, so the file always holds a complete snapshot. DB
+// serialises its own writers; no other process may write the file.
 type DB struct {
 	path string
-	mux  *sync.Mutex
+	mux  *sync.RWMutex
 }
 
-// type Chirp struct {
-// 	Id   int    `json:"id"`
-// 	Body string `json:"body"`
-// }
-
+// DBStructure is the content of a DB file: the rows of each table, keyed by
+// primary key.
 type DBStructure struct {
-	Chirps map[int]Chirp `json:"chirps"`
+	Users         map[uuid.UUID]User      `json:"users"`
+	Chirps        map[uuid.UUID]Chirp     `json:"chirps"`
+	RefreshTokens map[string]RefreshToken `json:"refresh_tokens"`
 }
 
+// NewDB opens the database file at path, creating an empty one if it does
+// not exist.
 func NewDB(path string) (*DB, error) {
-	fileContents, err := os.ReadFile(path)
+	db := &DB{
+		path: path,
+		mux:  &sync.RWMutex{},
+	}
+	err := db.ensureDB()
 	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			fmt.Println("Creating database")
-			err := os.WriteFile("database.json", []byte(`{
-	"chirps": {
-		"1": {
-			"id": 1,
-			"body": "This is the first chirp ever!"
-		},
-		"2": {
-			"id": 2,
-			"body": "Hello, world!"
-		}
-	}
-}`), 0666)
-			if err != nil {
-				fmt.Println("Error writing to file")
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *DB) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	var user User
+	err := db.update(func(data *DBStructure) error {
+		for _, u := range data.Users {
+			if u.Email == arg.Email {
+				return fmt.Errorf("%w: email %q is taken", ErrUniqueViolation, arg.Email)
 			}
-		} else {
-			fmt.Println("uh oh")
-			return nil, err
+			if u.Handle == arg.Handle {
+				return fmt.Errorf("%w: handle %q is taken", ErrUniqueViolation, arg.Handle)
+			}
+		}
+		now := now()
+		user = User{
+			ID:             uuid.New(),
+			HashedPassword: arg.HashedPassword,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			Email:          arg.Email,
+			Handle:         arg.Handle,
 		}
+		data.Users[user.ID] = user
+		return nil
+	})
+	return user, err
+}
+
+func (db *DB) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	return db.findUser(func(u User) bool { return u.Email == email })
+}
+
+func (db *DB) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	return db.findUser(func(u User) bool { return u.Handle == handle })
+}
+
+func (db *DB) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	return db.findUser(func(u User) bool { return u.ID == id })
+}
+
+func (db *DB) findUser(match func(User) bool) (User, error) {
+	data, err := db.view()
+	if err != nil {
+		return User{}, err
 	}
-	fmt.Println(fileContents)
-	mux := &sync.Mutex{}
-	db := DB{
-		path: path,
-		mux:  mux,
+	for _, u := range data.Users {
+		if match(u) {
+			return u, nil
+		}
 	}
-	return &db, nil
+	return User{}, sql.ErrNoRows
 }
 
-// func (db *DB) CreateChirp(body string) (Chirp, error) {
+func (db *DB) UpdateUserByID(ctx context.Context, arg UpdateUserByIDParams) (User, error) {
+	return db.updateUser(arg.ID, func(data *DBStructure, u *User) error {
+		for _, other := range data.Users {
+			if other.ID != u.ID && other.Email == arg.Email {
+				return fmt.Errorf("%w: email %q is taken", ErrUniqueViolation, arg.Email)
+			}
+		}
+		u.Email = arg.Email
+		u.HashedPassword = arg.HashedPassword
+		return nil
+	})
+}
 
-// }
+func (db *DB) UpdateUserChirpyRedByID(ctx context.Context, arg UpdateUserChirpyRedByIDParams) (User, error) {
+	return db.updateUser(arg.ID, func(data *DBStructure, u *User) error {
+		u.IsChirpyRed = arg.IsChirpyRed
+		return nil
+	})
+}
 
-func (db *DB) GetChirps() (DBStructure, error) {
-	db.mux.Lock()
-	contents, err := os.ReadFile(db.path)
+// updateUser applies fn to the user with id and returns the result.
+func (db *DB) updateUser(id uuid.UUID, fn func(data *DBStructure, u *User) error) (User, error) {
+	var user User
+	err := db.update(func(data *DBStructure) error {
+		var ok bool
+		user, ok = data.Users[id]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		err := fn(data, &user)
+		if err != nil {
+			return err
+		}
+		data.Users[id] = user
+		return nil
+	})
+	return user, err
+}
+
+// ResetUsers deletes every user, and with them their chirps and refresh
+// tokens.
+func (db *DB) ResetUsers(ctx context.Context) error {
+	return db.update(func(data *DBStructure) error {
+		clear(data.Users)
+		clear(data.Chirps)
+		clear(data.RefreshTokens)
+		return nil
+	})
+}
+
+func (db *DB) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
+	var chirp Chirp
+	err := db.update(func(data *DBStructure) error {
+		if _, ok := data.Users[arg.UserID]; !ok {
+			return fmt.Errorf("%w: no user %s", ErrForeignKeyViolation, arg.UserID)
+		}
+		for _, ref := range []uuid.NullUUID{arg.ReplyToID, arg.QuoteOfID} {
+			if _, ok := data.Chirps[ref.UUID]; ref.Valid && !ok {
+				return fmt.Errorf("%w: no chirp %s", ErrForeignKeyViolation, ref.UUID)
+			}
+		}
+		now := now()
+		chirp = Chirp{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Body:      arg.Body,
+			UserID:    arg.UserID,
+			ReplyToID: arg.ReplyToID,
+			Status:    "published",
+			QuoteOfID: arg.QuoteOfID,
+		}
+		data.Chirps[chirp.ID] = chirp
+		return nil
+	})
+	return chirp, err
+}
+
+func (db *DB) ListChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	data, err := db.view()
+	if err != nil {
+		return Chirp{}, err
+	}
+	chirp, ok := data.Chirps[id]
+	if !ok || chirp.DeletedAt.Valid {
+		return Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+func (db *DB) ListChirps(ctx context.Context) ([]Chirp, error) {
+	return db.listChirps(func(Chirp) bool { return true }, false)
+}
+
+func (db *DB) ListChirpsDesc(ctx context.Context) ([]Chirp, error) {
+	return db.listChirps(func(Chirp) bool { return true }, true)
+}
+
+func (db *DB) ListChirpByAuthorID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	return db.listChirps(func(c Chirp) bool { return c.UserID == userID }, false)
+}
+
+func (db *DB) ListChirpByAuthorIDDesc(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	return db.listChirps(func(c Chirp) bool { return c.UserID == userID }, true)
+}
+
+// listChirps returns the published, undeleted chirps that match, oldest
+// first unless desc.
+func (db *DB) listChirps(match func(Chirp) bool, desc bool) ([]Chirp, error) {
+	data, err := db.view()
 	if err != nil {
-		fmt.Println(err)
+		return nil, err
 	}
-	db.mux.Unlock()
-	// Temporary structure to unmarshal into with string keys for chirps
-	var temp struct {
-		Chirps map[string]Chirp `json:"chirps"`
+	var chirps []Chirp
+	for _, c := range data.Chirps {
+		if c.Status == "published" && !c.DeletedAt.Valid && match(c) {
+			chirps = append(chirps, c)
+		}
+	}
+	slices.SortFunc(chirps, func(a, b Chirp) int {
+		if n := a.CreatedAt.Compare(b.CreatedAt); n != 0 {
+			return n
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	if desc {
+		slices.Reverse(chirps)
 	}
+	return chirps, nil
+}
+
+// DeleteChirpById soft-deletes a chirp, as the SQL query does.
+func (db *DB) DeleteChirpById(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	var chirp Chirp
+	err := db.update(func(data *DBStructure) error {
+		var ok bool
+		chirp, ok = data.Chirps[id]
+		if !ok || chirp.DeletedAt.Valid {
+			return sql.ErrNoRows
+		}
+		chirp.DeletedAt = sql.NullTime{Time: now(), Valid: true}
+		data.Chirps[id] = chirp
+		return nil
+	})
+	return chirp, err
+}
+
+func (db *DB) ResetChirps(ctx context.Context) error {
+	return db.update(func(data *DBStructure) error {
+		clear(data.Chirps)
+		return nil
+	})
+}
 
-	// Unmarshal the JSON data into the temp structure
-	if err := json.Unmarshal(contents, &temp); err != nil {
-		fmt.Println("Error unmarshalling JSON:", err)
+func (db *DB) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
+	var token RefreshToken
+	err := db.update(func(data *DBStructure) error {
+		if _, ok := data.RefreshTokens[arg.Token]; ok {
+			return fmt.Errorf("%w: refresh token exists", ErrUniqueViolation)
+		}
+		if _, ok := data.Users[arg.UserID]; !ok {
+			return fmt.Errorf("%w: no user %s", ErrForeignKeyViolation, arg.UserID)
+		}
+		now := now()
+		token = RefreshToken{
+			Token:     arg.Token,
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    arg.UserID,
+			ExpiresAt: now.Add(time.Duration(arg.ExpiresInSeconds * float64(time.Second))).Truncate(time.Microsecond),
+		}
+		data.RefreshTokens[token.Token] = token
+		return nil
+	})
+	return token, err
+}
+
+func (db *DB) GetRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
+	data, err := db.view()
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	t, ok := data.RefreshTokens[token]
+	if !ok {
+		return RefreshToken{}, sql.ErrNoRows
 	}
+	return t, nil
+}
+
+func (db *DB) GetUserByRefreshToken(ctx context.Context, token string) (uuid.UUID, error) {
+	t, err := db.GetRefreshToken(ctx, token)
+	return t.UserID, err
+}
 
-	// Initialize the final DBStructure with int keys
-	c := DBStructure{
-		Chirps: make(map[int]Chirp),
+func (db *DB) ListRefreshTokensByUserID(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error) {
+	data, err := db.view()
+	if err != nil {
+		return nil, err
+	}
+	var tokens []RefreshToken
+	for _, t := range data.RefreshTokens {
+		if t.UserID == userID {
+			tokens = append(tokens, t)
+		}
 	}
+	slices.SortFunc(tokens, func(a, b RefreshToken) int {
+		if n := a.CreatedAt.Compare(b.CreatedAt); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Token, b.Token)
+	})
+	return tokens, nil
+}
 
-	// Convert string keys to int keys
-	for key, chirp := range temp.Chirps {
-		intKey, err := strconv.Atoi(key)
-		if err != nil {
-			fmt.Println("Error converting key to int:", err)
+// UpdateRefreshToken revokes a refresh token.
+func (db *DB) UpdateRefreshToken(ctx context.Context, token string) error {
+	return db.revokeRefreshTokens(func(t RefreshToken) bool { return t.Token == token })
+}
+
+func (db *DB) RevokeRefreshTokensByUserID(ctx context.Context, userID uuid.UUID) error {
+	return db.revokeRefreshTokens(func(t RefreshToken) bool { return t.UserID == userID && !t.RevokedAt.Valid })
+}
+
+func (db *DB) revokeRefreshTokens(match func(RefreshToken) bool) error {
+	return db.update(func(data *DBStructure) error {
+		now := now()
+		for key, t := range data.RefreshTokens {
+			if match(t) {
+				t.UpdatedAt = now
+				t.RevokedAt = sql.NullTime{Time: now, Valid: true}
+				data.RefreshTokens[key] = t
+			}
 		}
-		c.Chirps[intKey] = chirp
+		return nil
+	})
+}
+
+// now returns the current time as Postgres stores it: UTC, to the
+// microsecond.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// view returns the current contents of the database.
+func (db *DB) view() (DBStructure, error) {
+	db.mux.RLock()
+	defer db.mux.RUnlock()
+	return db.loadDB()
+}
+
+// update applies fn to the contents of the database and saves the result,
+// unless fn fails.
+func (db *DB) update(fn func(data *DBStructure) error) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+	data, err := db.loadDB()
+	if err != nil {
+		return err
+	}
+	err = fn(&data)
+	if err != nil {
+		return err
 	}
+	return db.writeDB(data)
+}
 
-	return c, nil
+// ensureDB creates an empty database file unless one exists.
+func (db *DB) ensureDB() error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+	_, err := os.Stat(db.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return db.writeDB(DBStructure{})
+	}
+	return err
+}
+
+func (db *DB) loadDB() (DBStructure, error) {
+	contents, err := os.ReadFile(db.path)
+	if err != nil {
+		return DBStructure{}, err
+	}
+	var data DBStructure
+	err = json.Unmarshal(contents, &data)
+	if err != nil {
+		return DBStructure{}, fmt.Errorf("database: read %s: %w", db.path, err)
+	}
+	if data.Users == nil {
+		data.Users = map[uuid.UUID]User{}
+	}
+	if data.Chirps == nil {
+		data.Chirps = map[uuid.UUID]Chirp{}
+	}
+	if data.RefreshTokens == nil {
+		data.RefreshTokens = map[string]RefreshToken{}
+	}
+	return data, nil
+}
+
+// writeDB replaces the database file with dbStructure. It writes a
+// temporary file next to it and renames that over the old one, so readers,
+// and the file after a crash, see either the old contents or the new.
+func (db *DB) writeDB(dbStructure DBStructure) error {
+	contents, err := json.MarshalIndent(dbStructure, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(db.path), filepath.Base(db.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(contents)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), db.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("database: write %s: %w", db.path, err)
+	}
+	return nil
 }
 
 type Res struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt string    `json:"created_at"`
-	UpdatedAt string    `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID           uuid.UUID       `json:"id"`
+	CreatedAt    string          `json:"created_at"`
+	UpdatedAt    string          `json:"updated_at"`
+	Body         string          `json:"body"`
+	UserID       uuid.UUID       `json:"user_id"`
+	ReplyToID    *uuid.UUID      `json:"reply_to_id,omitempty"`
+	RechirpOfID  *uuid.UUID      `json:"rechirp_of_id,omitempty"`
+	QuoteOfID    *uuid.UUID      `json:"quote_of_id,omitempty"`
+	Status       string          `json:"status"`
+	PublishAt    string          `json:"publish_at,omitempty"`
+	Author       *ResAuthor      `json:"author,omitempty"`
+	Attachments  []ResAttachment `json:"attachments,omitempty"`
+	RechirpCount int64           `json:"rechirp_count"`
+	Original     *Res            `json:"original,omitempty"`
+}
+
+// ResAuthor is the public summary of a chirp's author.
+type ResAuthor struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+}
+
+type ResAttachment struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	SizeBytes    int64     `json:"size_bytes"`
 }
 
 func MapSqlChirpToJsonChirp(chirp Chirp) Res {
-	return Res{
+	res := Res{
 		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt: chirp.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		CreatedAt: chirp.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		UpdatedAt: chirp.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z"),
 		Body:      chirp.Body,
 		UserID:    chirp.UserID,
+		Status:    chirp.Status,
+	}
+	if chirp.ReplyToID.Valid {
+		res.ReplyToID = &chirp.ReplyToID.UUID
+	}
+	if chirp.RechirpOfID.Valid {
+		res.RechirpOfID = &chirp.RechirpOfID.UUID
+	}
+	if chirp.QuoteOfID.Valid {
+		res.QuoteOfID = &chirp.QuoteOfID.UUID
+	}
+	if chirp.PublishAt.Valid && chirp.Status == "scheduled" {
+		res.PublishAt = chirp.PublishAt.Time.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	return res
+}
+
+// MapSqlAttachmentToJsonAttachment maps a stored attachment, using url to turn
+// storage keys into public URLs.
+func MapSqlAttachmentToJsonAttachment(a ChirpAttachment, url func(key string) string) ResAttachment {
+	return ResAttachment{
+		ID:           a.ID,
+		URL:          url(a.StorageKey),
+		ThumbnailURL: url(a.ThumbnailKey),
+		ContentType:  a.ContentType,
+		Width:        a.Width,
+		Height:       a.Height,
+		SizeBytes:    a.SizeBytes,
 	}
 }
 
@@ -124,15 +568,3 @@ func MapSqlChirpsToJsonChirps(chirps []Chirp) []Res {
 
 	return res
 }
-
-// func (db *DB) ensureDB() error {
-
-// }
-
-// func (db *DB) loadDB() (DBStructure, error) {
-
-// }
-
-// func (db *DB) writeDB(dbStructure DBStructure) error {
-
-// }
@@ -0,0 +1,33 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/storage/storagetest"
+)
+
+func TestDB(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "db.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = storagetest.Run(context.Background(), db)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDBUnsupported(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "db.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.BlockUser(context.Background(), database.BlockUserParams{})
+	if !errors.Is(err, database.ErrUnsupported) {
+		t.Errorf("BlockUser: got %v, want ErrUnsupported", err)
+	}
+}
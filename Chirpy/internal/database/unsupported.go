@@ -0,0 +1,413 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+// DB stores no rows outside users, chirps and refresh_tokens, so reads of
+// the other tables find nothing, as they would on an empty SQL database.
+
+func (db *DB) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (db *DB) FindDirectConversation(ctx context.Context, arg FindDirectConversationParams) (Conversation, error) {
+	return Conversation{}, sql.ErrNoRows
+}
+
+func (db *DB) GetActiveDataExport(ctx context.Context, userID uuid.UUID) (DataExport, error) {
+	return DataExport{}, sql.ErrNoRows
+}
+
+func (db *DB) GetBookmarkCollection(ctx context.Context, arg GetBookmarkCollectionParams) (BookmarkCollection, error) {
+	return BookmarkCollection{}, sql.ErrNoRows
+}
+
+func (db *DB) GetConversation(ctx context.Context, id uuid.UUID) (Conversation, error) {
+	return Conversation{}, sql.ErrNoRows
+}
+
+func (db *DB) GetConversationParticipant(ctx context.Context, arg GetConversationParticipantParams) (ConversationParticipant, error) {
+	return ConversationParticipant{}, sql.ErrNoRows
+}
+
+func (db *DB) GetDataExport(ctx context.Context, arg GetDataExportParams) (DataExport, error) {
+	return DataExport{}, sql.ErrNoRows
+}
+
+func (db *DB) GetNotification(ctx context.Context, id uuid.UUID) (Notification, error) {
+	return Notification{}, sql.ErrNoRows
+}
+
+func (db *DB) GetNotificationPreferences(ctx context.Context, userID uuid.UUID) (NotificationPreference, error) {
+	return NotificationPreference{}, sql.ErrNoRows
+}
+
+func (db *DB) GetWebhookSubscription(ctx context.Context, id uuid.UUID) (WebhookSubscription, error) {
+	return WebhookSubscription{}, sql.ErrNoRows
+}
+
+func (db *DB) IsBlocked(ctx context.Context, arg IsBlockedParams) (bool, error) {
+	return false, nil
+}
+
+func (db *DB) IsBlockedEitherWay(ctx context.Context, arg IsBlockedEitherWayParams) (bool, error) {
+	return false, nil
+}
+
+func (db *DB) IsSilenced(ctx context.Context, arg IsSilencedParams) (bool, error) {
+	return false, nil
+}
+
+func (db *DB) ListAttachmentsByChirpID(ctx context.Context, chirpID uuid.UUID) ([]ChirpAttachment, error) {
+	return nil, nil
+}
+
+func (db *DB) ListAttachmentsByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]ChirpAttachment, error) {
+	return nil, nil
+}
+
+func (db *DB) ListAttachmentsByUserID(ctx context.Context, userID uuid.UUID) ([]ChirpAttachment, error) {
+	return nil, nil
+}
+
+func (db *DB) ListBlockedUsers(ctx context.Context, arg ListBlockedUsersParams) ([]User, error) {
+	return nil, nil
+}
+
+func (db *DB) ListBookmarkCollections(ctx context.Context, userID uuid.UUID) ([]ListBookmarkCollectionsRow, error) {
+	return nil, nil
+}
+
+func (db *DB) ListBookmarks(ctx context.Context, arg ListBookmarksParams) ([]Bookmark, error) {
+	return nil, nil
+}
+
+func (db *DB) ListBookmarksInCollection(ctx context.Context, arg ListBookmarksInCollectionParams) ([]Bookmark, error) {
+	return nil, nil
+}
+
+func (db *DB) ListChirpEventsSince(ctx context.Context, arg ListChirpEventsSinceParams) ([]ChirpEvent, error) {
+	return nil, nil
+}
+
+func (db *DB) ListChirpEventsSinceByAuthorID(ctx context.Context, arg ListChirpEventsSinceByAuthorIDParams) ([]ChirpEvent, error) {
+	return nil, nil
+}
+
+func (db *DB) ListConversationParticipants(ctx context.Context, conversationID uuid.UUID) ([]ConversationParticipant, error) {
+	return nil, nil
+}
+
+func (db *DB) ListConversationsByUserID(ctx context.Context, arg ListConversationsByUserIDParams) ([]Conversation, error) {
+	return nil, nil
+}
+
+func (db *DB) ListHiddenAuthorIDs(ctx context.Context, viewerID uuid.UUID) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (db *DB) ListLikesByUserID(ctx context.Context, userID uuid.UUID) ([]ChirpLike, error) {
+	return nil, nil
+}
+
+func (db *DB) ListMessagesByConversationID(ctx context.Context, arg ListMessagesByConversationIDParams) ([]Message, error) {
+	return nil, nil
+}
+
+func (db *DB) ListMessagesBySenderID(ctx context.Context, senderID uuid.UUID) ([]Message, error) {
+	return nil, nil
+}
+
+func (db *DB) ListMutedUsers(ctx context.Context, arg ListMutedUsersParams) ([]User, error) {
+	return nil, nil
+}
+
+func (db *DB) ListNotificationsByUserID(ctx context.Context, arg ListNotificationsByUserIDParams) ([]Notification, error) {
+	return nil, nil
+}
+
+func (db *DB) ListUnreadNotificationsByUserID(ctx context.Context, arg ListUnreadNotificationsByUserIDParams) ([]Notification, error) {
+	return nil, nil
+}
+
+func (db *DB) ListWebhookDeliveriesBySubscriptionID(ctx context.Context, arg ListWebhookDeliveriesBySubscriptionIDParams) ([]WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (db *DB) ListWebhookSubscriptionsByUserID(ctx context.Context, userID uuid.UUID) ([]WebhookSubscription, error) {
+	return nil, nil
+}
+
+func (db *DB) ListWebhookSubscriptionsForEvent(ctx context.Context, event string) ([]WebhookSubscription, error) {
+	return nil, nil
+}
+
+// Every other query returns ErrUnsupported.
+
+func (db *DB) AddConversationParticipant(ctx context.Context, arg AddConversationParticipantParams) error {
+	return unsupported("AddConversationParticipant")
+}
+
+func (db *DB) BlockUser(ctx context.Context, arg BlockUserParams) error {
+	return unsupported("BlockUser")
+}
+
+func (db *DB) CancelUserDeletion(ctx context.Context, id uuid.UUID) (User, error) {
+	return User{}, unsupported("CancelUserDeletion")
+}
+
+func (db *DB) ClaimDueWebhookDeliveries(ctx context.Context, limit int32) ([]WebhookDelivery, error) {
+	return nil, unsupported("ClaimDueWebhookDeliveries")
+}
+
+func (db *DB) ClaimPendingDataExports(ctx context.Context, arg ClaimPendingDataExportsParams) ([]DataExport, error) {
+	return nil, unsupported("ClaimPendingDataExports")
+}
+
+func (db *DB) CompleteDataExport(ctx context.Context, arg CompleteDataExportParams) error {
+	return unsupported("CompleteDataExport")
+}
+
+func (db *DB) CountChirpsByAuthorID(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return 0, unsupported("CountChirpsByAuthorID")
+}
+
+func (db *DB) CountRechirpsByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]CountRechirpsByChirpIDsRow, error) {
+	return nil, unsupported("CountRechirpsByChirpIDs")
+}
+
+func (db *DB) CreateBookmarkCollection(ctx context.Context, arg CreateBookmarkCollectionParams) (BookmarkCollection, error) {
+	return BookmarkCollection{}, unsupported("CreateBookmarkCollection")
+}
+
+func (db *DB) CreateChirpAttachment(ctx context.Context, arg CreateChirpAttachmentParams) (ChirpAttachment, error) {
+	return ChirpAttachment{}, unsupported("CreateChirpAttachment")
+}
+
+func (db *DB) CreateChirpEvent(ctx context.Context, arg CreateChirpEventParams) (ChirpEvent, error) {
+	return ChirpEvent{}, unsupported("CreateChirpEvent")
+}
+
+func (db *DB) CreateConversation(ctx context.Context, isGroup bool) (Conversation, error) {
+	return Conversation{}, unsupported("CreateConversation")
+}
+
+func (db *DB) CreateDataExport(ctx context.Context, userID uuid.UUID) (DataExport, error) {
+	return DataExport{}, unsupported("CreateDataExport")
+}
+
+func (db *DB) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
+	return Message{}, unsupported("CreateMessage")
+}
+
+func (db *DB) CreateNotification(ctx context.Context, arg CreateNotificationParams) (Notification, error) {
+	return Notification{}, unsupported("CreateNotification")
+}
+
+func (db *DB) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	return Chirp{}, unsupported("CreateRechirp")
+}
+
+func (db *DB) CreateScheduledChirp(ctx context.Context, arg CreateScheduledChirpParams) (Chirp, error) {
+	return Chirp{}, unsupported("CreateScheduledChirp")
+}
+
+func (db *DB) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error) {
+	return WebhookDelivery{}, unsupported("CreateWebhookDelivery")
+}
+
+func (db *DB) CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	return WebhookSubscription{}, unsupported("CreateWebhookSubscription")
+}
+
+func (db *DB) DeleteBookmark(ctx context.Context, arg DeleteBookmarkParams) (int64, error) {
+	return 0, unsupported("DeleteBookmark")
+}
+
+func (db *DB) DeleteBookmarkCollection(ctx context.Context, arg DeleteBookmarkCollectionParams) (int64, error) {
+	return 0, unsupported("DeleteBookmarkCollection")
+}
+
+func (db *DB) DeleteChirpEventsOlderThan(ctx context.Context, retentionSeconds float64) error {
+	return unsupported("DeleteChirpEventsOlderThan")
+}
+
+func (db *DB) DeleteExpiredDataExports(ctx context.Context) (int64, error) {
+	return 0, unsupported("DeleteExpiredDataExports")
+}
+
+func (db *DB) DeleteIdleRateLimitBuckets(ctx context.Context, idleSeconds float64) (int64, error) {
+	return 0, unsupported("DeleteIdleRateLimitBuckets")
+}
+
+func (db *DB) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (Chirp, error) {
+	return Chirp{}, unsupported("DeleteRechirp")
+}
+
+func (db *DB) DeleteScheduledChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	return 0, unsupported("DeleteScheduledChirp")
+}
+
+func (db *DB) DeleteUser(ctx context.Context, arg DeleteUserParams) (int64, error) {
+	return 0, unsupported("DeleteUser")
+}
+
+func (db *DB) DeleteWebhookSubscription(ctx context.Context, id uuid.UUID) error {
+	return unsupported("DeleteWebhookSubscription")
+}
+
+func (db *DB) DisableUser(ctx context.Context, id uuid.UUID) (User, error) {
+	return User{}, unsupported("DisableUser")
+}
+
+func (db *DB) FailDataExport(ctx context.Context, arg FailDataExportParams) error {
+	return unsupported("FailDataExport")
+}
+
+func (db *DB) GetDeletedChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	return Chirp{}, unsupported("GetDeletedChirp")
+}
+
+func (db *DB) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	return Chirp{}, unsupported("GetRechirp")
+}
+
+func (db *DB) LikeChirp(ctx context.Context, arg LikeChirpParams) (int64, error) {
+	return 0, unsupported("LikeChirp")
+}
+
+func (db *DB) ListAllChirpsByAuthorID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	return nil, unsupported("ListAllChirpsByAuthorID")
+}
+
+func (db *DB) ListChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	return nil, unsupported("ListChirpsByIDs")
+}
+
+func (db *DB) ListPurgeableChirpIDs(ctx context.Context, arg ListPurgeableChirpIDsParams) ([]uuid.UUID, error) {
+	return nil, unsupported("ListPurgeableChirpIDs")
+}
+
+func (db *DB) ListRechirpsOf(ctx context.Context, rechirpOfID uuid.NullUUID) ([]Chirp, error) {
+	return nil, unsupported("ListRechirpsOf")
+}
+
+func (db *DB) ListScheduledChirps(ctx context.Context, arg ListScheduledChirpsParams) ([]Chirp, error) {
+	return nil, unsupported("ListScheduledChirps")
+}
+
+func (db *DB) ListUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	return nil, unsupported("ListUsersByIDs")
+}
+
+func (db *DB) ListUsersDueForDeletion(ctx context.Context, arg ListUsersDueForDeletionParams) ([]User, error) {
+	return nil, unsupported("ListUsersDueForDeletion")
+}
+
+func (db *DB) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) error {
+	return unsupported("MarkAllNotificationsRead")
+}
+
+func (db *DB) MarkConversationRead(ctx context.Context, arg MarkConversationReadParams) error {
+	return unsupported("MarkConversationRead")
+}
+
+func (db *DB) MarkNotificationRead(ctx context.Context, id uuid.UUID) error {
+	return unsupported("MarkNotificationRead")
+}
+
+func (db *DB) MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) error {
+	return unsupported("MarkWebhookDeliveryFailed")
+}
+
+func (db *DB) MarkWebhookDeliverySucceeded(ctx context.Context, arg MarkWebhookDeliverySucceededParams) error {
+	return unsupported("MarkWebhookDeliverySucceeded")
+}
+
+func (db *DB) MuteUser(ctx context.Context, arg MuteUserParams) error {
+	return unsupported("MuteUser")
+}
+
+func (db *DB) NotifyChirpEvent(ctx context.Context, payload string) error {
+	return unsupported("NotifyChirpEvent")
+}
+
+func (db *DB) PublishDueChirps(ctx context.Context, limit int32) ([]Chirp, error) {
+	return nil, unsupported("PublishDueChirps")
+}
+
+func (db *DB) PurgeChirps(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	return 0, unsupported("PurgeChirps")
+}
+
+func (db *DB) RenameBookmarkCollection(ctx context.Context, arg RenameBookmarkCollectionParams) (BookmarkCollection, error) {
+	return BookmarkCollection{}, unsupported("RenameBookmarkCollection")
+}
+
+func (db *DB) RequestUserDeletion(ctx context.Context, id uuid.UUID) (User, error) {
+	return User{}, unsupported("RequestUserDeletion")
+}
+
+func (db *DB) RescheduleChirp(ctx context.Context, arg RescheduleChirpParams) (Chirp, error) {
+	return Chirp{}, unsupported("RescheduleChirp")
+}
+
+func (db *DB) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (Chirp, error) {
+	return Chirp{}, unsupported("RestoreChirp")
+}
+
+func (db *DB) RestoreRechirpsOf(ctx context.Context, arg RestoreRechirpsOfParams) error {
+	return unsupported("RestoreRechirpsOf")
+}
+
+func (db *DB) SoftDeleteRechirpsOf(ctx context.Context, arg SoftDeleteRechirpsOfParams) error {
+	return unsupported("SoftDeleteRechirpsOf")
+}
+
+func (db *DB) TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (TakeRateLimitTokenRow, error) {
+	return TakeRateLimitTokenRow{}, unsupported("TakeRateLimitToken")
+}
+
+func (db *DB) TouchConversation(ctx context.Context, id uuid.UUID) error {
+	return unsupported("TouchConversation")
+}
+
+func (db *DB) UnblockUser(ctx context.Context, arg UnblockUserParams) (int64, error) {
+	return 0, unsupported("UnblockUser")
+}
+
+func (db *DB) UnlikeChirp(ctx context.Context, arg UnlikeChirpParams) error {
+	return unsupported("UnlikeChirp")
+}
+
+func (db *DB) UnmuteUser(ctx context.Context, arg UnmuteUserParams) (int64, error) {
+	return 0, unsupported("UnmuteUser")
+}
+
+func (db *DB) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	return Chirp{}, unsupported("UpdateChirpBody")
+}
+
+func (db *DB) UpdateUserAdminByID(ctx context.Context, arg UpdateUserAdminByIDParams) (User, error) {
+	return User{}, unsupported("UpdateUserAdminByID")
+}
+
+func (db *DB) UpdateUserAvatar(ctx context.Context, arg UpdateUserAvatarParams) (User, error) {
+	return User{}, unsupported("UpdateUserAvatar")
+}
+
+func (db *DB) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	return User{}, unsupported("UpdateUserProfile")
+}
+
+func (db *DB) UpsertBookmark(ctx context.Context, arg UpsertBookmarkParams) (Bookmark, error) {
+	return Bookmark{}, unsupported("UpsertBookmark")
+}
+
+func (db *DB) UpsertNotificationPreferences(ctx context.Context, arg UpsertNotificationPreferencesParams) (NotificationPreference, error) {
+	return NotificationPreference{}, unsupported("UpsertNotificationPreferences")
+}
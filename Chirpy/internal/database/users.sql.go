@@ -7,29 +7,100 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const cancelUserDeletion = `-- name: CancelUserDeletion :one
+UPDATE users SET deletion_requested_at = NULL, updated_at = NOW() WHERE id = $1 AND deletion_requested_at IS NOT NULL RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, cancelUserDeletion, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsAdmin,
+		&i.DeletionRequestedAt,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, hashed_password, created_at, updated_at, email)
+INSERT INTO users (id, hashed_password, created_at, updated_at, email, handle)
 VALUES (
     gen_random_uuid(),
     $1,
     NOW(),
     NOW(),
-    $2
+    $2,
+    $3
   )
-RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red
+RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at
 `
 
 type CreateUserParams struct {
 	HashedPassword string
 	Email          string
+	Handle         string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.HashedPassword, arg.Email)
+	row := q.db.QueryRowContext(ctx, createUser, arg.HashedPassword, arg.Email, arg.Handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsAdmin,
+		&i.DeletionRequestedAt,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = $1 AND deletion_requested_at < NOW() - make_interval(secs => $2::float8)
+`
+
+type DeleteUserParams struct {
+	ID           uuid.UUID
+	GraceSeconds float64
+}
+
+func (q *Queries) DeleteUser(ctx context.Context, arg DeleteUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, arg.ID, arg.GraceSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const disableUser = `-- name: DisableUser :one
+UPDATE users SET disabled_at = NOW(), updated_at = NOW() WHERE id = $1 AND disabled_at IS NULL RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at
+`
+
+func (q *Queries) DisableUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, disableUser, id)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -38,12 +109,19 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsAdmin,
+		&i.DeletionRequestedAt,
+		&i.DisabledAt,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, hashed_password, created_at, updated_at, email, is_chirpy_red FROM users WHERE email = $1
+SELECT id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -56,6 +134,178 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsAdmin,
+		&i.DeletionRequestedAt,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at FROM users WHERE handle = $1
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsAdmin,
+		&i.DeletionRequestedAt,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsAdmin,
+		&i.DeletionRequestedAt,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const listUsersByIDs = `-- name: ListUsersByIDs :many
+SELECT id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at FROM users WHERE id = ANY($1::UUID[])
+`
+
+func (q *Queries) ListUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.HashedPassword,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.IsChirpyRed,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarKey,
+			&i.IsAdmin,
+			&i.DeletionRequestedAt,
+			&i.DisabledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersDueForDeletion = `-- name: ListUsersDueForDeletion :many
+SELECT id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at FROM users
+WHERE deletion_requested_at < NOW() - make_interval(secs => $1::float8)
+ORDER BY deletion_requested_at ASC
+LIMIT $2
+`
+
+type ListUsersDueForDeletionParams struct {
+	GraceSeconds float64
+	MaxRows      int32
+}
+
+func (q *Queries) ListUsersDueForDeletion(ctx context.Context, arg ListUsersDueForDeletionParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersDueForDeletion, arg.GraceSeconds, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.HashedPassword,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.IsChirpyRed,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarKey,
+			&i.IsAdmin,
+			&i.DeletionRequestedAt,
+			&i.DisabledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const requestUserDeletion = `-- name: RequestUserDeletion :one
+UPDATE users SET deletion_requested_at = NOW(), updated_at = NOW() WHERE id = $1 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at
+`
+
+func (q *Queries) RequestUserDeletion(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, requestUserDeletion, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsAdmin,
+		&i.DeletionRequestedAt,
+		&i.DisabledAt,
 	)
 	return i, err
 }
@@ -69,8 +319,68 @@ func (q *Queries) ResetUsers(ctx context.Context) error {
 	return err
 }
 
+const updateUserAdminByID = `-- name: UpdateUserAdminByID :one
+UPDATE users SET is_admin = $1, updated_at = NOW() WHERE id = $2 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at
+`
+
+type UpdateUserAdminByIDParams struct {
+	IsAdmin bool
+	ID      uuid.UUID
+}
+
+func (q *Queries) UpdateUserAdminByID(ctx context.Context, arg UpdateUserAdminByIDParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserAdminByID, arg.IsAdmin, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsAdmin,
+		&i.DeletionRequestedAt,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const updateUserAvatar = `-- name: UpdateUserAvatar :one
+UPDATE users SET avatar_key = $1, updated_at = NOW() WHERE id = $2 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at
+`
+
+type UpdateUserAvatarParams struct {
+	AvatarKey sql.NullString
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdateUserAvatar(ctx context.Context, arg UpdateUserAvatarParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserAvatar, arg.AvatarKey, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsAdmin,
+		&i.DeletionRequestedAt,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
 const updateUserByID = `-- name: UpdateUserByID :one
-UPDATE users SET email = $1, hashed_password = $2 WHERE id = $3 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red
+UPDATE users SET email = $1, hashed_password = $2 WHERE id = $3 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at
 `
 
 type UpdateUserByIDParams struct {
@@ -89,12 +399,19 @@ func (q *Queries) UpdateUserByID(ctx context.Context, arg UpdateUserByIDParams)
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsAdmin,
+		&i.DeletionRequestedAt,
+		&i.DisabledAt,
 	)
 	return i, err
 }
 
 const updateUserChirpyRedByID = `-- name: UpdateUserChirpyRedByID :one
-UPDATE users SET is_chirpy_red = $1 WHERE id = $2 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red
+UPDATE users SET is_chirpy_red = $1 WHERE id = $2 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at
 `
 
 type UpdateUserChirpyRedByIDParams struct {
@@ -112,6 +429,50 @@ func (q *Queries) UpdateUserChirpyRedByID(ctx context.Context, arg UpdateUserChi
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsAdmin,
+		&i.DeletionRequestedAt,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users SET handle = $1, display_name = $2, bio = $3, updated_at = NOW() WHERE id = $4 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at
+`
+
+type UpdateUserProfileParams struct {
+	Handle      string
+	DisplayName string
+	Bio         string
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsAdmin,
+		&i.DeletionRequestedAt,
+		&i.DisabledAt,
 	)
 	return i, err
 }
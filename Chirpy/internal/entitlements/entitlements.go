@@ -0,0 +1,65 @@
+package entitlements
+
+import (
+	"Chirpy/internal/database"
+)
+
+type Tier string
+
+const (
+	TierFree Tier = "free"
+	TierRed  Tier = "chirpy_red"
+)
+
+// Capabilities describes what a subscription tier is allowed to do.
+type Capabilities struct {
+	Tier              Tier
+	MaxChirpLength    int
+	CanEditChirps     bool
+	CanScheduleChirps bool
+	RequestsPerMinute int
+	// RateLimitMultiplier scales the per-route rate limits.
+	RateLimitMultiplier float64
+}
+
+var tiers = map[Tier]Capabilities{
+	TierFree: {
+		Tier:                TierFree,
+		MaxChirpLength:      140,
+		CanEditChirps:       false,
+		CanScheduleChirps:   false,
+		RequestsPerMinute:   60,
+		RateLimitMultiplier: 1,
+	},
+	TierRed: {
+		Tier:                TierRed,
+		MaxChirpLength:      1000,
+		CanEditChirps:       true,
+		CanScheduleChirps:   true,
+		RequestsPerMinute:   600,
+		RateLimitMultiplier: 5,
+	},
+}
+
+// TierFor returns the subscription tier of a user.
+func TierFor(user database.User) Tier {
+	if user.IsChirpyRed {
+		return TierRed
+	}
+	return TierFree
+}
+
+// ForTier returns the capabilities of a tier, falling back to the free tier
+// for unknown values.
+func ForTier(tier Tier) Capabilities {
+	c, ok := tiers[tier]
+	if !ok {
+		return tiers[TierFree]
+	}
+	return c
+}
+
+// For returns the capabilities of a user.
+func For(user database.User) Capabilities {
+	return ForTier(TierFor(user))
+}
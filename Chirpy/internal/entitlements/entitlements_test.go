@@ -0,0 +1,53 @@
+package entitlements
+
+import (
+	"testing"
+
+	"Chirpy/internal/database"
+)
+
+func TestFor(t *testing.T) {
+	tests := []struct {
+		name string
+		user database.User
+		want Capabilities
+	}{
+		{
+			name: "free",
+			user: database.User{},
+			want: Capabilities{
+				Tier:                TierFree,
+				MaxChirpLength:      140,
+				RequestsPerMinute:   60,
+				RateLimitMultiplier: 1,
+			},
+		},
+		{
+			name: "red",
+			user: database.User{IsChirpyRed: true},
+			want: Capabilities{
+				Tier:                TierRed,
+				MaxChirpLength:      1000,
+				CanEditChirps:       true,
+				CanScheduleChirps:   true,
+				RequestsPerMinute:   600,
+				RateLimitMultiplier: 5,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := For(tt.user)
+			if got != tt.want {
+				t.Errorf("For() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForTierUnknown(t *testing.T) {
+	got := ForTier("platinum")
+	if got != ForTier(TierFree) {
+		t.Errorf("ForTier(unknown) = %+v, want the free tier", got)
+	}
+}
@@ -0,0 +1,443 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/media"
+)
+
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusReady      = "ready"
+	StatusFailed     = "failed"
+)
+
+// pageSize is how many rows are read per query for data that is only
+// available through paginated queries.
+const pageSize = 500
+
+type profile struct {
+	ID                  uuid.UUID  `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	Email               string     `json:"email"`
+	Handle              string     `json:"handle"`
+	DisplayName         string     `json:"display_name"`
+	Bio                 string     `json:"bio"`
+	AvatarURL           string     `json:"avatar_url,omitempty"`
+	IsChirpyRed         bool       `json:"is_chirpy_red"`
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"`
+}
+
+// session describes a refresh token without the token itself.
+type session struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type attachment struct {
+	ID           uuid.UUID `json:"id"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+}
+
+type chirp struct {
+	ID          uuid.UUID    `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	Body        string       `json:"body"`
+	Status      string       `json:"status"`
+	PublishAt   *time.Time   `json:"publish_at,omitempty"`
+	ReplyToID   *uuid.UUID   `json:"reply_to_id,omitempty"`
+	RechirpOfID *uuid.UUID   `json:"rechirp_of_id,omitempty"`
+	QuoteOfID   *uuid.UUID   `json:"quote_of_id,omitempty"`
+	DeletedAt   *time.Time   `json:"deleted_at,omitempty"`
+	Attachments []attachment `json:"attachments,omitempty"`
+}
+
+type like struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type bookmark struct {
+	ChirpID      uuid.UUID  `json:"chirp_id"`
+	CollectionID *uuid.UUID `json:"collection_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type collection struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+}
+
+type notification struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Type      string     `json:"type"`
+	ActorID   uuid.UUID  `json:"actor_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id,omitempty"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+type preferences struct {
+	Mentions bool `json:"mentions"`
+	Replies  bool `json:"replies"`
+	Likes    bool `json:"likes"`
+}
+
+type relatedUser struct {
+	ID     uuid.UUID `json:"id"`
+	Handle string    `json:"handle"`
+}
+
+type conversation struct {
+	ID           uuid.UUID   `json:"id"`
+	CreatedAt    time.Time   `json:"created_at"`
+	IsGroup      bool        `json:"is_group"`
+	Participants []uuid.UUID `json:"participants"`
+}
+
+type message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Body           string    `json:"body"`
+}
+
+// webhook describes a subscription without its signing secret.
+type webhook struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+}
+
+// Build returns a ZIP archive with one JSON file per kind of data held about
+// the user. Password hashes, refresh token values and webhook secrets are
+// never included.
+func Build(ctx context.Context, db database.Querier, storage media.Storage, userID uuid.UUID) ([]byte, error) {
+	user, err := db.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	p := profile{
+		ID:                  user.ID,
+		CreatedAt:           user.CreatedAt,
+		UpdatedAt:           user.UpdatedAt,
+		Email:               user.Email,
+		Handle:              user.Handle,
+		DisplayName:         user.DisplayName,
+		Bio:                 user.Bio,
+		IsChirpyRed:         user.IsChirpyRed,
+		DeletionRequestedAt: nullTime(user.DeletionRequestedAt),
+	}
+	if user.AvatarKey.Valid {
+		p.AvatarURL = storage.URL(user.AvatarKey.String)
+	}
+	err = writeJSON(zw, "profile.json", p)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := db.ListRefreshTokensByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions := []session{}
+	for _, t := range tokens {
+		sessions = append(sessions, session{CreatedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt, RevokedAt: nullTime(t.RevokedAt)})
+	}
+	err = writeJSON(zw, "sessions.json", sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeChirps(ctx, zw, db, storage, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	likeRows, err := db.ListLikesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	likes := []like{}
+	for _, l := range likeRows {
+		likes = append(likes, like{ChirpID: l.ChirpID, CreatedAt: l.CreatedAt})
+	}
+	err = writeJSON(zw, "likes.json", likes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeBookmarks(ctx, zw, db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeNotifications(ctx, zw, db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeRelations(ctx, zw, db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeMessages(ctx, zw, db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := db.ListWebhookSubscriptionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	webhooks := []webhook{}
+	for _, s := range subs {
+		webhooks = append(webhooks, webhook{ID: s.ID, CreatedAt: s.CreatedAt, URL: s.Url, Events: s.Events, Active: s.Active})
+	}
+	err = writeJSON(zw, "webhooks.json", webhooks)
+	if err != nil {
+		return nil, err
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeChirps includes scheduled and soft-deleted chirps, which are still
+// stored until they are published or purged.
+func writeChirps(ctx context.Context, zw *zip.Writer, db database.Querier, storage media.Storage, userID uuid.UUID) error {
+	rows, err := db.ListAllChirpsByAuthorID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	attachmentRows, err := db.ListAttachmentsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	attachments := make(map[uuid.UUID][]attachment)
+	for _, a := range attachmentRows {
+		attachments[a.ChirpID] = append(attachments[a.ChirpID], attachment{
+			ID:           a.ID,
+			ContentType:  a.ContentType,
+			SizeBytes:    a.SizeBytes,
+			Width:        a.Width,
+			Height:       a.Height,
+			URL:          storage.URL(a.StorageKey),
+			ThumbnailURL: storage.URL(a.ThumbnailKey),
+		})
+	}
+	chirps := []chirp{}
+	for _, c := range rows {
+		chirps = append(chirps, chirp{
+			ID:          c.ID,
+			CreatedAt:   c.CreatedAt,
+			UpdatedAt:   c.UpdatedAt,
+			Body:        c.Body,
+			Status:      c.Status,
+			PublishAt:   nullTime(c.PublishAt),
+			ReplyToID:   nullUUID(c.ReplyToID),
+			RechirpOfID: nullUUID(c.RechirpOfID),
+			QuoteOfID:   nullUUID(c.QuoteOfID),
+			DeletedAt:   nullTime(c.DeletedAt),
+			Attachments: attachments[c.ID],
+		})
+	}
+	return writeJSON(zw, "chirps.json", chirps)
+}
+
+func writeBookmarks(ctx context.Context, zw *zip.Writer, db database.Querier, userID uuid.UUID) error {
+	rows, err := paged(func(limit, offset int32) ([]database.Bookmark, error) {
+		return db.ListBookmarks(ctx, database.ListBookmarksParams{UserID: userID, Limit: limit, Offset: offset})
+	})
+	if err != nil {
+		return err
+	}
+	bookmarks := []bookmark{}
+	for _, b := range rows {
+		bookmarks = append(bookmarks, bookmark{ChirpID: b.ChirpID, CollectionID: nullUUID(b.CollectionID), CreatedAt: b.CreatedAt})
+	}
+	err = writeJSON(zw, "bookmarks.json", bookmarks)
+	if err != nil {
+		return err
+	}
+
+	collectionRows, err := db.ListBookmarkCollections(ctx, userID)
+	if err != nil {
+		return err
+	}
+	collections := []collection{}
+	for _, c := range collectionRows {
+		collections = append(collections, collection{ID: c.ID, CreatedAt: c.CreatedAt, Name: c.Name})
+	}
+	return writeJSON(zw, "bookmark_collections.json", collections)
+}
+
+func writeNotifications(ctx context.Context, zw *zip.Writer, db database.Querier, userID uuid.UUID) error {
+	rows, err := paged(func(limit, offset int32) ([]database.Notification, error) {
+		return db.ListNotificationsByUserID(ctx, database.ListNotificationsByUserIDParams{UserID: userID, Limit: limit, Offset: offset})
+	})
+	if err != nil {
+		return err
+	}
+	notifications := []notification{}
+	for _, n := range rows {
+		notifications = append(notifications, notification{
+			ID:        n.ID,
+			CreatedAt: n.CreatedAt,
+			Type:      n.Type,
+			ActorID:   n.ActorID,
+			ChirpID:   nullUUID(n.ChirpID),
+			ReadAt:    nullTime(n.ReadAt),
+		})
+	}
+	err = writeJSON(zw, "notifications.json", notifications)
+	if err != nil {
+		return err
+	}
+
+	// Users who never changed their preferences have no row and get the
+	// defaults, which is everything enabled.
+	prefs := preferences{Mentions: true, Replies: true, Likes: true}
+	row, err := db.GetNotificationPreferences(ctx, userID)
+	if err == nil {
+		prefs = preferences{Mentions: row.Mentions, Replies: row.Replies, Likes: row.Likes}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return writeJSON(zw, "notification_preferences.json", prefs)
+}
+
+func writeRelations(ctx context.Context, zw *zip.Writer, db database.Querier, userID uuid.UUID) error {
+	blocked, err := paged(func(limit, offset int32) ([]database.User, error) {
+		return db.ListBlockedUsers(ctx, database.ListBlockedUsersParams{BlockerID: userID, Limit: limit, Offset: offset})
+	})
+	if err != nil {
+		return err
+	}
+	err = writeJSON(zw, "blocks.json", relatedUsers(blocked))
+	if err != nil {
+		return err
+	}
+	muted, err := paged(func(limit, offset int32) ([]database.User, error) {
+		return db.ListMutedUsers(ctx, database.ListMutedUsersParams{MuterID: userID, Limit: limit, Offset: offset})
+	})
+	if err != nil {
+		return err
+	}
+	return writeJSON(zw, "mutes.json", relatedUsers(muted))
+}
+
+// writeMessages exports every conversation the user is part of, but only the
+// messages they sent themselves.
+func writeMessages(ctx context.Context, zw *zip.Writer, db database.Querier, userID uuid.UUID) error {
+	rows, err := paged(func(limit, offset int32) ([]database.Conversation, error) {
+		return db.ListConversationsByUserID(ctx, database.ListConversationsByUserIDParams{UserID: userID, Limit: limit, Offset: offset})
+	})
+	if err != nil {
+		return err
+	}
+	conversations := []conversation{}
+	for _, c := range rows {
+		participants, err := db.ListConversationParticipants(ctx, c.ID)
+		if err != nil {
+			return err
+		}
+		ids := []uuid.UUID{}
+		for _, p := range participants {
+			ids = append(ids, p.UserID)
+		}
+		conversations = append(conversations, conversation{ID: c.ID, CreatedAt: c.CreatedAt, IsGroup: c.IsGroup, Participants: ids})
+	}
+	err = writeJSON(zw, "conversations.json", conversations)
+	if err != nil {
+		return err
+	}
+
+	messageRows, err := db.ListMessagesBySenderID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	messages := []message{}
+	for _, m := range messageRows {
+		messages = append(messages, message{ID: m.ID, CreatedAt: m.CreatedAt, ConversationID: m.ConversationID, Body: m.Body})
+	}
+	return writeJSON(zw, "messages.json", messages)
+}
+
+func relatedUsers(users []database.User) []relatedUser {
+	res := []relatedUser{}
+	for _, u := range users {
+		res = append(res, relatedUser{ID: u.ID, Handle: u.Handle})
+	}
+	return res
+}
+
+// paged calls list with increasing offsets until it returns a short page.
+func paged[T any](list func(limit, offset int32) ([]T, error)) ([]T, error) {
+	var all []T
+	for offset := int32(0); ; offset += pageSize {
+		page, err := list(pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < pageSize {
+			return all, nil
+		}
+	}
+}
+
+func writeJSON(zw *zip.Writer, name string, v any) error {
+	dat, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(dat)
+	return err
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullUUID(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
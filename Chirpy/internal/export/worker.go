@@ -0,0 +1,91 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/media"
+)
+
+// Worker builds queued exports in the background. Jobs are claimed with
+// FOR UPDATE SKIP LOCKED, and a job left in processing by an instance that
+// died is picked up again once it is older than StaleAfter.
+type Worker struct {
+	db    database.Querier
+	media media.Storage
+	// Expiry is how long a finished archive can be downloaded.
+	Expiry       time.Duration
+	StaleAfter   time.Duration
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+func NewWorker(db database.Querier, storage media.Storage) *Worker {
+	return &Worker{
+		db:           db,
+		media:        storage,
+		Expiry:       7 * 24 * time.Hour,
+		StaleAfter:   30 * time.Minute,
+		BatchSize:    5,
+		PollInterval: 30 * time.Second,
+	}
+}
+
+// Run processes pending exports every PollInterval until ctx is cancelled,
+// finishing the batch in progress.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+	work := context.WithoutCancel(ctx)
+	for {
+		w.Process(work)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process builds one batch of pending exports, removes expired archives and
+// returns how many exports it built.
+func (w *Worker) Process(ctx context.Context) int {
+	_, err := w.db.DeleteExpiredDataExports(ctx)
+	if err != nil {
+		slog.Error("export: delete expired exports", "err", err)
+	}
+	jobs, err := w.db.ClaimPendingDataExports(ctx, database.ClaimPendingDataExportsParams{
+		StaleAfterSeconds: w.StaleAfter.Seconds(),
+		MaxRows:           int32(w.BatchSize),
+	})
+	if err != nil {
+		slog.Error("export: claim exports", "err", err)
+		return 0
+	}
+	built := 0
+	for _, job := range jobs {
+		archive, err := Build(ctx, w.db, w.media, job.UserID)
+		if err != nil {
+			slog.Error("export: build", "export_id", job.ID, "err", err)
+			err = w.db.FailDataExport(ctx, database.FailDataExportParams{
+				ID:               job.ID,
+				Error:            sql.NullString{String: "The export could not be generated", Valid: true},
+				ExpiresInSeconds: w.Expiry.Seconds(),
+			})
+			if err != nil {
+				slog.Error("export: mark failed", "export_id", job.ID, "err", err)
+			}
+			continue
+		}
+		err = w.db.CompleteDataExport(ctx, database.CompleteDataExportParams{ID: job.ID, Archive: archive, ExpiresInSeconds: w.Expiry.Seconds()})
+		if err != nil {
+			slog.Error("export: store", "export_id", job.ID, "err", err)
+			continue
+		}
+		built++
+	}
+	return built
+}
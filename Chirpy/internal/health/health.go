@@ -0,0 +1,120 @@
+// Package health checks that the database is reachable and migrated, at
+// startup and for the readiness probe.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"Chirpy/internal/migrations"
+)
+
+const (
+	minBackoff  = 100 * time.Millisecond
+	maxBackoff  = 5 * time.Second
+	pingTimeout = 5 * time.Second
+)
+
+// WaitForDB pings db until it answers, backing off exponentially between
+// attempts, so the server can start alongside its database. It gives up after
+// timeout or when ctx is cancelled.
+func WaitForDB(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	delay := minBackoff
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancelPing := context.WithTimeout(ctx, pingTimeout)
+		err := db.PingContext(pingCtx)
+		cancelPing()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("database unreachable after %d attempts: %w", attempt, err)
+		}
+		slog.Warn("health: database not ready", "attempt", attempt, "retry_in", delay.String(), "err", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database unreachable after %d attempts: %w", attempt, err)
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxBackoff)
+	}
+}
+
+// Status values of a Report and its checks.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Report is the outcome of Checker.Check.
+type Report struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks"`
+}
+
+type Check struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Version and Want are the applied and expected schema versions.
+	Version int64 `json:"version,omitempty"`
+	Want    int64 `json:"want,omitempty"`
+}
+
+// Checker decides whether the server is ready for traffic: the database
+// answers and its schema is at least the version the code was built for, as
+// in migrations.Migrator.Check. With a nil db, as on the JSON driver, there
+// is nothing to check and the server is always ready.
+type Checker struct {
+	db            *sql.DB
+	schemaVersion int64
+	Timeout       time.Duration
+}
+
+func NewChecker(db *sql.DB, schemaVersion int64) *Checker {
+	return &Checker{
+		db:            db,
+		schemaVersion: schemaVersion,
+		Timeout:       2 * time.Second,
+	}
+}
+
+func (c *Checker) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	report := Report{Status: StatusOK, Checks: map[string]Check{}}
+	fail := func(name string, check Check) {
+		check.Status = StatusUnavailable
+		report.Checks[name] = check
+		report.Status = StatusUnavailable
+	}
+	if c.db == nil {
+		return report
+	}
+
+	// Reports may be public, so they carry a summary and the cause is logged.
+	err := c.db.PingContext(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "health: ping database", "err", err)
+		fail("database", Check{Error: "unreachable"})
+		fail("migrations", Check{Error: "database unreachable", Want: c.schemaVersion})
+		return report
+	}
+	report.Checks["database"] = Check{Status: StatusOK}
+
+	version, err := migrations.Version(ctx, c.db)
+	switch {
+	case err != nil:
+		slog.WarnContext(ctx, "health: schema version", "err", err)
+		fail("migrations", Check{Error: "version unknown", Want: c.schemaVersion})
+	case version < c.schemaVersion:
+		fail("migrations", Check{Error: "schema is behind", Version: version, Want: c.schemaVersion})
+	default:
+		report.Checks["migrations"] = Check{Status: StatusOK, Version: version, Want: c.schemaVersion}
+	}
+	return report
+}
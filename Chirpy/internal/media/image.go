@@ -0,0 +1,313 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	MaxUploadBytes = 10 << 20
+	MaxPixels      = 40_000_000
+	ThumbnailSize  = 320
+	// MaxGIFFrames caps the frames of an animated GIF. Every frame decodes to
+	// a full-size paletted image, so the frames together are also held to
+	// MaxPixels.
+	MaxGIFFrames = 500
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooLarge        = errors.New("media is too large")
+)
+
+// Processed is an upload that has been validated and re-encoded. Re-encoding
+// drops every metadata block, including EXIF location data.
+type Processed struct {
+	ContentType string
+	Ext         string
+	Data        []byte
+	Width       int
+	Height      int
+	Thumbnail   []byte
+}
+
+const ThumbnailContentType = "image/jpeg"
+
+// Process sniffs the content type of data, rejects anything that is not a
+// supported image, strips metadata and generates a JPEG thumbnail.
+func Process(data []byte) (Processed, error) {
+	if len(data) > MaxUploadBytes {
+		return Processed{}, ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+	default:
+		return Processed{}, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrUnsupportedType
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return Processed{}, ErrTooLarge
+	}
+
+	if contentType == "image/gif" {
+		frames, ok := gifFrames(data)
+		if !ok {
+			return Processed{}, ErrUnsupportedType
+		}
+		if frames > MaxGIFFrames || frames*cfg.Width*cfg.Height > MaxPixels {
+			return Processed{}, ErrTooLarge
+		}
+		return processGIF(data)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrUnsupportedType
+	}
+
+	var out bytes.Buffer
+	p := Processed{}
+	switch contentType {
+	case "image/jpeg":
+		// The orientation lives in the EXIF block we are about to drop, so
+		// bake it into the pixels first.
+		img = applyOrientation(img, jpegOrientation(data))
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: 90})
+		p.ContentType, p.Ext = "image/jpeg", ".jpg"
+	default:
+		// There is no WebP encoder in the standard library, so WebP uploads
+		// are stored as PNG.
+		err = png.Encode(&out, img)
+		p.ContentType, p.Ext = "image/png", ".png"
+	}
+	if err != nil {
+		return Processed{}, err
+	}
+	p.Data = out.Bytes()
+	p.Width, p.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	p.Thumbnail, err = thumbnail(img)
+	if err != nil {
+		return Processed{}, err
+	}
+	return p, nil
+}
+
+func processGIF(data []byte) (Processed, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrUnsupportedType
+	}
+	// Drop comment and application extensions; only frames, timing and the
+	// loop count are kept.
+	var out bytes.Buffer
+	err = gif.EncodeAll(&out, &gif.GIF{
+		Image:           g.Image,
+		Delay:           g.Delay,
+		LoopCount:       g.LoopCount,
+		Disposal:        g.Disposal,
+		Config:          g.Config,
+		BackgroundIndex: g.BackgroundIndex,
+	})
+	if err != nil {
+		return Processed{}, err
+	}
+	thumb, err := thumbnail(g.Image[0])
+	if err != nil {
+		return Processed{}, err
+	}
+	return Processed{
+		ContentType: "image/gif",
+		Ext:         ".gif",
+		Data:        out.Bytes(),
+		Width:       g.Config.Width,
+		Height:      g.Config.Height,
+		Thumbnail:   thumb,
+	}, nil
+}
+
+// gifFrames counts the frames of a GIF by walking its blocks, without
+// decompressing any of them. It reports false if data is not a well-formed
+// GIF.
+func gifFrames(data []byte) (int, bool) {
+	// Header and logical screen descriptor.
+	i := 13
+	if len(data) < i {
+		return 0, false
+	}
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1)
+	}
+	frames := 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // Extension: introducer, label, sub-blocks.
+			i += 2
+		case 0x2C: // Image descriptor, then the LZW minimum code size.
+			if i+10 > len(data) {
+				return 0, false
+			}
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			i++
+			frames++
+		case 0x3B: // Trailer.
+			return frames, true
+		default:
+			return 0, false
+		}
+		// Skip the sub-blocks up to the zero-length terminator.
+		for {
+			if i >= len(data) {
+				return 0, false
+			}
+			n := int(data[i])
+			i += 1 + n
+			if n == 0 {
+				break
+			}
+		}
+	}
+	return 0, false
+}
+
+// thumbnail scales img to fit in a ThumbnailSize square, flattened onto white.
+func thumbnail(img image.Image) ([]byte, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > ThumbnailSize || h > ThumbnailSize {
+		if w >= h {
+			w, h = ThumbnailSize, max(1, h*ThumbnailSize/w)
+		} else {
+			w, h = max(1, w*ThumbnailSize/h), ThumbnailSize
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+
+	var out bytes.Buffer
+	err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 80})
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// jpegOrientation returns the EXIF orientation tag of a JPEG, or 1 if it has
+// none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		// Image data starts at SOS; EXIF always comes before it.
+		if marker == 0xDA {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			o := int(order.Uint16(tiff[entry+8:]))
+			if o < 1 || o > 8 {
+				return 1
+			}
+			return o
+		}
+	}
+	return 1
+}
+
+// applyOrientation transforms img so it displays upright for EXIF
+// orientation o.
+func applyOrientation(img image.Image, o int) image.Image {
+	if o < 2 || o > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,308 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+var palette = color.Palette{color.Black, color.White}
+
+// animation returns a GIF of n single-pixel frames.
+func animation(n int) *gif.GIF {
+	g := &gif.GIF{}
+	for range n {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), palette))
+		g.Delay = append(g.Delay, 10)
+	}
+	return g
+}
+
+func encodeGIF(t *testing.T, g *gif.GIF) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// exifSegment returns an APP1 segment holding a TIFF block in the given byte
+// order whose first IFD has the orientation tag set to o.
+func exifSegment(order binary.ByteOrder, o uint16) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112) // Orientation
+	order.PutUint16(tiff[12:], 3)      // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], o)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(2+len(payload)))
+	return append(seg, payload...)
+}
+
+// withSegment inserts seg into a JPEG straight after its SOI marker.
+func withSegment(jpg, seg []byte) []byte {
+	return append(append(append([]byte{}, jpg[:2]...), seg...), jpg[2:]...)
+}
+
+func decodeSize(t *testing.T, data []byte) (int, int) {
+	t.Helper()
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.Width, cfg.Height
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	jpg := encodeJPEG(t, testImage(4, 2))
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no EXIF", jpg, 1},
+		{"big endian", withSegment(jpg, exifSegment(binary.BigEndian, 6)), 6},
+		{"little endian", withSegment(jpg, exifSegment(binary.LittleEndian, 8)), 8},
+		{"out of range", withSegment(jpg, exifSegment(binary.BigEndian, 9)), 1},
+		{"truncated IFD", withSegment(jpg, exifSegment(binary.BigEndian, 6)[:22]), 1},
+		{"not a JPEG", encodePNG(t, testImage(4, 2)), 1},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	const w, h = 3, 2
+	src := testImage(w, h)
+	topLeft, topRight := src.At(0, 0), src.At(w-1, 0)
+
+	// Where the source's top corners should end up once the image is upright.
+	tests := []struct {
+		o                 int
+		width, height     int
+		topLeft, topRight image.Point
+	}{
+		{1, w, h, image.Pt(0, 0), image.Pt(w-1, 0)},
+		{2, w, h, image.Pt(w-1, 0), image.Pt(0, 0)},
+		{3, w, h, image.Pt(w-1, h-1), image.Pt(0, h-1)},
+		{4, w, h, image.Pt(0, h-1), image.Pt(w-1, h-1)},
+		{5, h, w, image.Pt(0, 0), image.Pt(0, w-1)},
+		{6, h, w, image.Pt(h-1, 0), image.Pt(h-1, w-1)},
+		{7, h, w, image.Pt(h-1, w-1), image.Pt(h-1, 0)},
+		{8, h, w, image.Pt(0, w-1), image.Pt(0, 0)},
+	}
+	for _, tt := range tests {
+		got := applyOrientation(src, tt.o)
+		if b := got.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+			t.Errorf("orientation %d: got %dx%d, want %dx%d", tt.o, b.Dx(), b.Dy(), tt.width, tt.height)
+			continue
+		}
+		if c := got.At(tt.topLeft.X, tt.topLeft.Y); c != topLeft {
+			t.Errorf("orientation %d: top left corner is not at %v", tt.o, tt.topLeft)
+		}
+		if c := got.At(tt.topRight.X, tt.topRight.Y); c != topRight {
+			t.Errorf("orientation %d: top right corner is not at %v", tt.o, tt.topRight)
+		}
+	}
+}
+
+func TestProcessJPEG(t *testing.T) {
+	data := withSegment(encodeJPEG(t, testImage(32, 16)), exifSegment(binary.BigEndian, 6))
+	p, err := Process(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ContentType != "image/jpeg" || p.Ext != ".jpg" {
+		t.Errorf("got %s %s, want image/jpeg .jpg", p.ContentType, p.Ext)
+	}
+	if p.Width != 16 || p.Height != 32 {
+		t.Errorf("got %dx%d, want the rotated 16x32", p.Width, p.Height)
+	}
+	if w, h := decodeSize(t, p.Data); w != 16 || h != 32 {
+		t.Errorf("data is %dx%d, want 16x32", w, h)
+	}
+	if bytes.Contains(p.Data, []byte("Exif")) {
+		t.Error("EXIF block was kept")
+	}
+	if o := jpegOrientation(p.Data); o != 1 {
+		t.Errorf("orientation = %d, want 1", o)
+	}
+}
+
+func TestProcessGIF(t *testing.T) {
+	frame := func() *image.Paletted {
+		return image.NewPaletted(image.Rect(0, 0, 8, 4), color.Palette{color.Black, color.White})
+	}
+	data := encodeGIF(t, &gif.GIF{
+		Image:     []*image.Paletted{frame(), frame()},
+		Delay:     []int{10, 20},
+		LoopCount: 0,
+	})
+	// Splice a comment extension in before the trailer.
+	comment := "taken at home"
+	ext := append([]byte{0x21, 0xFE, byte(len(comment))}, comment...)
+	ext = append(ext, 0)
+	data = append(append(data[:len(data)-1:len(data)-1], ext...), 0x3B)
+
+	p, err := Process(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ContentType != "image/gif" || p.Width != 8 || p.Height != 4 {
+		t.Errorf("got %s %dx%d, want image/gif 8x4", p.ContentType, p.Width, p.Height)
+	}
+	if bytes.Contains(p.Data, []byte(comment)) {
+		t.Error("comment extension was kept")
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(p.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 2 || g.Delay[1] != 20 {
+		t.Errorf("got %d frames with delays %v, want 2 with [10 20]", len(g.Image), g.Delay)
+	}
+}
+
+func TestGIFFrames(t *testing.T) {
+	data := encodeGIF(t, animation(3))
+	// A frame with a palette of its own gets a local colour table, which
+	// moves its LZW data along.
+	mixed := animation(2)
+	mixed.Config = image.Config{ColorModel: palette, Width: 1, Height: 1}
+	mixed.Image[1].Palette = color.Palette{color.Black, color.White, color.Gray{Y: 128}}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		want   int
+		wantOK bool
+	}{
+		{"three frames", data, 3, true},
+		{"local colour table", encodeGIF(t, mixed), 2, true},
+		{"no trailer", data[:len(data)-1], 0, false},
+		{"truncated", data[:len(data)/2], 0, false},
+		{"header only", data[:13], 0, false},
+		{"empty", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := gifFrames(tt.data)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("gifFrames = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		w, h         int
+		wantW, wantH int
+	}{
+		{640, 320, 320, 160},
+		{100, 1000, 32, 320},
+		{100, 50, 100, 50},
+		{2000, 1, 320, 1},
+	}
+	for _, tt := range tests {
+		thumb, err := thumbnail(testImage(tt.w, tt.h))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w, h := decodeSize(t, thumb); w != tt.wantW || h != tt.wantH {
+			t.Errorf("thumbnail of %dx%d is %dx%d, want %dx%d", tt.w, tt.h, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+// withPNGSize rewrites the dimensions in a PNG's header, leaving the pixel
+// data alone, so a huge image costs nothing to build.
+func withPNGSize(data []byte, w, h uint32) []byte {
+	data = bytes.Clone(data)
+	binary.BigEndian.PutUint32(data[16:], w)
+	binary.BigEndian.PutUint32(data[20:], h)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestProcessLimits(t *testing.T) {
+	small := encodePNG(t, testImage(4, 4))
+	// Three frames are fine alone, but each decodes to the full canvas.
+	bigCanvas := animation(3)
+	bigCanvas.Config = image.Config{ColorModel: palette, Width: 4000, Height: 4000}
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"too many bytes", append(bytes.Clone(small), make([]byte, MaxUploadBytes)...), ErrTooLarge},
+		{"too many pixels", withPNGSize(small, 8000, 8000), ErrTooLarge},
+		{"too many frames", encodeGIF(t, animation(MaxGIFFrames+1)), ErrTooLarge},
+		{"too many pixels over all frames", encodeGIF(t, bigCanvas), ErrTooLarge},
+		{"text", []byte("hello, world"), ErrUnsupportedType},
+		{"corrupt PNG", small[:40], ErrUnsupportedType},
+		{"small PNG", small, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process(tt.data)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Process = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
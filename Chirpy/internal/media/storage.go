@@ -0,0 +1,90 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage stores uploaded media under opaque keys.
+type Storage interface {
+	Put(ctx context.Context, key string, contentType string, data []byte) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// LocalStorage keeps media on the local disk. Files are served by mounting
+// an http.FileServer over Root at BaseURL.
+type LocalStorage struct {
+	Root    string
+	BaseURL string
+}
+
+func NewLocalStorage(root, baseURL string) *LocalStorage {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &LocalStorage{
+		Root:    root,
+		BaseURL: baseURL,
+	}
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	p := filepath.Join(s.Root, filepath.FromSlash(key))
+	rel, err := filepath.Rel(s.Root, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", errors.New("media: invalid key " + key)
+	}
+	return p, nil
+}
+
+// Put writes data to a temporary file and renames it into place so readers
+// never see a partial file.
+func (s *LocalStorage) Put(ctx context.Context, key string, contentType string, data []byte) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.BaseURL + key
+}
@@ -0,0 +1,78 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	s := NewLocalStorage(root, "/media")
+
+	for _, data := range []string{"first", "second"} {
+		err := s.Put(ctx, "avatars/u1/a.png", "image/png", []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(root, "avatars", "u1", "a.png"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != data {
+			t.Errorf("read %q, want %q", got, data)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "avatars", "u1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("got %d files, want no temporary files left behind", len(entries))
+	}
+	info, err := os.Stat(filepath.Join(root, "avatars", "u1", "a.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0644 {
+		t.Errorf("mode = %v, want 0644", perm)
+	}
+
+	if got := s.URL("avatars/u1/a.png"); got != "/media/avatars/u1/a.png" {
+		t.Errorf("URL = %q", got)
+	}
+
+	err = s.Delete(ctx, "avatars/u1/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(filepath.Join(root, "avatars", "u1", "a.png"))
+	if !os.IsNotExist(err) {
+		t.Errorf("file still exists after Delete: %v", err)
+	}
+	err = s.Delete(ctx, "avatars/u1/a.png")
+	if err != nil {
+		t.Errorf("Delete of a missing file = %v, want nil", err)
+	}
+}
+
+func TestLocalStorageInvalidKey(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "media")
+	s := NewLocalStorage(root, "/media/")
+
+	for _, key := range []string{"", ".", "../escape.png", "a/../../escape.png"} {
+		if err := s.Put(ctx, key, "image/png", []byte("x")); err == nil {
+			t.Errorf("Put(%q) succeeded, want an error", key)
+		}
+		if err := s.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded, want an error", key)
+		}
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(root), "escape.png"))
+	if !os.IsNotExist(err) {
+		t.Errorf("a file was written outside the root: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Package metrics collects Chirpy's Prometheus metrics in a single registry,
+// which backs both /metrics and the admin page.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const namespace = "chirpy"
+
+// Metric names other packages read back out of the registry.
+const (
+	FileserverHitsName = namespace + "_fileserver_hits_total"
+	RequestsName       = namespace + "_http_requests_total"
+)
+
+// Auth failure reasons, used as the reason label of
+// chirpy_auth_failures_total.
+const (
+	ReasonMissingToken        = "missing_token"
+	ReasonInvalidToken        = "invalid_token"
+	ReasonInvalidCredentials  = "invalid_credentials"
+	ReasonInvalidRefreshToken = "invalid_refresh_token"
+	ReasonInvalidAPIKey       = "invalid_api_key"
+	ReasonAccountDisabled     = "account_disabled"
+)
+
+// UnmatchedRoute labels requests no route pattern matched, so unknown paths
+// cannot blow up the route label's cardinality.
+const UnmatchedRoute = "unmatched"
+
+type Metrics struct {
+	registry       *prometheus.Registry
+	fileserverHits atomic.Int64
+	requests       *prometheus.CounterVec
+	duration       *prometheus.HistogramVec
+	inFlight       prometheus.Gauge
+	authFailures   *prometheus.CounterVec
+}
+
+// New registers Chirpy's metrics, along with Go runtime, process and db
+// connection pool stats, in a fresh registry.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: RequestsName,
+			Help: "HTTP requests served, by route pattern, method and status.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests, by route pattern and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being served.",
+		}),
+		authFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_failures_total",
+			Help:      "Rejected authentication attempts, by reason.",
+		}, []string{"reason"}),
+	}
+	fileserverHits := prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: FileserverHitsName,
+		Help: "Requests served by the /app file server since the last reset.",
+	}, func() float64 {
+		return float64(m.fileserverHits.Load())
+	})
+	m.registry.MustRegister(
+		fileserverHits,
+		m.requests,
+		m.duration,
+		m.inFlight,
+		m.authFailures,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+	}
+	return m
+}
+
+// Handler serves the registry in the Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Gather returns the current value of every metric in the registry.
+func (m *Metrics) Gather() ([]*dto.MetricFamily, error) {
+	return m.registry.Gather()
+}
+
+func (m *Metrics) IncFileserverHits() {
+	m.fileserverHits.Add(1)
+}
+
+// ResetFileserverHits zeroes the hit counter. Prometheus treats the drop as a
+// counter reset, so rates stay correct.
+func (m *Metrics) ResetFileserverHits() {
+	m.fileserverHits.Store(0)
+}
+
+func (m *Metrics) AuthFailure(reason string) {
+	m.authFailures.WithLabelValues(reason).Inc()
+}
+
+// RequestStarted counts a request as in flight until the returned function
+// records how it finished.
+func (m *Metrics) RequestStarted() func(route, method string, status int) {
+	start := time.Now()
+	m.inFlight.Inc()
+	return func(route, method string, status int) {
+		m.inFlight.Dec()
+		if route == "" {
+			route = UnmatchedRoute
+		}
+		method = knownMethod(method)
+		m.requests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+		m.duration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// knownMethod folds nonstandard methods into one label value.
+func knownMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// Sum adds up every sample of the named counter or gauge family in families.
+func Sum(families []*dto.MetricFamily, name string) float64 {
+	var total float64
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			total += metric.GetCounter().GetValue() + metric.GetGauge().GetValue()
+		}
+	}
+	return total
+}
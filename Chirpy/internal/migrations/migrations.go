@@ -0,0 +1,161 @@
+// Package migrations applies the goose migrations in sql/schema, or
+// sql/sqlite/schema on SQLite, which are embedded in the binary so a deploy
+// cannot run code against a schema it was not built for.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+
+	"Chirpy/internal/storage"
+)
+
+// ErrSchemaBehind is returned by Check when migrations are pending.
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// Migrator runs migrations. On Postgres it holds an advisory lock, so
+// instances that start together apply each migration once: the first takes
+// the lock and the rest wait for it, then find nothing left to do. A SQLite
+// database has a single server process and needs no lock.
+type Migrator struct {
+	db       *sql.DB
+	provider *goose.Provider
+}
+
+// New reads the migrations in the root of fsys, written for driver.
+func New(db *sql.DB, driver string, fsys fs.FS) (*Migrator, error) {
+	var provider *goose.Provider
+	switch driver {
+	case storage.Postgres:
+		locker, err := lock.NewPostgresSessionLocker()
+		if err != nil {
+			return nil, err
+		}
+		provider, err = goose.NewProvider(goose.DialectPostgres, db, fsys, goose.WithSessionLocker(locker))
+		if err != nil {
+			return nil, err
+		}
+	case storage.SQLite:
+		var err error
+		provider, err = goose.NewProvider(goose.DialectSQLite3, db, fsys)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("migrations: unknown driver %q", driver)
+	}
+	return &Migrator{db: db, provider: provider}, nil
+}
+
+// Latest is the version of the newest migration.
+func (m *Migrator) Latest() int64 {
+	sources := m.provider.ListSources()
+	if len(sources) == 0 {
+		return 0
+	}
+	return sources[len(sources)-1].Version
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	results, err := m.provider.Up(ctx)
+	for _, res := range results {
+		logResult(res)
+	}
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		slog.Info("migrations: schema is up to date", "version", m.Latest())
+	}
+	return nil
+}
+
+// Down rolls back the most recent migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	res, err := m.provider.Down(ctx)
+	if res != nil {
+		logResult(res)
+	}
+	return err
+}
+
+func logResult(res *goose.MigrationResult) {
+	if res.Error != nil {
+		slog.Error("migrations: failed", "direction", res.Direction, "migration", path.Base(res.Source.Path), "err", res.Error)
+		return
+	}
+	slog.Info("migrations: applied", "direction", res.Direction, "migration", path.Base(res.Source.Path), "duration_ms", res.Duration.Milliseconds())
+}
+
+// Status writes a table of every migration and whether it is applied.
+func (m *Migrator) Status(ctx context.Context, w io.Writer) error {
+	statuses, err := m.provider.Status(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tSTATE\tAPPLIED AT\tMIGRATION")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if s.State == goose.StateApplied {
+			appliedAt = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, appliedAt, path.Base(s.Source.Path))
+	}
+	return tw.Flush()
+}
+
+// Check returns ErrSchemaBehind, with the versions, if the database is not at
+// Latest. A newer schema is accepted, since during a rolling deploy the old
+// instances keep serving after the new ones migrate.
+func (m *Migrator) Check(ctx context.Context) error {
+	version, err := Version(ctx, m.db)
+	if err != nil {
+		return err
+	}
+	if version < m.Latest() {
+		return fmt.Errorf("%w: at version %d, want %d", ErrSchemaBehind, version, m.Latest())
+	}
+	return nil
+}
+
+// Version returns the latest migration goose has applied to db: the highest
+// version whose most recent row is an apply rather than a rollback. Unlike
+// goose's own lookup it takes no lock and never creates the version table,
+// so it is safe for health checks while another instance migrates.
+func Version(ctx context.Context, db *sql.DB) (int64, error) {
+	var version int64
+	err := db.QueryRowContext(ctx, `
+SELECT COALESCE(MAX(v.version_id), 0) FROM goose_db_version v
+WHERE v.is_applied AND NOT EXISTS (
+    SELECT 1 FROM goose_db_version later
+    WHERE later.version_id = v.version_id AND later.id > v.id
+)`).Scan(&version)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "42P01" {
+		// undefined_table: nothing has been migrated yet.
+		return 0, nil
+	}
+	if err != nil && strings.Contains(err.Error(), "no such table: goose_db_version") {
+		// The same on SQLite.
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,110 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+)
+
+const (
+	TypeMention = "mention"
+	TypeReply   = "reply"
+	TypeLike    = "like"
+)
+
+// DefaultPreferences are used for users who never saved any preferences.
+func DefaultPreferences(userID uuid.UUID) database.NotificationPreference {
+	return database.NotificationPreference{
+		UserID:   userID,
+		Mentions: true,
+		Replies:  true,
+		Likes:    true,
+	}
+}
+
+// Allows reports whether prefs permit notifications of type typ.
+func Allows(prefs database.NotificationPreference, typ string) bool {
+	switch typ {
+	case TypeMention:
+		return prefs.Mentions
+	case TypeReply:
+		return prefs.Replies
+	case TypeLike:
+		return prefs.Likes
+	}
+	return true
+}
+
+// mentionPattern matches "@handle" but not the domain part of an email
+// address.
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_@.])@([A-Za-z0-9_]{3,30})\b`)
+
+// Mentions returns the unique handles mentioned with "@" in body, lowercased.
+func Mentions(body string) []string {
+	seen := make(map[string]bool)
+	var mentions []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		name := strings.ToLower(m[1])
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		mentions = append(mentions, name)
+	}
+	return mentions
+}
+
+type Notifier struct {
+	db database.Querier
+}
+
+func NewNotifier(db database.Querier) *Notifier {
+	return &Notifier{db: db}
+}
+
+// Preferences returns the saved preferences of a user, or the defaults.
+func (n *Notifier) Preferences(ctx context.Context, userID uuid.UUID) (database.NotificationPreference, error) {
+	prefs, err := n.db.GetNotificationPreferences(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultPreferences(userID), nil
+	}
+	return prefs, err
+}
+
+// Notify records a notification for recipient about something actor did.
+// Nothing is recorded when users act on their own chirps or the recipient
+// has turned this type of notification off.
+func (n *Notifier) Notify(ctx context.Context, typ string, recipient, actor uuid.UUID, chirpID uuid.UUID) error {
+	if recipient == actor {
+		return nil
+	}
+	// Blocks in either direction, and mutes by the recipient, drop the
+	// notification silently.
+	silenced, err := n.db.IsSilenced(ctx, database.IsSilencedParams{RecipientID: recipient, ActorID: actor})
+	if err != nil {
+		return err
+	}
+	if silenced {
+		return nil
+	}
+	prefs, err := n.Preferences(ctx, recipient)
+	if err != nil {
+		return err
+	}
+	if !Allows(prefs, typ) {
+		return nil
+	}
+	_, err = n.db.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:  recipient,
+		ActorID: actor,
+		Type:    typ,
+		ChirpID: uuid.NullUUID{UUID: chirpID, Valid: chirpID != uuid.Nil},
+	})
+	return err
+}
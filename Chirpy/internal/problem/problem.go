@@ -0,0 +1,74 @@
+// Package problem writes API errors as RFC 7807 problem details.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of every error response.
+const ContentType = "application/problem+json"
+
+// typeBase prefixes Code to form the problem type URI.
+const typeBase = "urn:chirpy:problem:"
+
+// Codes identify the kind of error. Clients may match on them, so existing
+// codes must never change meaning; titles and details are for humans and may.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeInvalidJSON          = "invalid_json"
+	CodeInvalidID            = "invalid_id"
+	CodeInvalidQuery         = "invalid_query"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeInvalidCredentials   = "invalid_credentials"
+	CodeForbidden            = "forbidden"
+	CodeUpgradeRequired      = "upgrade_required"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodeGone                 = "gone"
+	CodeRateLimited          = "rate_limited"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeInternal             = "internal_error"
+	CodeNotImplemented       = "not_implemented"
+)
+
+// Problem is the problem details object, extended with a stable Code.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+}
+
+func New(status int, code, detail string) Problem {
+	return Problem{
+		Type:   typeBase + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Write sends a problem for the request r. r may be nil when there is no
+// request to name as the instance.
+func Write(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	p := New(status, code, detail)
+	if r != nil {
+		p.Instance = r.URL.Path
+	}
+	dat, err := json.Marshal(p)
+	if err != nil {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(dat)
+}
@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// newTestStore returns a MemoryStore on a fake clock and a function that
+// moves the clock forward.
+func newTestStore() (*MemoryStore, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	s.lastSweep = now
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+type take struct {
+	after   time.Duration
+	tokens  float64
+	allowed bool
+}
+
+func checkTakes(t *testing.T, s *MemoryStore, advance func(time.Duration), key string, limit Limit, takes []take) {
+	t.Helper()
+	for i, want := range takes {
+		advance(want.after)
+		tokens, allowed, err := s.Take(context.Background(), key, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed != want.allowed || math.Abs(tokens-want.tokens) > 1e-9 {
+			t.Errorf("take %d: got %v tokens, allowed %v, want %v, %v", i+1, tokens, allowed, want.tokens, want.allowed)
+		}
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	limit := Every(3, 3*time.Second) // a token a second
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{"burst then denied", []take{
+			{0, 2, true},
+			{0, 1, true},
+			{0, 0, true},
+			{0, 0, false},
+		}},
+		{"refills at the rate", []take{
+			{0, 2, true},
+			{0, 1, true},
+			{0, 0, true},
+			{500 * time.Millisecond, 0.5, false},
+			{500 * time.Millisecond, 0, true},
+			{1500 * time.Millisecond, 0.5, true},
+		}},
+		{"refill stops at the burst", []take{
+			{0, 2, true},
+			{time.Hour, 2, true},
+		}},
+		{"denial spends nothing", []take{
+			{0, 2, true},
+			{0, 1, true},
+			{0, 0, true},
+			{0, 0, false},
+			{0, 0, false},
+			{time.Second, 0, true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, advance := newTestStore()
+			checkTakes(t, s, advance, "k", limit, tt.takes)
+		})
+	}
+}
+
+func TestMemoryStoreKeys(t *testing.T) {
+	s, advance := newTestStore()
+	limit := Every(1, time.Minute)
+	checkTakes(t, s, advance, "a", limit, []take{{0, 0, true}, {0, 0, false}})
+	checkTakes(t, s, advance, "b", limit, []take{{0, 0, true}})
+}
+
+func TestMemoryStoreLimitChange(t *testing.T) {
+	s, advance := newTestStore()
+	checkTakes(t, s, advance, "k", Every(2, 2*time.Second), []take{{0, 1, true}, {0, 0, true}})
+	// A bigger limit applies to the bucket as it stands, refilling at the
+	// new rate and up to the new burst.
+	checkTakes(t, s, advance, "k", Every(10, 2*time.Second), []take{
+		{100 * time.Millisecond, 0.5, false},
+		{100 * time.Millisecond, 0, true},
+		{time.Hour, 9, true},
+	})
+	// A smaller burst caps the tokens left over from the bigger one.
+	checkTakes(t, s, advance, "k", Every(2, 2*time.Second), []take{{0, 1, true}})
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s, advance := newTestStore()
+	limit := Every(2, 10*time.Minute)
+	ctx := context.Background()
+	for _, key := range []string{"idle", "busy"} {
+		_, _, err := s.Take(ctx, key, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The sweep runs on the first take after sweepInterval, and drops only
+	// buckets that have refilled.
+	advance(sweepInterval - time.Second)
+	checkTakes(t, s, advance, "busy", limit, []take{{0, (sweepInterval - time.Second).Seconds() * limit.Rate, true}})
+	if len(s.buckets) != 2 {
+		t.Fatalf("got %d buckets before the sweep, want 2", len(s.buckets))
+	}
+	advance(5 * time.Minute)
+	checkTakes(t, s, advance, "other", limit, []take{{0, 1, true}})
+	if _, ok := s.buckets["idle"]; ok {
+		t.Error("refilled bucket survived the sweep")
+	}
+	if _, ok := s.buckets["busy"]; !ok {
+		t.Error("partly empty bucket was swept")
+	}
+
+	// Once the busy bucket refills too, the next sweep drops it, and it
+	// starts over full.
+	advance(10 * time.Minute)
+	checkTakes(t, s, advance, "other", limit, []take{{0, 1, true}})
+	if _, ok := s.buckets["busy"]; ok {
+		t.Error("refilled bucket survived the sweep")
+	}
+	checkTakes(t, s, advance, "busy", limit, []take{{0, 1, true}})
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"Chirpy/internal/database"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every
+// instance shares them. Each take is a single upsert that refills and takes
+// in one statement, so concurrent requests cannot both spend the last token.
+// Refills use the database clock, so instance clock skew does not matter.
+type PostgresStore struct {
+	db database.Querier
+	// Buckets untouched for IdleAfter are deleted by Run. It must be longer
+	// than the window of every limit, or a deleted bucket would come back
+	// full early.
+	IdleAfter    time.Duration
+	PollInterval time.Duration
+}
+
+func NewPostgresStore(db database.Querier) *PostgresStore {
+	return &PostgresStore{
+		db:           db,
+		IdleAfter:    24 * time.Hour,
+		PollInterval: 10 * time.Minute,
+	}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (float64, bool, error) {
+	row, err := s.db.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(limit.Burst),
+		Rate:  limit.Rate,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return row.Tokens, row.Allowed, nil
+}
+
+// Run deletes idle buckets every PollInterval until ctx is cancelled.
+func (s *PostgresStore) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		_, err := s.db.DeleteIdleRateLimitBuckets(ctx, s.IdleAfter.Seconds())
+		if err != nil {
+			slog.Error("ratelimit: delete idle buckets", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
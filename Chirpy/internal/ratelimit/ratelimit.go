@@ -0,0 +1,120 @@
+// Package ratelimit implements token bucket rate limiting. Buckets live in a
+// Store: in memory for a single instance, or in Postgres when several
+// instances must share limits.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket holding up to Burst tokens, refilled at Rate tokens
+// per second. Every request takes one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Every allows n requests per window, all of which may be used at once.
+func Every(n int, window time.Duration) Limit {
+	return Limit{Rate: float64(n) / window.Seconds(), Burst: n}
+}
+
+// ParseLimit parses a limit written as "n/window", such as "30/1m".
+func ParseLimit(s string) (Limit, error) {
+	count, window, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q is not of the form n/window", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("limit %q: count must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("limit %q: invalid window", s)
+	}
+	return Every(n, d), nil
+}
+
+// String formats l in the form ParseLimit reads.
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Burst, l.Window().Round(time.Millisecond))
+}
+
+func (l Limit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText parses l with ParseLimit, so limits can be read from
+// configuration files.
+func (l *Limit) UnmarshalText(text []byte) error {
+	parsed, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// Scale multiplies the rate and burst of l by f, for callers with a larger
+// allowance. The burst never drops below one.
+func (l Limit) Scale(f float64) Limit {
+	return Limit{Rate: l.Rate * f, Burst: max(1, int(float64(l.Burst)*f))}
+}
+
+// Window is how long an empty bucket takes to fill.
+func (l Limit) Window() time.Duration {
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+// Result describes the bucket after a request tried to take a token.
+type Result struct {
+	Limit     Limit
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until a token is available; zero when Allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	res := Result{
+		Limit:     limit,
+		Allowed:   allowed,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store keeps token buckets by key. Take refills the bucket for key under
+// limit, then takes a token if one is available, and reports the tokens left
+// and whether it took one.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (tokens float64, allowed bool, err error)
+}
+
+// Take tries to take a token from the bucket for key in store.
+func Take(ctx context.Context, store Store, key string, limit Limit) (Result, error) {
+	tokens, allowed, err := store.Take(ctx, key, limit)
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(limit, tokens, allowed), nil
+}
@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{"30/1m", Limit{Rate: 0.5, Burst: 30}, false},
+		{" 5/1s ", Limit{Rate: 5, Burst: 5}, false},
+		{"10/500ms", Limit{Rate: 20, Burst: 10}, false},
+		{"30", Limit{}, true},
+		{"0/1m", Limit{}, true},
+		{"-1/1m", Limit{}, true},
+		{"x/1m", Limit{}, true},
+		{"30/0s", Limit{}, true},
+		{"30/soon", Limit{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLimit(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitText(t *testing.T) {
+	for _, s := range []string{"30/1m0s", "5/1s", "10/500ms", "100/1h0m0s"} {
+		var l Limit
+		err := l.UnmarshalText([]byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := l.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(text) != s {
+			t.Errorf("%s formats as %s", s, text)
+		}
+	}
+}
+
+func TestLimitScale(t *testing.T) {
+	base := Every(10, time.Minute)
+	tests := []struct {
+		f    float64
+		want Limit
+	}{
+		{1, base},
+		{3, Limit{Rate: base.Rate * 3, Burst: 30}},
+		{0.5, Limit{Rate: base.Rate * 0.5, Burst: 5}},
+		{0.01, Limit{Rate: base.Rate * 0.01, Burst: 1}},
+	}
+	for _, tt := range tests {
+		if got := base.Scale(tt.f); got != tt.want {
+			t.Errorf("Scale(%v) = %+v, want %+v", tt.f, got, tt.want)
+		}
+	}
+	// Scaling both sides keeps the time to refill.
+	if got := base.Scale(3).Window(); got != time.Minute {
+		t.Errorf("Window of a scaled limit = %v, want 1m", got)
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	limit := Every(10, 10*time.Second) // a token a second
+	tests := []struct {
+		name    string
+		tokens  float64
+		allowed bool
+		want    Result
+	}{
+		{"full after taking one", 9, true, Result{Limit: limit, Allowed: true, Remaining: 9, Reset: time.Second}},
+		{"part of a token left", 2.5, true, Result{Limit: limit, Allowed: true, Remaining: 2, Reset: 7500 * time.Millisecond}},
+		{"last token taken", 0, true, Result{Limit: limit, Allowed: true, Remaining: 0, Reset: 10 * time.Second}},
+		{"empty", 0.25, false, Result{Limit: limit, Remaining: 0, RetryAfter: 750 * time.Millisecond, Reset: 9750 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newResult(limit, tt.tokens, tt.allowed); got != tt.want {
+				t.Errorf("newResult = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeconds(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want time.Duration
+	}{
+		{1.5, 1500 * time.Millisecond},
+		{0, 0},
+		{-2, 0},
+		{math.Inf(-1), 0},
+	}
+	for _, tt := range tests {
+		if got := seconds(tt.in); got != tt.want {
+			t.Errorf("seconds(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/media"
+)
+
+// Purger permanently removes soft-deleted chirps once they are older than
+// Retention, together with their stored attachments. Rechirps, likes,
+// bookmarks and attachment rows go with them through foreign keys. It also
+// deletes accounts whose deletion was requested more than AccountGrace ago.
+type Purger struct {
+	db           database.Querier
+	media        media.Storage
+	Retention    time.Duration
+	AccountGrace time.Duration
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+func NewPurger(db database.Querier, storage media.Storage, retention, accountGrace time.Duration) *Purger {
+	return &Purger{
+		db:           db,
+		media:        storage,
+		Retention:    retention,
+		AccountGrace: accountGrace,
+		BatchSize:    500,
+		PollInterval: time.Hour,
+	}
+}
+
+// Run purges expired chirps and accounts every PollInterval until ctx is
+// cancelled, finishing the purge in progress.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.PollInterval)
+	defer ticker.Stop()
+	work := context.WithoutCancel(ctx)
+	for {
+		p.Purge(work)
+		p.PurgeAccounts(work)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge removes every chirp deleted more than Retention ago and returns how
+// many it removed.
+func (p *Purger) Purge(ctx context.Context) int64 {
+	var total int64
+	for {
+		ids, err := p.db.ListPurgeableChirpIDs(ctx, database.ListPurgeableChirpIDsParams{RetentionSeconds: p.Retention.Seconds(), MaxRows: int32(p.BatchSize)})
+		if err != nil {
+			slog.Error("retention: list purgeable chirps", "err", err)
+			return total
+		}
+		if len(ids) == 0 {
+			return total
+		}
+		attachments, err := p.db.ListAttachmentsByChirpIDs(ctx, ids)
+		if err != nil {
+			slog.Error("retention: list attachments", "err", err)
+			return total
+		}
+		n, err := p.db.PurgeChirps(ctx, ids)
+		if err != nil {
+			slog.Error("retention: purge chirps", "err", err)
+			return total
+		}
+		total += n
+		// Objects are removed after the rows so a failure here only leaves
+		// unreferenced files behind, never rows pointing at missing files.
+		for _, a := range attachments {
+			for _, key := range []string{a.StorageKey, a.ThumbnailKey} {
+				err := p.media.Delete(ctx, key)
+				if err != nil {
+					slog.Error("retention: delete media", "key", key, "err", err)
+				}
+			}
+		}
+		if len(ids) < p.BatchSize {
+			return total
+		}
+	}
+}
+
+// PurgeAccounts deletes every account whose deletion was requested more than
+// AccountGrace ago, along with its media, and returns how many it deleted.
+// Everything else the user owns goes with the row through foreign keys.
+func (p *Purger) PurgeAccounts(ctx context.Context) int64 {
+	grace := p.AccountGrace.Seconds()
+	users, err := p.db.ListUsersDueForDeletion(ctx, database.ListUsersDueForDeletionParams{GraceSeconds: grace, MaxRows: int32(p.BatchSize)})
+	if err != nil {
+		slog.Error("retention: list accounts due for deletion", "err", err)
+		return 0
+	}
+	var total int64
+	for _, user := range users {
+		attachments, err := p.db.ListAttachmentsByUserID(ctx, user.ID)
+		if err != nil {
+			slog.Error("retention: list attachments", "err", err)
+			continue
+		}
+		// The cutoff is checked again so an account whose deletion was
+		// cancelled in the meantime is left alone.
+		n, err := p.db.DeleteUser(ctx, database.DeleteUserParams{ID: user.ID, GraceSeconds: grace})
+		if err != nil {
+			slog.Error("retention: delete account", "user_id", user.ID, "err", err)
+			continue
+		}
+		if n == 0 {
+			continue
+		}
+		total += n
+		keys := []string{}
+		if user.AvatarKey.Valid {
+			keys = append(keys, user.AvatarKey.String)
+		}
+		for _, a := range attachments {
+			keys = append(keys, a.StorageKey, a.ThumbnailKey)
+		}
+		for _, key := range keys {
+			err := p.media.Delete(ctx, key)
+			if err != nil {
+				slog.Error("retention: delete media", "key", key, "err", err)
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"Chirpy/internal/database"
+)
+
+const (
+	StatusScheduled = "scheduled"
+	StatusPublished = "published"
+)
+
+// Scheduler publishes scheduled chirps once their publish_at has passed.
+// State lives entirely in the chirps table, so pending chirps survive
+// restarts, and rows are claimed with FOR UPDATE SKIP LOCKED so several
+// instances can run a scheduler without publishing a chirp twice.
+type Scheduler struct {
+	db database.Querier
+	// OnPublish runs once for every chirp this instance published, after the
+	// status change is committed.
+	OnPublish    func(ctx context.Context, chirp database.Chirp)
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+func New(db database.Querier, onPublish func(ctx context.Context, chirp database.Chirp)) *Scheduler {
+	return &Scheduler{
+		db:           db,
+		OnPublish:    onPublish,
+		BatchSize:    100,
+		PollInterval: 10 * time.Second,
+	}
+}
+
+// Run publishes due chirps every PollInterval until ctx is cancelled,
+// finishing the batch in progress.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	work := context.WithoutCancel(ctx)
+	for {
+		s.PublishDue(work)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishDue publishes every chirp that is due, a batch at a time, and
+// returns how many it published.
+func (s *Scheduler) PublishDue(ctx context.Context) int {
+	total := 0
+	for {
+		chirps, err := s.db.PublishDueChirps(ctx, int32(s.BatchSize))
+		if err != nil {
+			slog.Error("scheduler: publish due chirps", "err", err)
+			return total
+		}
+		for _, chirp := range chirps {
+			if s.OnPublish != nil {
+				s.OnPublish(ctx, chirp)
+			}
+		}
+		total += len(chirps)
+		if len(chirps) < s.BatchSize {
+			return total
+		}
+	}
+}
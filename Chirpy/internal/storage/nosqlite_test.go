@@ -0,0 +1,12 @@
+//go:build !sqlite
+
+package storage_test
+
+import "testing"
+
+// TestSQLite stands in for the real test in builds without the SQLite
+// driver, so that a plain go test reports the backend as skipped rather than
+// leaving it out silently.
+func TestSQLite(t *testing.T) {
+	t.Skip("built without the SQLite driver; run go test -tags sqlite ./internal/storage")
+}
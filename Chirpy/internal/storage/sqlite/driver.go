@@ -0,0 +1,17 @@
+//go:build sqlite
+
+package sqlite
+
+// The driver is pure Go, so SQLite needs no cgo, but it adds several
+// megabytes to the binary, and production runs on Postgres. It is only
+// compiled in with -tags sqlite.
+
+import _ "modernc.org/sqlite"
+
+func init() {
+	driverName = "sqlite"
+	// Each _pragma runs on every new connection. Immediate transactions
+	// take the write lock up front, so the busy timeout applies to them
+	// rather than failing when a read transaction tries to write.
+	connParams = "_pragma=foreign_keys(1)&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+}
@@ -0,0 +1,209 @@
+// Package sqlite runs Chirpy's queries on SQLite, so the server can run
+// without Postgres in development and CI.
+//
+// The sqlc queries are written for Postgres. Dialect swaps each one, by its
+// sqlc name, for the SQLite version of the same query and formats time
+// arguments the way SQLite stores them; results scan into the same
+// database types. The driver itself is only compiled in with the sqlite
+// build tag, see driver.go.
+package sqlite
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"Chirpy/internal/database"
+)
+
+// TimeFormat is how timestamps are stored: UTC with milliseconds, as
+// strftime('%Y-%m-%d %H:%M:%f', 'now') writes them, so that they compare and
+// sort correctly as text.
+const TimeFormat = "2006-01-02 15:04:05.000"
+
+// ErrNoDriver is returned by Open in binaries built without the sqlite tag.
+var ErrNoDriver = errors.New("this binary was built without SQLite support; rebuild it with -tags sqlite")
+
+// driverName and connParams are set by driver.go: the database/sql driver
+// and the DSN parameters that configure each of its connections.
+var (
+	driverName string
+	connParams string
+)
+
+// Open opens the SQLite database file at path, creating it if needed, with
+// foreign keys enforced and a busy timeout so concurrent writers wait for
+// each other rather than fail.
+func Open(path string) (*sql.DB, error) {
+	if driverName == "" {
+		return nil, ErrNoDriver
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return sql.Open(driverName, path+sep+connParams)
+}
+
+// IsUniqueViolation reports whether err is SQLite's unique constraint
+// error. Drivers wrap it in their own types but keep SQLite's message.
+func IsUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// Queries holds the SQLite version of each query, by sqlc name.
+type Queries map[string]string
+
+// ParseQueries reads the .sql files at the root of fsys, where each query
+// starts with a "-- name: Name :kind" line as in sqlc's query files. It
+// fails unless there is exactly one query for every database.Querier method.
+func ParseQueries(fsys fs.FS) (Queries, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	queries := Queries{}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		err = queries.parse(file, string(data))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	querier := reflect.TypeFor[database.Querier]()
+	var missing []string
+	for i := range querier.NumMethod() {
+		name := querier.Method(i).Name
+		if _, ok := queries[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("sqlite: no SQLite version of %s", strings.Join(missing, ", "))
+	}
+	for name := range queries {
+		if _, ok := querier.MethodByName(name); !ok {
+			return nil, fmt.Errorf("sqlite: query %s is not a database.Querier method", name)
+		}
+	}
+	return queries, nil
+}
+
+func (q Queries) parse(file, data string) error {
+	var name string
+	var query strings.Builder
+	flush := func() {
+		if name != "" {
+			q[name] = strings.TrimSpace(query.String())
+		}
+		query.Reset()
+	}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if header, ok := strings.CutPrefix(line, "-- name: "); ok {
+			flush()
+			fields := strings.Fields(header)
+			if len(fields) == 0 {
+				return fmt.Errorf("sqlite: %s: query without a name", file)
+			}
+			name = fields[0]
+			if _, ok := q[name]; ok {
+				return fmt.Errorf("sqlite: %s: query %s is defined twice", file, name)
+			}
+		}
+		if name != "" {
+			query.WriteString(line)
+			query.WriteByte('\n')
+		}
+	}
+	flush()
+	return scanner.Err()
+}
+
+// Dialect returns db running the SQLite version of each sqlc query it is
+// given. Pass the result to database.New, and wrap transactions the same
+// way.
+func Dialect(db database.DBTX, queries Queries) database.DBTX {
+	return dialect{db: db, queries: queries}
+}
+
+type dialect struct {
+	db      database.DBTX
+	queries Queries
+}
+
+func (d dialect) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.db.ExecContext(ctx, d.translate(query), convertArgs(args)...)
+}
+
+// PrepareContext is only translated: arguments to the statement bypass
+// convertArgs. sqlc does not prepare queries unless asked to.
+func (d dialect) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return d.db.PrepareContext(ctx, d.translate(query))
+}
+
+func (d dialect) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.QueryContext(ctx, d.translate(query), convertArgs(args)...)
+}
+
+func (d dialect) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.db.QueryRowContext(ctx, d.translate(query), convertArgs(args)...)
+}
+
+// translate returns the SQLite version of a sqlc query. ParseQueries has
+// checked there is one for every query; anything else is passed through.
+func (d dialect) translate(query string) string {
+	rest, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return query
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	if q, ok := d.queries[name]; ok {
+		return q
+	}
+	return query
+}
+
+// convertArgs formats times as TimeFormat. Drivers store time.Time in
+// formats of their own, which would not compare correctly with the
+// timestamps the queries write. Postgres arrays from pq.Array are left as
+// their text form, which the queries unpack.
+func convertArgs(args []interface{}) []interface{} {
+	if !slices.ContainsFunc(args, isTime) {
+		return args
+	}
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			converted[i] = v.UTC().Format(TimeFormat)
+		case sql.NullTime:
+			if v.Valid {
+				converted[i] = v.Time.UTC().Format(TimeFormat)
+			}
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
+
+func isTime(arg interface{}) bool {
+	switch arg.(type) {
+	case time.Time, sql.NullTime:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+//go:build sqlite
+
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"Chirpy/internal/storage"
+)
+
+func TestSQLite(t *testing.T) {
+	db, err := storage.Open(storage.SQLite, filepath.Join(t.TempDir(), "chirpy.db"), sqliteQueries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	checkBackend(t, db, sqliteSchema)
+}
@@ -0,0 +1,111 @@
+// Package storage opens the database Chirpy keeps its data in. Handlers and
+// workers only see database.Querier, so they run unchanged on Postgres, the
+// production database, or on SQLite, which needs no server and suits local
+// development and CI. The JSON file database is there for demos: it only
+// stores users, chirps and refresh tokens.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/lib/pq"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/storage/sqlite"
+	"Chirpy/internal/tracing"
+)
+
+// Drivers, as set by DB_DRIVER.
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+	JSON     = "json"
+)
+
+// ErrNoTransactions is returned by BeginTx on the JSON driver.
+var ErrNoTransactions = errors.New("storage: the json driver does not support transactions")
+
+// DB is an open database and the driver it was opened with. On the JSON
+// driver there is no SQL connection and the embedded *sql.DB is nil.
+type DB struct {
+	*sql.DB
+	Driver  string
+	queries sqlite.Queries
+	file    *database.DB
+}
+
+// Open opens the database at url: a Postgres connection string, or the
+// path of a SQLite or JSON file. sqliteQueries holds the SQLite versions of
+// the queries, see sqlite.ParseQueries; it is only read for SQLite.
+func Open(driver, url string, sqliteQueries fs.FS) (*DB, error) {
+	switch driver {
+	case Postgres:
+		db, err := sql.Open("postgres", url)
+		if err != nil {
+			return nil, err
+		}
+		return &DB{DB: db, Driver: driver}, nil
+	case SQLite:
+		queries, err := sqlite.ParseQueries(sqliteQueries)
+		if err != nil {
+			return nil, err
+		}
+		db, err := sqlite.Open(url)
+		if err != nil {
+			return nil, err
+		}
+		return &DB{DB: db, Driver: driver, queries: queries}, nil
+	case JSON:
+		file, err := database.NewDB(url)
+		if err != nil {
+			return nil, err
+		}
+		return &DB{Driver: driver, file: file}, nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+}
+
+// Queries returns the traced queries on dbtx, which is db itself or a
+// transaction on it. On SQLite the spans record the SQLite queries, which
+// keep the sqlc name line the spans are named after. The JSON driver ignores
+// dbtx and is not traced.
+func (db *DB) Queries(dbtx database.DBTX) database.Querier {
+	if db.file != nil {
+		return db.file
+	}
+	if db.Driver == SQLite {
+		return database.New(sqlite.Dialect(tracing.WrapDB(dbtx, "sqlite"), db.queries))
+	}
+	return database.New(tracing.WrapDB(dbtx, "postgresql"))
+}
+
+// BeginTx starts a transaction, or returns ErrNoTransactions on the JSON
+// driver.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if db.file != nil {
+		return nil, ErrNoTransactions
+	}
+	return db.DB.BeginTx(ctx, opts)
+}
+
+// Close closes the database. The JSON driver holds nothing open.
+func (db *DB) Close() error {
+	if db.file != nil {
+		return nil
+	}
+	return db.DB.Close()
+}
+
+// IsUniqueViolation reports whether err is a unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return errors.Is(err, database.ErrUniqueViolation) || sqlite.IsUniqueViolation(err)
+}
@@ -0,0 +1,134 @@
+package storage_test
+
+import (
+	"context"
+	"io/fs"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/migrations"
+	"Chirpy/internal/storage"
+	"Chirpy/internal/storage/sqlite"
+	"Chirpy/internal/storage/storagetest"
+)
+
+// The main package embeds these, so the tests read them from the tree.
+var (
+	postgresSchema  = os.DirFS("../../sql/schema")
+	postgresQueries = os.DirFS("../../sql/queries")
+	sqliteSchema    = os.DirFS("../../sql/sqlite/schema")
+	sqliteQueries   = os.DirFS("../../sql/sqlite/queries")
+)
+
+// checkBackend migrates db, which must be empty, and runs the storagetest
+// checks against it.
+func checkBackend(t *testing.T, db *storage.DB, schema fs.FS) {
+	t.Helper()
+	ctx := context.Background()
+	migrator, err := migrations.New(db.DB, db.Driver, schema)
+	if err != nil {
+		t.Fatalf("migrations.New: %v", err)
+	}
+	err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	err = storagetest.RunSQL(ctx, db.Queries(db))
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+var queryName = regexp.MustCompile(`(?m)^-- name: (\S+)`)
+
+func queryNames(t *testing.T, fsys fs.FS) []string {
+	t.Helper()
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range queryName.FindAllStringSubmatch(string(data), -1) {
+			names = append(names, m[1])
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// TestSQLiteQueries checks that every sqlc query has a SQLite version and
+// that the SQLite versions match the generated database.Querier. It needs
+// no SQLite driver, so it runs in every build.
+func TestSQLiteQueries(t *testing.T) {
+	_, err := sqlite.ParseQueries(sqliteQueries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, got := queryNames(t, postgresQueries), queryNames(t, sqliteQueries)
+	for _, name := range want {
+		if _, found := slices.BinarySearch(got, name); !found {
+			t.Errorf("query %s has no SQLite version in sql/sqlite/queries", name)
+		}
+	}
+	for _, name := range got {
+		if _, found := slices.BinarySearch(want, name); !found {
+			t.Errorf("SQLite query %s is not in sql/queries", name)
+		}
+	}
+}
+
+// TestPostgres runs the checks against the database at DB_URL. They run in
+// a schema of their own, dropped afterwards, so the database may hold data.
+func TestPostgres(t *testing.T) {
+	dbURL := os.Getenv("DB_URL")
+	if dbURL == "" {
+		t.Skip("DB_URL is not set")
+	}
+	admin, err := storage.Open(storage.Postgres, dbURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	schema := "storagetest_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	if err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	defer func() {
+		_, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+	}()
+
+	db, err := storage.Open(storage.Postgres, withSearchPath(dbURL, schema), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	checkBackend(t, db, postgresSchema)
+}
+
+// withSearchPath adds a search_path setting to a Postgres connection string
+// in either URL or key=value form.
+func withSearchPath(dbURL, schema string) string {
+	if u, err := url.Parse(dbURL); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dbURL + " search_path=" + schema
+}
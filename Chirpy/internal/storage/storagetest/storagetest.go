@@ -0,0 +1,360 @@
+// Package storagetest checks that a storage backend behaves like Postgres,
+// in the manner of testing/fstest: each check runs against a fresh, fully
+// migrated database and returns an error describing the first difference it
+// finds. Checks leave their data behind.
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/storage"
+)
+
+// ChirpQueries are the queries Chirps checks. Chirps need an author.
+type ChirpQueries interface {
+	database.UserQuerier
+	database.ChirpQuerier
+}
+
+// RefreshTokenQueries are the queries RefreshTokens checks.
+type RefreshTokenQueries interface {
+	database.UserQuerier
+	database.RefreshTokenQuerier
+}
+
+// Querier is what Run checks: database.Querier, or a backend such as
+// database.DB that implements only the queries the checks use.
+type Querier interface {
+	database.UserQuerier
+	database.ChirpQuerier
+	database.RefreshTokenQuerier
+}
+
+// Run runs every check against q, which must be an empty database.
+func Run(ctx context.Context, q Querier) error {
+	return errors.Join(
+		Users(ctx, q),
+		Chirps(ctx, q),
+		RefreshTokens(ctx, q),
+	)
+}
+
+// Users checks creating, finding and updating users.
+func Users(ctx context.Context, q database.UserQuerier) error {
+	start := time.Now()
+	user, err := q.CreateUser(ctx, database.CreateUserParams{
+		HashedPassword: "hash",
+		Email:          "storagetest-users@example.com",
+		Handle:         "storagetest_users",
+	})
+	if err != nil {
+		return fmt.Errorf("CreateUser: %w", err)
+	}
+	if user.ID == uuid.Nil {
+		return errors.New("CreateUser: no ID assigned")
+	}
+	if user.Email != "storagetest-users@example.com" || user.Handle != "storagetest_users" || user.HashedPassword != "hash" {
+		return fmt.Errorf("CreateUser: got %+v", user)
+	}
+	if user.IsChirpyRed || user.IsAdmin || user.DeletionRequestedAt.Valid || user.DisabledAt.Valid {
+		return fmt.Errorf("CreateUser: new user is not a plain account: %+v", user)
+	}
+	err = checkNow("CreateUser: created_at", user.CreatedAt, start)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateUser(ctx, database.CreateUserParams{
+		HashedPassword: "hash",
+		Email:          "storagetest-users@example.com",
+		Handle:         "storagetest_users2",
+	})
+	if !storage.IsUniqueViolation(err) {
+		return fmt.Errorf("CreateUser with a taken email: got %v, want a unique violation", err)
+	}
+	_, err = q.CreateUser(ctx, database.CreateUserParams{
+		HashedPassword: "hash",
+		Email:          "storagetest-users2@example.com",
+		Handle:         "storagetest_users",
+	})
+	if !storage.IsUniqueViolation(err) {
+		return fmt.Errorf("CreateUser with a taken handle: got %v, want a unique violation", err)
+	}
+
+	for _, get := range []struct {
+		name string
+		fn   func() (database.User, error)
+	}{
+		{"GetUserByID", func() (database.User, error) { return q.GetUserByID(ctx, user.ID) }},
+		{"GetUserByEmail", func() (database.User, error) { return q.GetUserByEmail(ctx, user.Email) }},
+		{"GetUserByHandle", func() (database.User, error) { return q.GetUserByHandle(ctx, user.Handle) }},
+	} {
+		got, err := get.fn()
+		if err != nil {
+			return fmt.Errorf("%s: %w", get.name, err)
+		}
+		if got.ID != user.ID || !got.CreatedAt.Equal(user.CreatedAt) {
+			return fmt.Errorf("%s: got %+v, want %+v", get.name, got, user)
+		}
+	}
+	_, err = q.GetUserByID(ctx, uuid.New())
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("GetUserByID of a missing user: got %v, want sql.ErrNoRows", err)
+	}
+	_, err = q.GetUserByEmail(ctx, "storagetest-missing@example.com")
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("GetUserByEmail of a missing user: got %v, want sql.ErrNoRows", err)
+	}
+
+	updated, err := q.UpdateUserByID(ctx, database.UpdateUserByIDParams{
+		Email:          "storagetest-users-new@example.com",
+		HashedPassword: "new-hash",
+		ID:             user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("UpdateUserByID: %w", err)
+	}
+	if updated.Email != "storagetest-users-new@example.com" || updated.HashedPassword != "new-hash" || updated.Handle != user.Handle {
+		return fmt.Errorf("UpdateUserByID: got %+v", updated)
+	}
+	_, err = q.GetUserByEmail(ctx, user.Email)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("GetUserByEmail of the old email: got %v, want sql.ErrNoRows", err)
+	}
+	_, err = q.UpdateUserByID(ctx, database.UpdateUserByIDParams{Email: "x@example.com", HashedPassword: "x", ID: uuid.New()})
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("UpdateUserByID of a missing user: got %v, want sql.ErrNoRows", err)
+	}
+
+	red, err := q.UpdateUserChirpyRedByID(ctx, database.UpdateUserChirpyRedByIDParams{IsChirpyRed: true, ID: user.ID})
+	if err != nil {
+		return fmt.Errorf("UpdateUserChirpyRedByID: %w", err)
+	}
+	if !red.IsChirpyRed || red.Email != updated.Email {
+		return fmt.Errorf("UpdateUserChirpyRedByID: got %+v", red)
+	}
+	return nil
+}
+
+// Chirps checks creating, listing and deleting chirps.
+func Chirps(ctx context.Context, q ChirpQueries) error {
+	start := time.Now()
+	author, err := q.CreateUser(ctx, database.CreateUserParams{
+		HashedPassword: "hash",
+		Email:          "storagetest-chirps@example.com",
+		Handle:         "storagetest_chirps",
+	})
+	if err != nil {
+		return fmt.Errorf("CreateUser: %w", err)
+	}
+	first, err := q.CreateChirp(ctx, database.CreateChirpParams{Body: "first", UserID: author.ID})
+	if err != nil {
+		return fmt.Errorf("CreateChirp: %w", err)
+	}
+	if first.ID == uuid.Nil || first.Body != "first" || first.UserID != author.ID || first.Status != "published" {
+		return fmt.Errorf("CreateChirp: got %+v", first)
+	}
+	if first.ReplyToID.Valid || first.QuoteOfID.Valid || first.RechirpOfID.Valid || first.DeletedAt.Valid {
+		return fmt.Errorf("CreateChirp: plain chirp has references: %+v", first)
+	}
+	err = checkNow("CreateChirp: created_at", first.CreatedAt, start)
+	if err != nil {
+		return err
+	}
+	// Timestamps order chirps, so the second must come strictly later.
+	time.Sleep(10 * time.Millisecond)
+	reply, err := q.CreateChirp(ctx, database.CreateChirpParams{
+		Body:      "reply",
+		UserID:    author.ID,
+		ReplyToID: uuid.NullUUID{UUID: first.ID, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("CreateChirp reply: %w", err)
+	}
+	if reply.ReplyToID != (uuid.NullUUID{UUID: first.ID, Valid: true}) {
+		return fmt.Errorf("CreateChirp reply: got reply_to_id %v, want %v", reply.ReplyToID, first.ID)
+	}
+	_, err = q.CreateChirp(ctx, database.CreateChirpParams{Body: "orphan", UserID: uuid.New()})
+	if err == nil {
+		return errors.New("CreateChirp by a missing user: succeeded, want a foreign key error")
+	}
+
+	got, err := q.ListChirp(ctx, first.ID)
+	if err != nil {
+		return fmt.Errorf("ListChirp: %w", err)
+	}
+	if got.ID != first.ID || got.Body != first.Body || !got.CreatedAt.Equal(first.CreatedAt) {
+		return fmt.Errorf("ListChirp: got %+v, want %+v", got, first)
+	}
+	chirps, err := q.ListChirps(ctx)
+	err = checkChirpIDs("ListChirps", chirps, err, first.ID, reply.ID)
+	if err != nil {
+		return err
+	}
+	chirps, err = q.ListChirpByAuthorID(ctx, author.ID)
+	err = checkChirpIDs("ListChirpByAuthorID", chirps, err, first.ID, reply.ID)
+	if err != nil {
+		return err
+	}
+	chirps, err = q.ListChirpByAuthorID(ctx, uuid.New())
+	err = checkChirpIDs("ListChirpByAuthorID of a missing user", chirps, err)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := q.DeleteChirpById(ctx, first.ID)
+	if err != nil {
+		return fmt.Errorf("DeleteChirpById: %w", err)
+	}
+	if deleted.ID != first.ID || !deleted.DeletedAt.Valid {
+		return fmt.Errorf("DeleteChirpById: got %+v", deleted)
+	}
+	_, err = q.DeleteChirpById(ctx, first.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("DeleteChirpById twice: got %v, want sql.ErrNoRows", err)
+	}
+	_, err = q.ListChirp(ctx, first.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("ListChirp of a deleted chirp: got %v, want sql.ErrNoRows", err)
+	}
+	chirps, err = q.ListChirps(ctx)
+	return checkChirpIDs("ListChirps after a delete", chirps, err, reply.ID)
+}
+
+// checkChirpIDs checks that name, which returned chirps and err, listed the
+// chirps ids in order.
+func checkChirpIDs(name string, chirps []database.Chirp, err error, ids ...uuid.UUID) error {
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	got := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		got[i] = chirp.ID
+	}
+	if !slices.Equal(got, ids) {
+		return fmt.Errorf("%s: got chirps %v, want %v", name, got, ids)
+	}
+	return nil
+}
+
+// RefreshTokens checks issuing, finding and revoking refresh tokens.
+func RefreshTokens(ctx context.Context, q RefreshTokenQueries) error {
+	start := time.Now()
+	user, err := q.CreateUser(ctx, database.CreateUserParams{
+		HashedPassword: "hash",
+		Email:          "storagetest-tokens@example.com",
+		Handle:         "storagetest_tokens",
+	})
+	if err != nil {
+		return fmt.Errorf("CreateUser: %w", err)
+	}
+	token, err := q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:            "storagetest-token-1",
+		UserID:           user.ID,
+		ExpiresInSeconds: time.Hour.Seconds(),
+	})
+	if err != nil {
+		return fmt.Errorf("CreateRefreshToken: %w", err)
+	}
+	if token.Token != "storagetest-token-1" || token.UserID != user.ID || token.RevokedAt.Valid {
+		return fmt.Errorf("CreateRefreshToken: got %+v", token)
+	}
+	err = checkNow("CreateRefreshToken: created_at", token.CreatedAt, start)
+	if err != nil {
+		return err
+	}
+	err = checkNow("CreateRefreshToken: expires_at", token.ExpiresAt.Add(-time.Hour), start)
+	if err != nil {
+		return err
+	}
+	_, err = q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:            "storagetest-token-1",
+		UserID:           user.ID,
+		ExpiresInSeconds: 60,
+	})
+	if !storage.IsUniqueViolation(err) {
+		return fmt.Errorf("CreateRefreshToken with a taken token: got %v, want a unique violation", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	second, err := q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:            "storagetest-token-2",
+		UserID:           user.ID,
+		ExpiresInSeconds: 60,
+	})
+	if err != nil {
+		return fmt.Errorf("CreateRefreshToken: %w", err)
+	}
+
+	got, err := q.GetRefreshToken(ctx, token.Token)
+	if err != nil {
+		return fmt.Errorf("GetRefreshToken: %w", err)
+	}
+	if got.UserID != user.ID || !got.ExpiresAt.Equal(token.ExpiresAt) {
+		return fmt.Errorf("GetRefreshToken: got %+v, want %+v", got, token)
+	}
+	userID, err := q.GetUserByRefreshToken(ctx, token.Token)
+	if err != nil {
+		return fmt.Errorf("GetUserByRefreshToken: %w", err)
+	}
+	if userID != user.ID {
+		return fmt.Errorf("GetUserByRefreshToken: got %v, want %v", userID, user.ID)
+	}
+	_, err = q.GetRefreshToken(ctx, "storagetest-missing")
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("GetRefreshToken of a missing token: got %v, want sql.ErrNoRows", err)
+	}
+
+	err = q.UpdateRefreshToken(ctx, token.Token)
+	if err != nil {
+		return fmt.Errorf("UpdateRefreshToken: %w", err)
+	}
+	got, err = q.GetRefreshToken(ctx, token.Token)
+	if err != nil {
+		return fmt.Errorf("GetRefreshToken: %w", err)
+	}
+	if !got.RevokedAt.Valid {
+		return errors.New("UpdateRefreshToken: token not revoked")
+	}
+	got, err = q.GetRefreshToken(ctx, second.Token)
+	if err != nil {
+		return fmt.Errorf("GetRefreshToken: %w", err)
+	}
+	if got.RevokedAt.Valid {
+		return errors.New("UpdateRefreshToken: revoked another token of the user")
+	}
+
+	err = q.RevokeRefreshTokensByUserID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("RevokeRefreshTokensByUserID: %w", err)
+	}
+	tokens, err := q.ListRefreshTokensByUserID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("ListRefreshTokensByUserID: %w", err)
+	}
+	if len(tokens) != 2 || tokens[0].Token != token.Token || tokens[1].Token != second.Token {
+		return fmt.Errorf("ListRefreshTokensByUserID: got %+v", tokens)
+	}
+	for _, t := range tokens {
+		if !t.RevokedAt.Valid {
+			return fmt.Errorf("RevokeRefreshTokensByUserID: %s not revoked", t.Token)
+		}
+	}
+	return nil
+}
+
+// checkNow checks that t, a timestamp the database set, is between start
+// and now, allowing a minute of clock skew either way.
+func checkNow(name string, t, start time.Time) error {
+	if t.Before(start.Add(-time.Minute)) || t.After(time.Now().Add(time.Minute)) {
+		return fmt.Errorf("%s: %v is not the current time", name, t)
+	}
+	return nil
+}
@@ -0,0 +1,591 @@
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+)
+
+// The background workers share their queues between instances, so the checks
+// below cover what that relies on: a claim hides rows from the next claim
+// until its lease runs out, and every cutoff computed from a number of
+// seconds falls on the right side of rows just inside or outside it.
+//
+// A window of an hour is never reached during a check, and one of zero
+// seconds has always passed once tick has.
+
+// tick is long enough for the database clock to move past a timestamp it
+// just set, at the millisecond precision SQLite stores.
+const tick = 10 * time.Millisecond
+
+const hour = float64(time.Hour / time.Second)
+
+// RunSQL runs Run and the checks of the queries the background workers use,
+// which only the SQL backends implement, against q, an empty database.
+func RunSQL(ctx context.Context, q database.Querier) error {
+	return errors.Join(
+		Run(ctx, q),
+		WebhookDeliveries(ctx, q),
+		ScheduledChirps(ctx, q),
+		ChirpEvents(ctx, q),
+		RateLimits(ctx, q),
+		DataExports(ctx, q),
+		Retention(ctx, q),
+	)
+}
+
+// newUser creates a user named after the check that needs one.
+func newUser(ctx context.Context, q database.UserQuerier, name string) (database.User, error) {
+	user, err := q.CreateUser(ctx, database.CreateUserParams{
+		HashedPassword: "hash",
+		Email:          "storagetest-" + name + "@example.com",
+		Handle:         "storagetest_" + name,
+	})
+	if err != nil {
+		return database.User{}, fmt.Errorf("CreateUser: %w", err)
+	}
+	return user, nil
+}
+
+// WebhookDeliveries checks the delivery queue: claiming due deliveries,
+// retrying after a delay and recording the outcome.
+func WebhookDeliveries(ctx context.Context, q database.Querier) error {
+	user, err := newUser(ctx, q, "webhooks")
+	if err != nil {
+		return err
+	}
+	sub, err := q.CreateWebhookSubscription(ctx, database.CreateWebhookSubscriptionParams{
+		UserID: user.ID,
+		Url:    "https://example.com/hook",
+		Secret: "secret",
+		Events: []string{"chirp.created", "user.upgraded"},
+	})
+	if err != nil {
+		return fmt.Errorf("CreateWebhookSubscription: %w", err)
+	}
+	if !slices.Equal(sub.Events, []string{"chirp.created", "user.upgraded"}) || !sub.Active {
+		return fmt.Errorf("CreateWebhookSubscription: got %+v", sub)
+	}
+	subs, err := q.ListWebhookSubscriptionsForEvent(ctx, "user.upgraded")
+	if err != nil {
+		return fmt.Errorf("ListWebhookSubscriptionsForEvent: %w", err)
+	}
+	if len(subs) != 1 || subs[0].ID != sub.ID {
+		return fmt.Errorf("ListWebhookSubscriptionsForEvent: got %+v, want the subscription", subs)
+	}
+	subs, err = q.ListWebhookSubscriptionsForEvent(ctx, "chirp.deleted")
+	if err != nil || len(subs) != 0 {
+		return fmt.Errorf("ListWebhookSubscriptionsForEvent of an unsubscribed event: got %+v, %v", subs, err)
+	}
+
+	var deliveries []database.WebhookDelivery
+	for i := range 2 {
+		d, err := q.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			SubscriptionID: sub.ID,
+			Event:          "chirp.created",
+			Payload:        json.RawMessage(fmt.Sprintf(`{"n":%d}`, i)),
+		})
+		if err != nil {
+			return fmt.Errorf("CreateWebhookDelivery: %w", err)
+		}
+		if d.Status != "pending" || d.Attempts != 0 {
+			return fmt.Errorf("CreateWebhookDelivery: got %+v", d)
+		}
+		deliveries = append(deliveries, d)
+		time.Sleep(tick)
+	}
+	first, second := deliveries[0], deliveries[1]
+
+	claimed, err := q.ClaimDueWebhookDeliveries(ctx, 1)
+	err = checkDeliveryIDs("ClaimDueWebhookDeliveries with a limit of 1", claimed, err, first.ID)
+	if err != nil {
+		return err
+	}
+	claimed, err = q.ClaimDueWebhookDeliveries(ctx, 10)
+	err = checkDeliveryIDs("ClaimDueWebhookDeliveries", claimed, err, second.ID)
+	if err != nil {
+		return err
+	}
+	claimed, err = q.ClaimDueWebhookDeliveries(ctx, 10)
+	err = checkDeliveryIDs("ClaimDueWebhookDeliveries of leased deliveries", claimed, err)
+	if err != nil {
+		return err
+	}
+
+	err = q.MarkWebhookDeliveryFailed(ctx, database.MarkWebhookDeliveryFailedParams{
+		Status:         "pending",
+		LastStatusCode: sql.NullInt32{Int32: 503, Valid: true},
+		LastError:      sql.NullString{String: "unavailable", Valid: true},
+		RetryInSeconds: 0,
+		ID:             first.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("MarkWebhookDeliveryFailed: %w", err)
+	}
+	time.Sleep(tick)
+	claimed, err = q.ClaimDueWebhookDeliveries(ctx, 10)
+	err = checkDeliveryIDs("ClaimDueWebhookDeliveries after a retry came due", claimed, err, first.ID)
+	if err != nil {
+		return err
+	}
+	retried := claimed[0]
+	if retried.Attempts != 1 || retried.LastStatusCode.Int32 != 503 || retried.LastError.String != "unavailable" {
+		return fmt.Errorf("MarkWebhookDeliveryFailed: got %+v", retried)
+	}
+	err = q.MarkWebhookDeliveryFailed(ctx, database.MarkWebhookDeliveryFailedParams{
+		Status:         "pending",
+		LastError:      sql.NullString{String: "timeout", Valid: true},
+		RetryInSeconds: hour,
+		ID:             first.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("MarkWebhookDeliveryFailed: %w", err)
+	}
+	err = q.MarkWebhookDeliverySucceeded(ctx, database.MarkWebhookDeliverySucceededParams{
+		ID:             second.ID,
+		LastStatusCode: sql.NullInt32{Int32: 200, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("MarkWebhookDeliverySucceeded: %w", err)
+	}
+	time.Sleep(tick)
+	claimed, err = q.ClaimDueWebhookDeliveries(ctx, 10)
+	err = checkDeliveryIDs("ClaimDueWebhookDeliveries before a retry is due", claimed, err)
+	if err != nil {
+		return err
+	}
+
+	listed, err := q.ListWebhookDeliveriesBySubscriptionID(ctx, database.ListWebhookDeliveriesBySubscriptionIDParams{SubscriptionID: sub.ID, Limit: 10})
+	err = checkDeliveryIDs("ListWebhookDeliveriesBySubscriptionID", listed, err, second.ID, first.ID)
+	if err != nil {
+		return err
+	}
+	delivered, pending := listed[0], listed[1]
+	if delivered.Status != "delivered" || delivered.Attempts != 1 || !delivered.DeliveredAt.Valid || delivered.LastError.Valid {
+		return fmt.Errorf("MarkWebhookDeliverySucceeded: got %+v", delivered)
+	}
+	if pending.Status != "pending" || pending.Attempts != 2 || pending.LastStatusCode.Valid {
+		return fmt.Errorf("MarkWebhookDeliveryFailed: got %+v", pending)
+	}
+	err = checkNow("MarkWebhookDeliveryFailed: next_attempt_at", pending.NextAttemptAt.Add(-time.Hour), time.Now().Add(-time.Minute))
+	if err != nil {
+		return err
+	}
+
+	err = q.MarkWebhookDeliveryFailed(ctx, database.MarkWebhookDeliveryFailedParams{
+		Status: "failed",
+		ID:     first.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("MarkWebhookDeliveryFailed: %w", err)
+	}
+	time.Sleep(tick)
+	claimed, err = q.ClaimDueWebhookDeliveries(ctx, 10)
+	return checkDeliveryIDs("ClaimDueWebhookDeliveries of a dead-lettered delivery", claimed, err)
+}
+
+func checkDeliveryIDs(name string, deliveries []database.WebhookDelivery, err error, ids ...uuid.UUID) error {
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	got := make([]uuid.UUID, len(deliveries))
+	for i, d := range deliveries {
+		got[i] = d.ID
+	}
+	if !slices.Equal(got, ids) {
+		return fmt.Errorf("%s: got deliveries %v, want %v", name, got, ids)
+	}
+	return nil
+}
+
+// ScheduledChirps checks that publishing claims due chirps exactly once and
+// leaves the rest scheduled.
+func ScheduledChirps(ctx context.Context, q database.Querier) error {
+	author, err := newUser(ctx, q, "scheduled")
+	if err != nil {
+		return err
+	}
+	schedule := func(body string, at time.Time) (database.Chirp, error) {
+		chirp, err := q.CreateScheduledChirp(ctx, database.CreateScheduledChirpParams{
+			Body:      body,
+			UserID:    author.ID,
+			PublishAt: sql.NullTime{Time: at, Valid: true},
+		})
+		if err != nil {
+			return database.Chirp{}, fmt.Errorf("CreateScheduledChirp: %w", err)
+		}
+		if chirp.Status != "scheduled" || !chirp.PublishAt.Time.Equal(at) {
+			return database.Chirp{}, fmt.Errorf("CreateScheduledChirp: got %+v, want publish_at %v", chirp, at)
+		}
+		return chirp, nil
+	}
+	// Timestamps are compared at the precision of the coarsest backend.
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	due, err := schedule("due", now.Add(-time.Minute))
+	if err != nil {
+		return err
+	}
+	later, err := schedule("later", now.Add(time.Hour))
+	if err != nil {
+		return err
+	}
+
+	published, err := q.PublishDueChirps(ctx, 10)
+	err = checkChirpIDs("PublishDueChirps", published, err, due.ID)
+	if err != nil {
+		return err
+	}
+	if published[0].Status != "published" {
+		return fmt.Errorf("PublishDueChirps: got status %q", published[0].Status)
+	}
+	err = checkNow("PublishDueChirps: created_at", published[0].CreatedAt, now)
+	if err != nil {
+		return err
+	}
+	published, err = q.PublishDueChirps(ctx, 10)
+	err = checkChirpIDs("PublishDueChirps again", published, err)
+	if err != nil {
+		return err
+	}
+	scheduled, err := q.ListScheduledChirps(ctx, database.ListScheduledChirpsParams{UserID: author.ID, Limit: 10})
+	return checkChirpIDs("ListScheduledChirps", scheduled, err, later.ID)
+}
+
+// ChirpEvents checks the stream's event log: listing events after a position
+// and pruning old ones.
+func ChirpEvents(ctx context.Context, q database.Querier) error {
+	alice, err := newUser(ctx, q, "events_a")
+	if err != nil {
+		return err
+	}
+	bob, err := newUser(ctx, q, "events_b")
+	if err != nil {
+		return err
+	}
+	var events []database.ChirpEvent
+	for _, user := range []database.User{alice, bob, alice} {
+		ev, err := q.CreateChirpEvent(ctx, database.CreateChirpEventParams{
+			Type:    "chirp.created",
+			ChirpID: uuid.New(),
+			UserID:  user.ID,
+			Payload: json.RawMessage(`{}`),
+		})
+		if err != nil {
+			return fmt.Errorf("CreateChirpEvent: %w", err)
+		}
+		if len(events) > 0 && ev.ID <= events[len(events)-1].ID {
+			return fmt.Errorf("CreateChirpEvent: ID %d does not follow %d", ev.ID, events[len(events)-1].ID)
+		}
+		events = append(events, ev)
+	}
+	start := events[0].ID - 1
+
+	listed, err := q.ListChirpEventsSince(ctx, database.ListChirpEventsSinceParams{ID: start, Limit: 10})
+	err = checkEventIDs("ListChirpEventsSince", listed, err, events...)
+	if err != nil {
+		return err
+	}
+	listed, err = q.ListChirpEventsSince(ctx, database.ListChirpEventsSinceParams{ID: events[0].ID, Limit: 1})
+	err = checkEventIDs("ListChirpEventsSince with a limit of 1", listed, err, events[1])
+	if err != nil {
+		return err
+	}
+	listed, err = q.ListChirpEventsSinceByAuthorID(ctx, database.ListChirpEventsSinceByAuthorIDParams{ID: start, UserID: alice.ID, Limit: 10})
+	err = checkEventIDs("ListChirpEventsSinceByAuthorID", listed, err, events[0], events[2])
+	if err != nil {
+		return err
+	}
+
+	err = q.DeleteChirpEventsOlderThan(ctx, hour)
+	if err != nil {
+		return fmt.Errorf("DeleteChirpEventsOlderThan: %w", err)
+	}
+	listed, err = q.ListChirpEventsSince(ctx, database.ListChirpEventsSinceParams{ID: start, Limit: 10})
+	err = checkEventIDs("DeleteChirpEventsOlderThan an hour", listed, err, events...)
+	if err != nil {
+		return err
+	}
+	time.Sleep(tick)
+	err = q.DeleteChirpEventsOlderThan(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("DeleteChirpEventsOlderThan: %w", err)
+	}
+	listed, err = q.ListChirpEventsSince(ctx, database.ListChirpEventsSinceParams{ID: start, Limit: 10})
+	return checkEventIDs("DeleteChirpEventsOlderThan now", listed, err)
+}
+
+func checkEventIDs(name string, events []database.ChirpEvent, err error, want ...database.ChirpEvent) error {
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	got := make([]int64, len(events))
+	for i, ev := range events {
+		got[i] = ev.ID
+	}
+	ids := make([]int64, len(want))
+	for i, ev := range want {
+		ids[i] = ev.ID
+	}
+	if !slices.Equal(got, ids) {
+		return fmt.Errorf("%s: got events %v, want %v", name, got, ids)
+	}
+	return nil
+}
+
+// RateLimits checks the shared token buckets: spending, refilling and
+// forgetting idle ones.
+func RateLimits(ctx context.Context, q database.Querier) error {
+	take := func(key string, burst, rate float64) (database.TakeRateLimitTokenRow, error) {
+		row, err := q.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{Key: key, Burst: burst, Rate: rate})
+		if err != nil {
+			return row, fmt.Errorf("TakeRateLimitToken: %w", err)
+		}
+		return row, nil
+	}
+	// A rate this low adds nothing measurable during the check.
+	const still = 1e-9
+	for i, want := range []struct {
+		allowed bool
+		tokens  float64
+	}{{true, 1}, {true, 0}, {false, 0}} {
+		row, err := take("storagetest:spend", 2, still)
+		if err != nil {
+			return err
+		}
+		if row.Allowed != want.allowed || math.Abs(row.Tokens-want.tokens) > 0.001 {
+			return fmt.Errorf("TakeRateLimitToken %d of a bucket of 2: got %+v, want allowed %v with %v tokens left", i+1, row, want.allowed, want.tokens)
+		}
+	}
+
+	row, err := take("storagetest:refill", 1, 1000)
+	if err != nil {
+		return err
+	}
+	if !row.Allowed {
+		return fmt.Errorf("TakeRateLimitToken of a new bucket: got %+v", row)
+	}
+	time.Sleep(tick)
+	row, err = take("storagetest:refill", 1, 1000)
+	if err != nil {
+		return err
+	}
+	// The refill is capped at the burst, so the bucket is empty again.
+	if !row.Allowed || math.Abs(row.Tokens) > 0.001 {
+		return fmt.Errorf("TakeRateLimitToken after a refill: got %+v, want allowed with 0 tokens left", row)
+	}
+
+	n, err := q.DeleteIdleRateLimitBuckets(ctx, hour)
+	if err != nil {
+		return fmt.Errorf("DeleteIdleRateLimitBuckets: %w", err)
+	}
+	if n != 0 {
+		return fmt.Errorf("DeleteIdleRateLimitBuckets idle for an hour: deleted %d, want 0", n)
+	}
+	time.Sleep(tick)
+	n, err = q.DeleteIdleRateLimitBuckets(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("DeleteIdleRateLimitBuckets: %w", err)
+	}
+	if n != 2 {
+		return fmt.Errorf("DeleteIdleRateLimitBuckets idle now: deleted %d, want 2", n)
+	}
+	row, err = take("storagetest:spend", 2, still)
+	if err != nil {
+		return err
+	}
+	if !row.Allowed || math.Abs(row.Tokens-1) > 0.001 {
+		return fmt.Errorf("TakeRateLimitToken of a deleted bucket: got %+v, want a full bucket", row)
+	}
+	return nil
+}
+
+// DataExports checks the export queue: claiming pending exports, reclaiming
+// stale ones and expiring finished ones.
+func DataExports(ctx context.Context, q database.Querier) error {
+	user, err := newUser(ctx, q, "exports")
+	if err != nil {
+		return err
+	}
+	export, err := q.CreateDataExport(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("CreateDataExport: %w", err)
+	}
+	if export.Status != "pending" {
+		return fmt.Errorf("CreateDataExport: got %+v", export)
+	}
+	claim := func(name string, staleAfter float64, want ...uuid.UUID) error {
+		claimed, err := q.ClaimPendingDataExports(ctx, database.ClaimPendingDataExportsParams{StaleAfterSeconds: staleAfter, MaxRows: 10})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		got := make([]uuid.UUID, len(claimed))
+		for i, e := range claimed {
+			got[i] = e.ID
+			if e.Status != "processing" {
+				return fmt.Errorf("%s: got status %q", name, e.Status)
+			}
+		}
+		if !slices.Equal(got, want) {
+			return fmt.Errorf("%s: got exports %v, want %v", name, got, want)
+		}
+		return nil
+	}
+	err = claim("ClaimPendingDataExports", hour, export.ID)
+	if err != nil {
+		return err
+	}
+	err = claim("ClaimPendingDataExports of a fresh claim", hour)
+	if err != nil {
+		return err
+	}
+	time.Sleep(tick)
+	err = claim("ClaimPendingDataExports of a stale claim", 0, export.ID)
+	if err != nil {
+		return err
+	}
+
+	err = q.CompleteDataExport(ctx, database.CompleteDataExportParams{Archive: []byte("zip"), ExpiresInSeconds: hour, ID: export.ID})
+	if err != nil {
+		return fmt.Errorf("CompleteDataExport: %w", err)
+	}
+	ready, err := q.GetDataExport(ctx, database.GetDataExportParams{ID: export.ID, UserID: user.ID})
+	if err != nil {
+		return fmt.Errorf("GetDataExport: %w", err)
+	}
+	if ready.Status != "ready" || string(ready.Archive) != "zip" || !ready.CompletedAt.Valid || !ready.ExpiresAt.Valid {
+		return fmt.Errorf("CompleteDataExport: got %+v", ready)
+	}
+	err = checkNow("CompleteDataExport: expires_at", ready.ExpiresAt.Time.Add(-time.Hour), time.Now().Add(-time.Minute))
+	if err != nil {
+		return err
+	}
+	time.Sleep(tick)
+	err = claim("ClaimPendingDataExports of a finished export", 0)
+	if err != nil {
+		return err
+	}
+
+	failed, err := q.CreateDataExport(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("CreateDataExport: %w", err)
+	}
+	err = q.FailDataExport(ctx, database.FailDataExportParams{Error: sql.NullString{String: "boom", Valid: true}, ExpiresInSeconds: 0, ID: failed.ID})
+	if err != nil {
+		return fmt.Errorf("FailDataExport: %w", err)
+	}
+	time.Sleep(tick)
+	n, err := q.DeleteExpiredDataExports(ctx)
+	if err != nil {
+		return fmt.Errorf("DeleteExpiredDataExports: %w", err)
+	}
+	if n != 1 {
+		return fmt.Errorf("DeleteExpiredDataExports: deleted %d, want 1", n)
+	}
+	_, err = q.GetDataExport(ctx, database.GetDataExportParams{ID: failed.ID, UserID: user.ID})
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("GetDataExport of an expired export: got %v, want sql.ErrNoRows", err)
+	}
+	_, err = q.GetDataExport(ctx, database.GetDataExportParams{ID: export.ID, UserID: user.ID})
+	if err != nil {
+		return fmt.Errorf("GetDataExport of an unexpired export: %w", err)
+	}
+	return nil
+}
+
+// Retention checks the windows for restoring and purging deleted chirps and
+// for deleting accounts.
+func Retention(ctx context.Context, q database.Querier) error {
+	author, err := newUser(ctx, q, "retention")
+	if err != nil {
+		return err
+	}
+	chirp, err := q.CreateChirp(ctx, database.CreateChirpParams{Body: "gone", UserID: author.ID})
+	if err != nil {
+		return fmt.Errorf("CreateChirp: %w", err)
+	}
+	_, err = q.DeleteChirpById(ctx, chirp.ID)
+	if err != nil {
+		return fmt.Errorf("DeleteChirpById: %w", err)
+	}
+	restored, err := q.RestoreChirp(ctx, database.RestoreChirpParams{ID: chirp.ID, WindowSeconds: hour})
+	if err != nil {
+		return fmt.Errorf("RestoreChirp within the window: %w", err)
+	}
+	if restored.DeletedAt.Valid {
+		return fmt.Errorf("RestoreChirp: got %+v", restored)
+	}
+	_, err = q.DeleteChirpById(ctx, chirp.ID)
+	if err != nil {
+		return fmt.Errorf("DeleteChirpById: %w", err)
+	}
+	time.Sleep(tick)
+	_, err = q.RestoreChirp(ctx, database.RestoreChirpParams{ID: chirp.ID, WindowSeconds: 0})
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("RestoreChirp after the window: got %v, want sql.ErrNoRows", err)
+	}
+
+	ids, err := q.ListPurgeableChirpIDs(ctx, database.ListPurgeableChirpIDsParams{RetentionSeconds: hour, MaxRows: 10})
+	if err != nil {
+		return fmt.Errorf("ListPurgeableChirpIDs: %w", err)
+	}
+	if slices.Contains(ids, chirp.ID) {
+		return errors.New("ListPurgeableChirpIDs: listed a chirp still within retention")
+	}
+	ids, err = q.ListPurgeableChirpIDs(ctx, database.ListPurgeableChirpIDsParams{RetentionSeconds: 0, MaxRows: 10})
+	if err != nil {
+		return fmt.Errorf("ListPurgeableChirpIDs: %w", err)
+	}
+	if !slices.Contains(ids, chirp.ID) {
+		return fmt.Errorf("ListPurgeableChirpIDs past retention: got %v, want %v listed", ids, chirp.ID)
+	}
+	n, err := q.PurgeChirps(ctx, []uuid.UUID{chirp.ID})
+	if err != nil {
+		return fmt.Errorf("PurgeChirps: %w", err)
+	}
+	if n != 1 {
+		return fmt.Errorf("PurgeChirps: purged %d, want 1", n)
+	}
+
+	leaving, err := newUser(ctx, q, "leaving")
+	if err != nil {
+		return err
+	}
+	_, err = q.RequestUserDeletion(ctx, leaving.ID)
+	if err != nil {
+		return fmt.Errorf("RequestUserDeletion: %w", err)
+	}
+	due, err := q.ListUsersDueForDeletion(ctx, database.ListUsersDueForDeletionParams{GraceSeconds: hour, MaxRows: 10})
+	if err != nil || len(due) != 0 {
+		return fmt.Errorf("ListUsersDueForDeletion within the grace period: got %+v, %v", due, err)
+	}
+	n, err = q.DeleteUser(ctx, database.DeleteUserParams{ID: leaving.ID, GraceSeconds: hour})
+	if err != nil || n != 0 {
+		return fmt.Errorf("DeleteUser within the grace period: deleted %d, %v", n, err)
+	}
+	time.Sleep(tick)
+	due, err = q.ListUsersDueForDeletion(ctx, database.ListUsersDueForDeletionParams{GraceSeconds: 0, MaxRows: 10})
+	if err != nil {
+		return fmt.Errorf("ListUsersDueForDeletion: %w", err)
+	}
+	if len(due) != 1 || due[0].ID != leaving.ID {
+		return fmt.Errorf("ListUsersDueForDeletion after the grace period: got %+v", due)
+	}
+	n, err = q.DeleteUser(ctx, database.DeleteUserParams{ID: leaving.ID, GraceSeconds: 0})
+	if err != nil || n != 1 {
+		return fmt.Errorf("DeleteUser after the grace period: deleted %d, %v", n, err)
+	}
+	_, err = q.GetUserByID(ctx, leaving.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("GetUserByID of a deleted user: got %v, want sql.ErrNoRows", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"Chirpy/internal/database"
+)
+
+const notifyChannel = "chirp_events"
+
+// Broker records chirp events so clients can resume after a disconnect and
+// fans them out to the local Hub. When Listen is running, events are sent
+// through Postgres NOTIFY so every instance sharing the database sees them.
+type Broker struct {
+	db        database.Querier
+	hub       *Hub
+	listening atomic.Bool
+	Retention time.Duration
+}
+
+func NewBroker(db database.Querier, hub *Hub) *Broker {
+	return &Broker{
+		db:        db,
+		hub:       hub,
+		Retention: 24 * time.Hour,
+	}
+}
+
+func (b *Broker) Hub() *Hub {
+	return b.hub
+}
+
+// Publish stores an event for chirp and delivers it to subscribers.
+func (b *Broker) Publish(ctx context.Context, eventType string, chirp database.Chirp) error {
+	res := database.MapSqlChirpToJsonChirp(chirp)
+	payload, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	row, err := b.db.CreateChirpEvent(ctx, database.CreateChirpEventParams{
+		Type:    eventType,
+		ChirpID: chirp.ID,
+		UserID:  chirp.UserID,
+		Payload: payload,
+	})
+	if err != nil {
+		return err
+	}
+	ev := Event{
+		ID:        row.ID,
+		Type:      row.Type,
+		CreatedAt: row.CreatedAt,
+		Chirp:     res,
+	}
+
+	if b.listening.Load() {
+		dat, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		err = b.db.NotifyChirpEvent(ctx, string(dat))
+		if err == nil {
+			return nil
+		}
+		slog.WarnContext(ctx, "stream: notify failed, delivering locally", "err", err)
+	}
+	b.hub.Broadcast(ev)
+	return nil
+}
+
+// Since returns up to limit stored events after lastID, optionally limited
+// to one author.
+func (b *Broker) Since(ctx context.Context, lastID int64, authorID uuid.UUID, limit int32) ([]Event, error) {
+	var rows []database.ChirpEvent
+	var err error
+	if authorID == uuid.Nil {
+		rows, err = b.db.ListChirpEventsSince(ctx, database.ListChirpEventsSinceParams{ID: lastID, Limit: limit})
+	} else {
+		rows, err = b.db.ListChirpEventsSinceByAuthorID(ctx, database.ListChirpEventsSinceByAuthorIDParams{ID: lastID, UserID: authorID, Limit: limit})
+	}
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0, len(rows))
+	for _, row := range rows {
+		ev := Event{
+			ID:        row.ID,
+			Type:      row.Type,
+			CreatedAt: row.CreatedAt,
+		}
+		err := json.Unmarshal(row.Payload, &ev.Chirp)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// Listen subscribes to Postgres notifications on dbURL and forwards them to
+// the Hub until ctx is cancelled.
+func (b *Broker) Listen(ctx context.Context, dbURL string) error {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("stream: listener", "err", err)
+		}
+	})
+	// Listen blocks until the listener connects; closing it on shutdown
+	// unblocks it.
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	err := listener.Listen(notifyChannel)
+	stop()
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	b.listening.Store(true)
+
+	go func() {
+		defer listener.Close()
+		defer b.listening.Store(false)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.Notify:
+				// A nil notification means the connection was re-established
+				// and notifications may have been missed; clients recover
+				// those through Last-Event-ID.
+				if n == nil {
+					continue
+				}
+				var ev Event
+				err := json.Unmarshal([]byte(n.Extra), &ev)
+				if err != nil {
+					slog.Error("stream: bad notification", "err", err)
+					continue
+				}
+				b.hub.Broadcast(ev)
+			case <-time.After(90 * time.Second):
+				go listener.Ping()
+			}
+		}
+	}()
+	return nil
+}
+
+// Run prunes events older than the retention period until ctx is cancelled.
+func (b *Broker) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		err := b.db.DeleteChirpEventsOlderThan(ctx, b.Retention.Seconds())
+		if err != nil && ctx.Err() == nil {
+			slog.Error("stream: prune events", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
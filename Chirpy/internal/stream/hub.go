@@ -0,0 +1,103 @@
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+)
+
+const (
+	EventChirpCreated = "chirp.created"
+	EventChirpDeleted = "chirp.deleted"
+)
+
+type Event struct {
+	ID        int64        `json:"id"`
+	Type      string       `json:"type"`
+	CreatedAt time.Time    `json:"created_at"`
+	Chirp     database.Res `json:"chirp"`
+}
+
+// Subscription receives live events from a Hub. C is closed when the
+// subscriber falls too far behind or is unsubscribed.
+type Subscription struct {
+	C        chan Event
+	authorID uuid.UUID
+}
+
+func (s *Subscription) matches(ev Event) bool {
+	return s.authorID == uuid.Nil || s.authorID == ev.Chirp.UserID
+}
+
+// Hub fans events out to the subscribers of this process.
+type Hub struct {
+	mux    sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber for events. A nil authorID receives
+// events for every author.
+func (h *Hub) Subscribe(authorID uuid.UUID) *Subscription {
+	s := &Subscription{
+		C:        make(chan Event, 64),
+		authorID: authorID,
+	}
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if h.closed {
+		close(s.C)
+		return s
+	}
+	h.subs[s] = struct{}{}
+	return s
+}
+
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.C)
+	}
+}
+
+// Close ends every subscription, so streaming handlers return and the server
+// can shut down. Later subscriptions start out closed.
+func (h *Hub) Close() {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.closed = true
+	for s := range h.subs {
+		delete(h.subs, s)
+		close(s.C)
+	}
+}
+
+// Broadcast delivers ev to every matching subscriber without blocking.
+// Subscribers whose buffer is full are dropped; they can reconnect and
+// resume with Last-Event-ID.
+func (h *Hub) Broadcast(ev Event) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	for s := range h.subs {
+		if !s.matches(ev) {
+			continue
+		}
+		select {
+		case s.C <- ev:
+		default:
+			delete(h.subs, s)
+			close(s.C)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"Chirpy/internal/database"
+)
+
+var dbTracer = Tracer("Chirpy/internal/database")
+
+// tracedDB wraps a database.DBTX so every query gets a span named after its
+// sqlc query name.
+type tracedDB struct {
+	db     database.DBTX
+	system string
+}
+
+// WrapDB returns db with every query traced. Pass the result to database.New,
+// and wrap transactions the same way. system is the db.system attribute of
+// the spans, such as "postgresql".
+func WrapDB(db database.DBTX, system string) database.DBTX {
+	return tracedDB{db: db, system: system}
+}
+
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.startQuery(ctx, query)
+	defer span.End()
+	res, err := t.db.ExecContext(ctx, query, args...)
+	recordQueryError(span, err)
+	return res, err
+}
+
+func (t tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := t.startQuery(ctx, query)
+	defer span.End()
+	stmt, err := t.db.PrepareContext(ctx, query)
+	recordQueryError(span, err)
+	return stmt, err
+}
+
+// QueryContext traces running the query; reading the rows afterwards is not
+// part of the span.
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := t.startQuery(ctx, query)
+	defer span.End()
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	recordQueryError(span, err)
+	return rows, err
+}
+
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := t.startQuery(ctx, query)
+	defer span.End()
+	row := t.db.QueryRowContext(ctx, query, args...)
+	recordQueryError(span, row.Err())
+	return row
+}
+
+func (t tracedDB) startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+	name := queryName(query)
+	return dbTracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String(t.system),
+			semconv.DBOperationName(name),
+			semconv.DBQueryText(query),
+		),
+	)
+}
+
+// recordQueryError ignores sql.ErrNoRows, which callers treat as an answer
+// rather than a failure.
+func recordQueryError(span trace.Span, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	RecordError(span, err)
+}
+
+// queryName returns the name from sqlc's "-- name: GetUser :one" header, or
+// the SQL verb for queries without one.
+func queryName(query string) string {
+	if rest, ok := strings.CutPrefix(query, "-- name: "); ok {
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	if fields := strings.Fields(query); len(fields) > 0 {
+		return strings.ToUpper(fields[0])
+	}
+	return "query"
+}
@@ -0,0 +1,270 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/tracing"
+)
+
+const (
+	EventChirpCreated = "chirp.created"
+	EventChirpDeleted = "chirp.deleted"
+	EventUserUpgraded = "user.upgraded"
+)
+
+// Events lists every event a subscription may listen for.
+var Events = []string{EventChirpCreated, EventChirpDeleted, EventUserUpgraded}
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+const (
+	SignatureHeader = "X-Chirpy-Signature"
+	EventHeader     = "X-Chirpy-Event"
+	DeliveryHeader  = "X-Chirpy-Delivery"
+)
+
+// IsEvent reports whether name is a known event.
+func IsEvent(name string) bool {
+	for _, e := range Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+var tracer = tracing.Tracer("Chirpy/internal/webhooks")
+
+type Envelope struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Dispatcher queues events for every matching subscription and delivers
+// them in the background, retrying with exponential backoff until
+// MaxAttempts is reached, after which the delivery is dead-lettered.
+type Dispatcher struct {
+	db           database.Querier
+	client       *http.Client
+	MaxAttempts  int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+// NewDispatcher returns a Dispatcher that delivers with client, or with a
+// client that refuses private and loopback addresses if client is nil.
+func NewDispatcher(db database.Querier, client *http.Client) *Dispatcher {
+	if client == nil {
+		client = newClient()
+	}
+	return &Dispatcher{
+		db:           db,
+		client:       client,
+		MaxAttempts:  8,
+		BaseDelay:    30 * time.Second,
+		MaxDelay:     6 * time.Hour,
+		BatchSize:    20,
+		PollInterval: 5 * time.Second,
+	}
+}
+
+// NewSecret generates a signing secret for a subscription.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign computes the signature header value for a payload. Receivers should
+// recompute the HMAC over "<timestamp>.<body>" and compare it to v1.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Backoff returns the delay before the next attempt after attempts failures.
+func (d *Dispatcher) Backoff(attempts int) time.Duration {
+	delay := d.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.MaxDelay {
+			return d.MaxDelay
+		}
+	}
+	return delay
+}
+
+// Publish queues an event for every active subscription listening for it.
+// Events carrying UserData are only queued for that user's own
+// subscriptions.
+func (d *Dispatcher) Publish(ctx context.Context, event string, data any) error {
+	subs, err := d.db.ListWebhookSubscriptionsForEvent(ctx, event)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(Envelope{Event: event, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		if u, ok := data.(UserData); ok && sub.UserID != u.UserID {
+			continue
+		}
+		_, err := d.db.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			SubscriptionID: sub.ID,
+			Event:          event,
+			Payload:        payload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run delivers due webhooks until ctx is cancelled, finishing the batch in
+// progress.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+	work := context.WithoutCancel(ctx)
+	for {
+		d.DeliverDue(work)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue claims and attempts a batch of due deliveries. Claimed rows are
+// leased for a minute so a crashed instance's work is picked up again.
+func (d *Dispatcher) DeliverDue(ctx context.Context) {
+	deliveries, err := d.db.ClaimDueWebhookDeliveries(ctx, int32(d.BatchSize))
+	if err != nil {
+		slog.Error("webhooks: claim deliveries", "err", err)
+		return
+	}
+	for _, delivery := range deliveries {
+		d.deliver(ctx, delivery)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery database.WebhookDelivery) {
+	sub, err := d.db.GetWebhookSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		slog.Error("webhooks: load subscription", "err", err)
+		return
+	}
+
+	statusCode, err := d.send(ctx, sub, delivery)
+	code := sql.NullInt32{Int32: int32(statusCode), Valid: statusCode != 0}
+	if err == nil {
+		err = d.db.MarkWebhookDeliverySucceeded(ctx, database.MarkWebhookDeliverySucceededParams{
+			ID:             delivery.ID,
+			LastStatusCode: code,
+		})
+		if err != nil {
+			slog.Error("webhooks: mark delivered", "err", err)
+		}
+		return
+	}
+
+	attempts := int(delivery.Attempts) + 1
+	status := StatusPending
+	if attempts >= d.MaxAttempts {
+		status = StatusDead
+	}
+	err = d.db.MarkWebhookDeliveryFailed(ctx, database.MarkWebhookDeliveryFailedParams{
+		ID:             delivery.ID,
+		Status:         status,
+		LastStatusCode: code,
+		LastError:      sql.NullString{String: err.Error(), Valid: true},
+		RetryInSeconds: d.Backoff(attempts).Seconds(),
+	})
+	if err != nil {
+		slog.Error("webhooks: mark failed", "err", err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, sub database.WebhookSubscription, delivery database.WebhookDelivery) (statusCode int, err error) {
+	ctx, span := tracer.Start(ctx, "webhooks.send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("webhook.event", delivery.Event), attribute.String("webhook.delivery_id", delivery.ID.String())),
+	)
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, time.Now().Unix(), delivery.Payload))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("receiver responded with %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// ChirpData is the payload sent for chirp events.
+type ChirpData struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Body   string    `json:"body,omitempty"`
+}
+
+// UserData is the payload sent for user events.
+type UserData struct {
+	UserID uuid.UUID `json:"user_id"`
+}
@@ -0,0 +1,355 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+)
+
+// store is an in-memory stand-in for the webhook queries the dispatcher
+// uses. Every pending delivery is due, so tests can step through retries
+// without waiting; the requested delays are recorded in retries instead.
+type store struct {
+	database.Querier
+	subs       []database.WebhookSubscription
+	deliveries []*database.WebhookDelivery
+	retries    []float64
+}
+
+func (s *store) subscribe(userID uuid.UUID, rawURL string, events ...string) database.WebhookSubscription {
+	sub := database.WebhookSubscription{ID: uuid.New(), UserID: userID, Url: rawURL, Secret: "whsec_test", Events: events, Active: true}
+	s.subs = append(s.subs, sub)
+	return sub
+}
+
+func (s *store) ListWebhookSubscriptionsForEvent(ctx context.Context, event string) ([]database.WebhookSubscription, error) {
+	var subs []database.WebhookSubscription
+	for _, sub := range s.subs {
+		for _, e := range sub.Events {
+			if e == event {
+				subs = append(subs, sub)
+			}
+		}
+	}
+	return subs, nil
+}
+
+func (s *store) GetWebhookSubscription(ctx context.Context, id uuid.UUID) (database.WebhookSubscription, error) {
+	for _, sub := range s.subs {
+		if sub.ID == id {
+			return sub, nil
+		}
+	}
+	return database.WebhookSubscription{}, errors.New("no such subscription")
+}
+
+func (s *store) CreateWebhookDelivery(ctx context.Context, arg database.CreateWebhookDeliveryParams) (database.WebhookDelivery, error) {
+	d := &database.WebhookDelivery{ID: uuid.New(), SubscriptionID: arg.SubscriptionID, Event: arg.Event, Payload: arg.Payload, Status: StatusPending}
+	s.deliveries = append(s.deliveries, d)
+	return *d, nil
+}
+
+func (s *store) ClaimDueWebhookDeliveries(ctx context.Context, limit int32) ([]database.WebhookDelivery, error) {
+	var due []database.WebhookDelivery
+	for _, d := range s.deliveries {
+		if d.Status == StatusPending && len(due) < int(limit) {
+			due = append(due, *d)
+		}
+	}
+	return due, nil
+}
+
+func (s *store) delivery(id uuid.UUID) *database.WebhookDelivery {
+	for _, d := range s.deliveries {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+func (s *store) MarkWebhookDeliverySucceeded(ctx context.Context, arg database.MarkWebhookDeliverySucceededParams) error {
+	d := s.delivery(arg.ID)
+	d.Status = StatusDelivered
+	d.Attempts++
+	d.LastStatusCode = arg.LastStatusCode
+	return nil
+}
+
+func (s *store) MarkWebhookDeliveryFailed(ctx context.Context, arg database.MarkWebhookDeliveryFailedParams) error {
+	d := s.delivery(arg.ID)
+	d.Status = arg.Status
+	d.Attempts++
+	d.LastStatusCode = arg.LastStatusCode
+	d.LastError = arg.LastError
+	s.retries = append(s.retries, arg.RetryInSeconds)
+	return nil
+}
+
+// receiver records the requests it gets and answers with the next status
+// in statuses, repeating the last one once they run out.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := rc.statuses[0]
+	if len(rc.statuses) > 1 {
+		rc.statuses = rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func newTestDispatcher(t *testing.T, statuses ...int) (*Dispatcher, *store, *receiver, *httptest.Server) {
+	t.Helper()
+	rc := &receiver{statuses: statuses}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+	db := &store{}
+	return NewDispatcher(db, srv.Client()), db, rc, srv
+}
+
+func TestDeliverSignsPayload(t *testing.T) {
+	ctx := context.Background()
+	d, db, rc, srv := newTestDispatcher(t, http.StatusNoContent)
+	sub := db.subscribe(uuid.New(), srv.URL+"/hook", EventChirpCreated)
+
+	err := d.Publish(ctx, EventChirpCreated, ChirpData{ID: uuid.New(), UserID: sub.UserID, Body: "hello"})
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	d.DeliverDue(ctx)
+
+	if rc.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", rc.count())
+	}
+	req, body := rc.requests[0], rc.bodies[0]
+	delivery := db.deliveries[0]
+	if got := req.Header.Get(EventHeader); got != EventChirpCreated {
+		t.Errorf("%s = %q, want %q", EventHeader, got, EventChirpCreated)
+	}
+	if got := req.Header.Get(DeliveryHeader); got != delivery.ID.String() {
+		t.Errorf("%s = %q, want %q", DeliveryHeader, got, delivery.ID)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	sig := req.Header.Get(SignatureHeader)
+	var ts int64
+	var v1 string
+	_, err = fmt.Sscanf(sig, "t=%d,v1=%s", &ts, &v1)
+	if err != nil {
+		t.Fatalf("%s = %q: %v", SignatureHeader, sig, err)
+	}
+	if want := Sign(sub.Secret, ts, body); sig != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, sig, want)
+	}
+	if age := time.Since(time.Unix(ts, 0)); age < 0 || age > time.Minute {
+		t.Errorf("signature timestamp is %v old", age)
+	}
+	if Sign("whsec_other", ts, body) == sig {
+		t.Error("signature does not depend on the secret")
+	}
+
+	if delivery.Status != StatusDelivered || delivery.Attempts != 1 {
+		t.Errorf("delivery is %s after %d attempts, want %s after 1", delivery.Status, delivery.Attempts, StatusDelivered)
+	}
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	d, db, rc, srv := newTestDispatcher(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+	d.BaseDelay = time.Second
+	d.MaxDelay = time.Minute
+	sub := db.subscribe(uuid.New(), srv.URL, EventChirpDeleted)
+
+	err := d.Publish(ctx, EventChirpDeleted, ChirpData{ID: uuid.New(), UserID: sub.UserID})
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	delivery := db.deliveries[0]
+
+	d.DeliverDue(ctx)
+	if delivery.Status != StatusPending || delivery.LastStatusCode.Int32 != 500 || !delivery.LastError.Valid {
+		t.Fatalf("after a 500 the delivery is %s with code %d and error %q", delivery.Status, delivery.LastStatusCode.Int32, delivery.LastError.String)
+	}
+	d.DeliverDue(ctx)
+	d.DeliverDue(ctx)
+
+	if rc.count() != 3 {
+		t.Fatalf("receiver got %d requests, want 3", rc.count())
+	}
+	if delivery.Status != StatusDelivered || delivery.Attempts != 3 {
+		t.Errorf("delivery is %s after %d attempts, want %s after 3", delivery.Status, delivery.Attempts, StatusDelivered)
+	}
+	if want := []float64{1, 2}; !equalFloats(db.retries, want) {
+		t.Errorf("retry delays = %v, want %v", db.retries, want)
+	}
+	// Retries reuse the delivery ID so receivers can drop duplicates.
+	for _, req := range rc.requests {
+		if got := req.Header.Get(DeliveryHeader); got != delivery.ID.String() {
+			t.Errorf("%s = %q, want %q", DeliveryHeader, got, delivery.ID)
+		}
+	}
+}
+
+func TestDeliverDeadLettersAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	d, db, rc, srv := newTestDispatcher(t, http.StatusServiceUnavailable)
+	d.MaxAttempts = 3
+	sub := db.subscribe(uuid.New(), srv.URL, EventChirpCreated)
+
+	err := d.Publish(ctx, EventChirpCreated, ChirpData{ID: uuid.New(), UserID: sub.UserID})
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	delivery := db.deliveries[0]
+	for i := 0; i < 5; i++ {
+		d.DeliverDue(ctx)
+	}
+
+	if rc.count() != 3 {
+		t.Errorf("receiver got %d requests, want 3", rc.count())
+	}
+	if delivery.Status != StatusDead || delivery.Attempts != 3 {
+		t.Errorf("delivery is %s after %d attempts, want %s after 3", delivery.Status, delivery.Attempts, StatusDead)
+	}
+	if delivery.LastStatusCode.Int32 != http.StatusServiceUnavailable {
+		t.Errorf("last status code = %d, want %d", delivery.LastStatusCode.Int32, http.StatusServiceUnavailable)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(nil, nil)
+	d.BaseDelay = 30 * time.Second
+	d.MaxDelay = 5 * time.Minute
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 5 * time.Minute},
+		{20, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := d.Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestPublishUserEventOnlyToThatUser(t *testing.T) {
+	ctx := context.Background()
+	d, db, _, srv := newTestDispatcher(t, http.StatusOK)
+	upgraded, other := uuid.New(), uuid.New()
+	own := db.subscribe(upgraded, srv.URL, EventUserUpgraded)
+	db.subscribe(other, srv.URL, EventUserUpgraded)
+
+	err := d.Publish(ctx, EventUserUpgraded, UserData{UserID: upgraded})
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if len(db.deliveries) != 1 || db.deliveries[0].SubscriptionID != own.ID {
+		t.Errorf("queued %d deliveries, want 1 for the upgraded user's subscription", len(db.deliveries))
+	}
+}
+
+func TestDefaultClientRefusesLoopback(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{statuses: []int{http.StatusOK}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	db := &store{}
+	d := NewDispatcher(db, nil)
+	sub := db.subscribe(uuid.New(), srv.URL, EventChirpCreated)
+
+	err := d.Publish(ctx, EventChirpCreated, ChirpData{ID: uuid.New(), UserID: sub.UserID})
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	d.DeliverDue(ctx)
+
+	if rc.count() != 0 {
+		t.Errorf("receiver on %s got %d requests, want 0", srv.URL, rc.count())
+	}
+	delivery := db.deliveries[0]
+	if delivery.Status != StatusPending || !strings.Contains(delivery.LastError.String, ErrForbiddenTarget.Error()) {
+		t.Errorf("delivery is %s with error %q, want %s with %q", delivery.Status, delivery.LastError.String, StatusPending, ErrForbiddenTarget)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://93.184.215.14/hook", true},
+		{"http://[2606:4700::1111]:8080/", true},
+		{"http://127.0.0.1/", false},
+		{"http://localhost:8080/", false},
+		{"http://10.1.2.3/", false},
+		{"http://172.16.0.1/", false},
+		{"http://192.168.1.1/", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://0.0.0.0/", false},
+		{"http://[::1]/", false},
+		{"http://[::ffff:127.0.0.1]/", false},
+		{"http://[fe80::1]/", false},
+		{"http://[fd00::1]/", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = CheckURL(context.Background(), u)
+		if tt.allowed && err != nil {
+			t.Errorf("CheckURL(%s) = %v, want nil", tt.url, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrForbiddenTarget) {
+			t.Errorf("CheckURL(%s) = %v, want %v", tt.url, err, ErrForbiddenTarget)
+		}
+	}
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,29 +1,66 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
 
 	"Chirpy/internal/auth"
+	"Chirpy/internal/config"
 	"Chirpy/internal/database"
+	"Chirpy/internal/entitlements"
+	"Chirpy/internal/export"
+	"Chirpy/internal/health"
+	"Chirpy/internal/media"
+	"Chirpy/internal/metrics"
+	"Chirpy/internal/notifications"
+	"Chirpy/internal/problem"
+	"Chirpy/internal/ratelimit"
+	"Chirpy/internal/retention"
+	"Chirpy/internal/scheduler"
+	"Chirpy/internal/storage"
+	"Chirpy/internal/stream"
+	"Chirpy/internal/tracing"
+	"Chirpy/internal/webhooks"
 )
 
 type apiConfig struct {
-	fileserverHits int
-	dbQueries      *database.Queries
-	platform       string
-	token          string
-	polkaKey       string
+	metrics         *metrics.Metrics
+	health          *health.Checker
+	db              *storage.DB
+	dbQueries       database.Querier
+	platform        string
+	token           string
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	polkaKey        string
+	webhooks        *webhooks.Dispatcher
+	stream          *stream.Broker
+	notifier        *notifications.Notifier
+	media           media.Storage
+	restoreWindow   time.Duration
+	deletionGrace   time.Duration
+	limiter         *rateLimiter
+}
+
+// withTx returns queries that run in tx, traced like cfg.dbQueries.
+func (cfg *apiConfig) withTx(tx *sql.Tx) database.Querier {
+	return cfg.db.Queries(tx)
 }
 
 type User struct {
@@ -31,6 +68,7 @@ type User struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	Email        string    `json:"email"`
+	Handle       string    `json:"handle"`
 	Token        string    `json:"token"`
 	RefreshToken string    `json:"refresh_token"`
 	IsChirpyRed  bool      `json:"is_chirpy_red"`
@@ -40,11 +78,13 @@ type Token struct {
 	Token string `json:"token"`
 }
 
-func (cfg *apiConfig) increaseHits() {
-	cfg.fileserverHits = cfg.fileserverHits + 1
-}
-
+// checkHits renders the admin page from the same registry /metrics serves.
 func (cfg *apiConfig) checkHits(w http.ResponseWriter, r *http.Request) {
+	families, err := cfg.metrics.Gather()
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(200)
 	w.Write([]byte(fmt.Sprintf(`<html>
@@ -52,126 +92,201 @@ func (cfg *apiConfig) checkHits(w http.ResponseWriter, r *http.Request) {
 <body>
     <h1>Welcome, Chirpy Admin</h1>
     <p>Chirpy has been visited %d times!</p>
+    <p>Chirpy has served %d requests.</p>
 </body>
 
-</html>`, cfg.fileserverHits)))
+</html>`, int64(metrics.Sum(families, metrics.FileserverHitsName)), int64(metrics.Sum(families, metrics.RequestsName)))))
 }
 
 func (cfg *apiConfig) resetHits(w http.ResponseWriter, r *http.Request) {
-	cfg.fileserverHits = 0
+	cfg.metrics.ResetFileserverHits()
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cfg.increaseHits()
+		cfg.metrics.IncFileserverHits()
 		next.ServeHTTP(w, r)
 	})
 }
 
 func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body   string    `json:"body"`
-		UserID uuid.UUID `json:"user_id"`
+		Body      string     `json:"body"`
+		UserID    uuid.UUID  `json:"user_id"`
+		ReplyToID *uuid.UUID `json:"reply_to_id"`
+		PublishAt *time.Time `json:"publish_at"`
+		QuoteOfID *uuid.UUID `json:"quote_of_id"`
 	}
 
-	type e struct {
-		Err string `json:"error"`
+	id, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		w.WriteHeader(401)
+	params := parameters{}
+	var uploads []*multipart.FileHeader
+	if isMultipart(r) {
+		var err error
+		params.Body, params.ReplyToID, uploads, err = decodeChirpForm(w, r)
+		if isAttachmentInputError(err) {
+			respondWithAttachmentError(w, r, err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, r, 400, problem.CodeInvalidRequest, "Request body is not a valid multipart form")
+			return
+		}
+		if v := r.FormValue("publish_at"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				respondWithError(w, r, 400, problem.CodeValidationFailed, "publish_at must be an RFC 3339 timestamp")
+				return
+			}
+			params.PublishAt = &t
+		}
+		if v := r.FormValue("quote_of_id"); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				respondWithError(w, r, 400, problem.CodeValidationFailed, "quote_of_id must be a UUID")
+				return
+			}
+			params.QuoteOfID = &id
+		}
+	} else if !decodeJSON(w, r, &params) {
 		return
 	}
+	// The author always comes from the token, never from the body.
+	params.UserID = id
 
-	id, err := auth.ValidateJWT(token, []byte(cfg.token))
+	caps, err := cfg.entitlementsFor(r.Context(), params.UserID)
 	if err != nil {
-		w.WriteHeader(401)
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "User no longer exists")
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	params := parameters{}
-	params.UserID = id
-	err = decoder.Decode(&params)
-	if err != nil {
-		er := e{
-			Err: "Something went wrong",
+	if len(params.Body) > caps.MaxChirpLength {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, fmt.Sprintf("Chirps can be at most %d characters", caps.MaxChirpLength))
+		return
+	}
+
+	// A publish_at in the past is treated as "now".
+	publishAt := sql.NullTime{}
+	if params.PublishAt != nil && params.PublishAt.After(time.Now()) {
+		if !caps.CanScheduleChirps {
+			respondWithError(w, r, 403, problem.CodeUpgradeRequired, "Scheduling chirps requires Chirpy Red")
+			return
 		}
-		dat, err := json.Marshal(er)
-		if err != nil {
-			w.WriteHeader(500)
+		if params.PublishAt.After(time.Now().Add(maxScheduleAhead)) {
+			respondWithError(w, r, 400, problem.CodeValidationFailed, "Chirps can be scheduled at most a year ahead")
 			return
 		}
-		w.WriteHeader(500)
-		w.Write(dat)
-		return
+		publishAt = sql.NullTime{Time: params.PublishAt.UTC(), Valid: true}
 	}
 
-	if len(params.Body) > 140 {
-		er := e{
-			Err: "Chirp is too long",
+	replyToID := uuid.NullUUID{}
+	if params.ReplyToID != nil {
+		parent, err := cfg.dbQueries.ListChirp(r.Context(), *params.ReplyToID)
+		if err == nil {
+			parent, err = cfg.originalChirp(r.Context(), parent)
+		}
+		if err != nil || parent.Status != scheduler.StatusPublished {
+			respondWithError(w, r, 400, problem.CodeValidationFailed, "Chirp being replied to does not exist")
+			return
 		}
-		dat, err := json.Marshal(er)
+		blocked, err := cfg.dbQueries.IsBlocked(r.Context(), database.IsBlockedParams{BlockerID: parent.UserID, BlockedID: params.UserID})
 		if err != nil {
-			w.WriteHeader(500)
+			respondWithInternalError(w, r, err)
 			return
 		}
-		w.WriteHeader(400)
-		w.Write(dat)
-		return
+		if blocked {
+			respondWithError(w, r, 403, problem.CodeForbidden, "You cannot reply to this chirp")
+			return
+		}
+		replyToID = uuid.NullUUID{UUID: parent.ID, Valid: true}
 	}
 
-	words := strings.Split(params.Body, " ")
-	isFixed := false
-	var newWords []string
-	for _, word := range words {
-		w := strings.ToLower(word)
-		if w == "kerfuffle" || w == "sharbert" || w == "fornax" {
-			word = "****"
-			isFixed = true
-			newWords = append(newWords, word)
-		} else {
-			newWords = append(newWords, word)
+	quoteOfID := uuid.NullUUID{}
+	if params.QuoteOfID != nil {
+		// A quote without commentary is a rechirp.
+		if strings.TrimSpace(params.Body) == "" {
+			respondWithError(w, r, 400, problem.CodeValidationFailed, "Quote chirps need a body")
+			return
 		}
-	}
-	if isFixed {
-		fixedChirp := strings.Join(newWords, " ")
-		chirp, err := cfg.dbQueries.CreateChirp(r.Context(), database.CreateChirpParams{Body: fixedChirp, UserID: params.UserID})
-		if err != nil {
+		quoted, err := cfg.dbQueries.ListChirp(r.Context(), *params.QuoteOfID)
+		if err == nil {
+			quoted, err = cfg.originalChirp(r.Context(), quoted)
 		}
-		r := database.Res{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			UpdatedAt: chirp.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
+		if err != nil || quoted.Status != scheduler.StatusPublished {
+			respondWithError(w, r, 400, problem.CodeValidationFailed, "Chirp being quoted does not exist")
+			return
 		}
-		dat, err := json.Marshal(r)
+		blocked, err := cfg.dbQueries.IsBlocked(r.Context(), database.IsBlockedParams{BlockerID: quoted.UserID, BlockedID: params.UserID})
 		if err != nil {
-			w.WriteHeader(500)
+			respondWithInternalError(w, r, err)
 			return
 		}
-		w.WriteHeader(201)
-		w.Write(dat)
+		if blocked {
+			respondWithError(w, r, 403, problem.CodeForbidden, "You cannot quote this chirp")
+			return
+		}
+		quoteOfID = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+	}
+
+	blocker, err := cfg.mentionedBlocker(r.Context(), params.UserID, params.Body)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if blocker != "" {
+		respondWithError(w, r, 403, problem.CodeForbidden, "You cannot mention @"+blocker)
 		return
 	}
-	chirp, err := cfg.dbQueries.CreateChirp(r.Context(), database.CreateChirpParams{Body: params.Body, UserID: params.UserID})
+
+	stored, err := cfg.storeAttachments(r.Context(), uploads)
 	if err != nil {
+		respondWithAttachmentError(w, r, err)
+		return
+	}
+
+	chirp, attachments, err := cfg.createChirpWithAttachments(r.Context(), database.CreateChirpParams{Body: cleanChirpBody(params.Body), UserID: params.UserID, ReplyToID: replyToID, QuoteOfID: quoteOfID}, publishAt, stored)
+	if err != nil {
+		cfg.deleteStoredAttachments(r.Context(), stored)
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if chirp.Status == scheduler.StatusPublished {
+		cfg.chirpPublished(r.Context(), chirp)
 	}
-	r2 := database.Res{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt: chirp.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	r2 := database.MapSqlChirpToJsonChirp(chirp)
+	for _, a := range attachments {
+		r2.Attachments = append(r2.Attachments, database.MapSqlAttachmentToJsonAttachment(a, cfg.media.URL))
+	}
+	r2 = cfg.withOriginals(r.Context(), cfg.withAuthors(r.Context(), []database.Res{r2}))[0]
+	respondWithJSON(w, 201, r2)
+}
+
+// cleanChirpBody replaces profane words with asterisks.
+func cleanChirpBody(body string) string {
+	words := strings.Split(body, " ")
+	var newWords []string
+	for _, word := range words {
+		w := strings.ToLower(word)
+		if w == "kerfuffle" || w == "sharbert" || w == "fornax" {
+			word = "****"
+		}
+		newWords = append(newWords, word)
 	}
-	dat, err := json.Marshal(r2)
+	return strings.Join(newWords, " ")
+}
+
+// entitlementsFor looks up the capabilities granted to a user by their
+// subscription tier.
+func (cfg *apiConfig) entitlementsFor(ctx context.Context, userID uuid.UUID) (entitlements.Capabilities, error) {
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
 	if err != nil {
+		return entitlements.ForTier(entitlements.TierFree), err
 	}
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.WriteHeader(201)
-	w.Write(dat)
+	return entitlements.For(user), nil
 }
 
 func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
@@ -181,130 +296,205 @@ func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
 	if sort != "asc" && sort != "desc" {
 		sort = "asc"
 	}
+	hidden, err := cfg.hiddenAuthors(r.Context(), cfg.viewerID(r))
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
 	var chirps []database.Chirp
 	if authorID == "" {
 		if sort == "desc" {
-			chirps, _ = cfg.dbQueries.ListChirpsDesc(r.Context())
+			chirps, err = cfg.dbQueries.ListChirpsDesc(r.Context())
 		} else {
-			chirps, _ = cfg.dbQueries.ListChirps(r.Context())
+			chirps, err = cfg.dbQueries.ListChirps(r.Context())
 		}
-		res := database.MapSqlChirpsToJsonChirps(chirps)
-		w.Header().Set("Content-Type", "text/json; charset=utf-8")
-		w.WriteHeader(200)
-		c, err := json.Marshal(res)
-		if err != nil {
-			fmt.Println(err)
-		}
-		w.Write(c)
 	} else {
+		author, perr := uuid.Parse(authorID)
+		if perr != nil {
+			respondWithError(w, r, 400, problem.CodeInvalidQuery, "author_id must be a UUID")
+			return
+		}
 		if sort == "desc" {
-			chirps, _ = cfg.dbQueries.ListChirpByAuthorIDDesc(r.Context(), uuid.MustParse(authorID))
+			chirps, err = cfg.dbQueries.ListChirpByAuthorIDDesc(r.Context(), author)
 		} else {
-			chirps, _ = cfg.dbQueries.ListChirpByAuthorID(r.Context(), uuid.MustParse(authorID))
+			chirps, err = cfg.dbQueries.ListChirpByAuthorID(r.Context(), author)
 		}
-		res := database.MapSqlChirpsToJsonChirps(chirps)
-		w.Header().Set("Content-Type", "text/json; charset=utf-8")
-		w.WriteHeader(200)
-		c, err := json.Marshal(res)
-		if err != nil {
-			fmt.Println(err)
-		}
-		w.Write(c)
 	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	res := cfg.chirpResponses(r.Context(), filterHiddenChirps(chirps, hidden))
+	respondWithJSON(w, 200, hideOriginals(res, hidden))
 }
 
 func (cfg *apiConfig) getChirpById(w http.ResponseWriter, r *http.Request) {
-	if r.Method == http.MethodGet {
-		id := r.PathValue("chirpID")
-		chirp, err := cfg.dbQueries.ListChirp(r.Context(), uuid.MustParse(id))
-		if err != nil {
-			w.WriteHeader(404)
-			return
-		}
-		res := database.MapSqlChirpToJsonChirp(chirp)
-		w.WriteHeader(200)
-		c, err := json.Marshal(res)
-		if err != nil {
-		}
-		w.Write(c)
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		cfg.updateChirpById(w, r)
+		return
+	case http.MethodDelete:
+		cfg.deleteChirpById(w, r)
+		return
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		respondWithError(w, r, 405, problem.CodeMethodNotAllowed, "")
+		return
 	}
 
-	if r.Method == http.MethodDelete {
-		cfg.deleteChirpById(w, r)
+	id, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+	chirp, err := cfg.dbQueries.ListChirp(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Chirp not found")
+		return
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	// Scheduled chirps are only visible to their author until published.
+	if chirp.Status != scheduler.StatusPublished && chirp.UserID != cfg.viewerID(r) {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Chirp not found")
+		return
+	}
+	respondWithJSON(w, 200, cfg.chirpResponses(r.Context(), []database.Chirp{chirp})[0])
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation.
+func isUniqueViolation(err error) bool {
+	return storage.IsUniqueViolation(err)
+}
+
+// hashPassword validates and hashes a new password, writing a 400 itself when
+// it is unacceptable.
+func hashPassword(w http.ResponseWriter, r *http.Request, password string) (string, bool) {
+	if password == "" {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "Password is required")
+		return "", false
+	}
+	hashed, err := auth.HashedPassword(r.Context(), password)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "Password must be at most 72 bytes")
+		return "", false
 	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return "", false
+	}
+	return hashed, true
 }
 
 func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err := decoder.Decode(&params)
-	if err != nil {
+	if !decodeJSON(w, r, &params) {
+		return
 	}
-	hashedPass, err := auth.HashedPassword(params.Password)
-	if err != nil {
+	if params.Email == "" {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "Email is required")
+		return
+	}
+	hashedPass, ok := hashPassword(w, r, params.Password)
+	if !ok {
+		return
+	}
+	var handle string
+	var err error
+	if params.Handle != "" {
+		handle, err = normalizeHandle(params.Handle)
+		if err != nil {
+			respondWithError(w, r, 400, problem.CodeValidationFailed, err.Error())
+			return
+		}
+		_, err = cfg.dbQueries.GetUserByHandle(r.Context(), handle)
+		if err == nil {
+			respondWithError(w, r, 409, problem.CodeConflict, "Handle is already taken")
+			return
+		}
+	} else {
+		handle, err = cfg.availableHandle(r.Context(), handleFromEmail(params.Email))
+		if err != nil {
+			respondWithInternalError(w, r, err)
+			return
+		}
 	}
 	userCred := database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: hashedPass,
+		Handle:         handle,
 	}
 	user, err := cfg.dbQueries.CreateUser(r.Context(), userCred)
+	if isUniqueViolation(err) {
+		respondWithError(w, r, 409, problem.CodeConflict, "Email or handle is already registered")
+		return
+	}
 	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
 	}
 	newUser := User{
 		ID:          user.ID,
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 		Email:       user.Email,
+		Handle:      user.Handle,
 		IsChirpyRed: user.IsChirpyRed,
 	}
-	userJSON, err := json.Marshal(newUser)
-	if err != nil {
-	}
-	w.WriteHeader(201)
-	w.Write(userJSON)
+	respondWithJSON(w, 201, newUser)
 }
 
 func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
-	authToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-	}
 	type request struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
-	decoder := json.NewDecoder(r.Body)
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
 	params := request{}
-	err = decoder.Decode(&params)
-	if err != nil {
+	if !decodeJSON(w, r, &params) {
+		return
 	}
-	hashedPass, err := auth.HashedPassword(params.Password)
-	if err != nil {
+	if params.Email == "" {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "Email is required")
+		return
 	}
-	userID, err := auth.ValidateJWT(authToken, []byte(cfg.token))
-	if err != nil {
-		w.WriteHeader(401)
+	hashedPass, ok := hashPassword(w, r, params.Password)
+	if !ok {
 		return
 	}
 	user, err := cfg.dbQueries.UpdateUserByID(r.Context(), database.UpdateUserByIDParams{Email: params.Email, HashedPassword: hashedPass, ID: userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "User no longer exists")
+		return
+	}
+	if isUniqueViolation(err) {
+		respondWithError(w, r, 409, problem.CodeConflict, "Email is already registered")
+		return
+	}
 	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
 	}
 	newUser := User{
 		ID:          user.ID,
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 		Email:       user.Email,
+		Handle:      user.Handle,
 		IsChirpyRed: user.IsChirpyRed,
 	}
-	userJSON, err := json.Marshal(newUser)
-	if err != nil {
-	}
-	w.WriteHeader(200)
-	w.Write(userJSON)
+	respondWithJSON(w, 200, newUser)
 }
 
 func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
@@ -312,144 +502,246 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
-	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err := decoder.Decode(&params)
-	if err != nil {
+	if !decodeJSON(w, r, &params) {
+		return
 	}
 	user, err := cfg.dbQueries.GetUserByEmail(r.Context(), params.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.metrics.AuthFailure(metrics.ReasonInvalidCredentials)
+		respondWithError(w, r, 401, problem.CodeInvalidCredentials, "Incorrect email or password")
+		return
+	}
 	if err != nil {
-		w.WriteHeader(401)
-		w.Write([]byte("Incorrect email or password"))
+		respondWithInternalError(w, r, err)
 		return
 	}
-	hashErr := auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	hashErr := auth.CheckPasswordHash(r.Context(), params.Password, user.HashedPassword)
 	if hashErr != nil {
-		w.WriteHeader(401)
-		w.Write([]byte("Incorrect email or password"))
+		cfg.metrics.AuthFailure(metrics.ReasonInvalidCredentials)
+		respondWithError(w, r, 401, problem.CodeInvalidCredentials, "Incorrect email or password")
 		return
 	}
-	expiresInSeconds := 3600
-	t, err := auth.MakeJWT(user.ID, []byte(cfg.token), time.Duration(expiresInSeconds)*time.Second)
+	if user.DisabledAt.Valid {
+		cfg.metrics.AuthFailure(metrics.ReasonAccountDisabled)
+		respondWithError(w, r, 403, problem.CodeForbidden, "Account is disabled")
+		return
+	}
+	t, err := auth.MakeJWT(r.Context(), user.ID, []byte(cfg.token), cfg.accessTokenTTL)
 	if err != nil {
-		w.WriteHeader(401)
+		respondWithInternalError(w, r, err)
 		return
 	}
 	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
 	}
 	// insert refresh token into db
-	rt, err := cfg.dbQueries.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{Token: refreshToken, UserID: user.ID})
+	rt, err := cfg.dbQueries.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		Token:            refreshToken,
+		UserID:           user.ID,
+		ExpiresInSeconds: cfg.refreshTokenTTL.Seconds(),
+	})
 	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
 	}
 	newUser := User{
 		ID:           user.ID,
 		CreatedAt:    user.CreatedAt,
 		UpdatedAt:    user.UpdatedAt,
 		Email:        user.Email,
+		Handle:       user.Handle,
 		Token:        t,
 		RefreshToken: rt.Token,
 		IsChirpyRed:  user.IsChirpyRed,
 	}
-	userJSON, err := json.Marshal(newUser)
-	if err != nil {
-	}
-	w.WriteHeader(200)
-	w.Write(userJSON)
+	respondWithJSON(w, 200, newUser)
 }
 
 func (cfg *apiConfig) refreshToken(w http.ResponseWriter, r *http.Request) {
 	authToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
+		cfg.metrics.AuthFailure(metrics.ReasonMissingToken)
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "Missing bearer token")
+		return
 	}
 	rf, err := cfg.dbQueries.GetRefreshToken(r.Context(), authToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.metrics.AuthFailure(metrics.ReasonInvalidRefreshToken)
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "Invalid refresh token")
+		return
+	}
 	if err != nil {
-		w.WriteHeader(401)
+		respondWithInternalError(w, r, err)
 		return
 	}
 	if rf.RevokedAt.Valid {
-		w.WriteHeader(401)
+		cfg.metrics.AuthFailure(metrics.ReasonInvalidRefreshToken)
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "Refresh token has been revoked")
 		return
 	}
 	userID, err := cfg.dbQueries.GetUserByRefreshToken(r.Context(), rf.Token)
 	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
 	}
 	if userID == uuid.Nil {
-		w.WriteHeader(401)
+		cfg.metrics.AuthFailure(metrics.ReasonInvalidRefreshToken)
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "Invalid refresh token")
 		return
 	}
-	expiresInSeconds := 3600
-	t, err := auth.MakeJWT(userID, []byte(cfg.token), time.Duration(expiresInSeconds)*time.Second)
+	t, err := auth.MakeJWT(r.Context(), userID, []byte(cfg.token), cfg.accessTokenTTL)
 	if err != nil {
-		w.WriteHeader(401)
+		respondWithInternalError(w, r, err)
 		return
 	}
 	newToken := Token{
 		Token: t,
 	}
-	ret, err := json.Marshal(newToken)
-	if err != nil {
-	}
-	w.WriteHeader(200)
-	w.Write(ret)
+	respondWithJSON(w, 200, newToken)
 }
 
 func (cfg *apiConfig) revokeToken(w http.ResponseWriter, r *http.Request) {
 	authToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "Missing bearer token")
+		return
 	}
 	err = cfg.dbQueries.UpdateRefreshToken(r.Context(), authToken)
 	if err != nil {
-		log.Println(err)
+		respondWithInternalError(w, r, err)
+		return
 	}
 	w.WriteHeader(204)
 }
 
 func (cfg *apiConfig) resetUsers(w http.ResponseWriter, r *http.Request) {
 	if cfg.platform != "dev" {
-		w.WriteHeader(403)
+		respondWithError(w, r, 403, problem.CodeForbidden, "Reset is only available on the dev platform")
 		return
 	}
 
-	cfg.dbQueries.ResetUsers(r.Context())
+	err := cfg.dbQueries.ResetUsers(r.Context())
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(200)
 }
 
 func (cfg *apiConfig) deleteChirpById(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("chirpID")
-	chirp, err := cfg.dbQueries.ListChirp(r.Context(), uuid.MustParse(id))
-	if err != nil {
-		w.WriteHeader(404)
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
 		return
 	}
-	authToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
+	id, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+	chirp, err := cfg.dbQueries.ListChirp(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Chirp not found")
+		return
 	}
-	userID, err := auth.ValidateJWT(authToken, []byte(cfg.token))
 	if err != nil {
-		w.WriteHeader(401)
+		respondWithInternalError(w, r, err)
 		return
 	}
 	if chirp.UserID != userID {
-		w.WriteHeader(403)
+		user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+		if err != nil || !user.IsAdmin {
+			respondWithError(w, r, 403, problem.CodeForbidden, "Only the author can delete this chirp")
+			return
+		}
+	}
+	// Rechirps go down with the original and come back with it on restore.
+	rechirps, err := cfg.dbQueries.ListRechirpsOf(r.Context(), uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	deleted, err := cfg.softDeleteChirp(r.Context(), chirp.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Chirp not found")
 		return
 	}
-	err = cfg.dbQueries.DeleteChirpById(r.Context(), uuid.MustParse(id))
 	if err != nil {
-		w.WriteHeader(500)
+		respondWithInternalError(w, r, err)
 		return
 	}
+	if deleted.Status == scheduler.StatusPublished {
+		cfg.chirpDeleted(r.Context(), deleted)
+		for _, rechirp := range rechirps {
+			cfg.chirpDeleted(r.Context(), rechirp)
+		}
+	}
 	w.WriteHeader(204)
 }
 
-func (cfg *apiConfig) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+func (cfg *apiConfig) updateChirpById(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
 
-	key, err := auth.GetAPIKey(r.Header)
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+	chirp, err := cfg.dbQueries.ListChirp(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Chirp not found")
+		return
+	}
 	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, r, 403, problem.CodeForbidden, "Only the author can edit this chirp")
+		return
+	}
+	if chirp.RechirpOfID.Valid {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "Rechirps cannot be edited")
+		return
+	}
+	caps, err := cfg.entitlementsFor(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "User no longer exists")
+		return
+	}
+	if !caps.CanEditChirps {
+		respondWithError(w, r, 403, problem.CodeUpgradeRequired, "Editing chirps requires Chirpy Red")
+		return
+	}
 
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	if len(params.Body) > caps.MaxChirpLength {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, fmt.Sprintf("Chirps can be at most %d characters", caps.MaxChirpLength))
+		return
 	}
 
-	if key != cfg.polkaKey {
-		w.WriteHeader(401)
+	updated, err := cfg.dbQueries.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{Body: cleanChirpBody(params.Body), ID: chirp.ID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, database.MapSqlChirpToJsonChirp(updated))
+}
+
+func (cfg *apiConfig) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	key, err := auth.GetAPIKey(r.Header)
+	if err != nil || key != cfg.polkaKey {
+		cfg.metrics.AuthFailure(metrics.ReasonInvalidAPIKey)
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "Invalid API key")
 		return
 	}
 
@@ -460,70 +752,232 @@ func (cfg *apiConfig) handleWebhooks(w http.ResponseWriter, r *http.Request) {
 		} `json:"data"`
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	req := webhookRequest{}
-	err = decoder.Decode(&req)
-	if err != nil {
+	if !decodeJSON(w, r, &req) {
+		return
 	}
 
-	if req.Event == "user.upgraded" {
-		_, err := cfg.dbQueries.UpdateUserChirpyRedByID(r.Context(), database.UpdateUserChirpyRedByIDParams{IsChirpyRed: true, ID: uuid.MustParse(req.Data.UserID)})
-		if err != nil {
-			w.WriteHeader(404)
-			return
-		}
-	} else {
+	if req.Event != "user.upgraded" {
 		w.WriteHeader(204)
 		return
 	}
-
+	userID, err := uuid.Parse(req.Data.UserID)
+	if err != nil {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "data.user_id must be a UUID")
+		return
+	}
+	user, err := cfg.dbQueries.UpdateUserChirpyRedByID(r.Context(), database.UpdateUserChirpyRedByIDParams{IsChirpyRed: true, ID: userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 404, problem.CodeNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	cfg.publishWebhook(r.Context(), webhooks.EventUserUpgraded, webhooks.UserData{UserID: user.ID})
 	w.WriteHeader(204)
 }
 
+// healthz is the liveness probe. It only shows the process is serving
+// requests, so a database outage does not get every instance restarted;
+// readyz decides whether an instance gets traffic.
 func healthz(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(200)
 	w.Write([]byte("OK"))
 }
 
+// readyz is the readiness probe: 200 when the database answers and is
+// migrated, 503 with the failing checks otherwise.
+func (cfg *apiConfig) readyz(w http.ResponseWriter, r *http.Request) {
+	report := cfg.health.Check(r.Context())
+	status := 200
+	if report.Status != health.StatusOK {
+		status = 503
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, status, report)
+}
+
 func main() {
-	godotenv.Load()
-	dbURL := os.Getenv("DB_URL")
-	platform := os.Getenv("PLATFORM")
-	tokenSecret := os.Getenv("TOKEN_SECRET")
-	polkaKey := os.Getenv("POLKA_KEY")
-	db, err := sql.Open("postgres", dbURL)
+	flag.Usage = usage
+	printConfig := flag.Bool("print-config", false, "print the effective configuration, secrets redacted, and exit")
+	flag.Parse()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		err := cfg.Print(os.Stdout)
+		if err != nil {
+			log.Fatalf("print config: %v", err)
+		}
+		return
+	}
+	slog.SetDefault(newLogger(cfg.LogLevel))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err = runCommand(ctx, cfg, flag.Args())
+	stop()
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		slog.Error("chirpy: "+commandName(flag.Args()), "err", err)
+		os.Exit(1)
+	}
+}
+
+// newAPIConfig wires the handlers' dependencies to db. The server and the
+// admin commands share it, so both apply the same rules.
+func newAPIConfig(cfg *config.Config, db *storage.DB) *apiConfig {
+	dbQueries := db.Queries(db.DB)
+	restoreWindow, _ := chirpRetention(cfg.Retention)
+	return &apiConfig{
+		metrics:         metrics.New(db.DB),
+		db:              db,
+		dbQueries:       dbQueries,
+		platform:        cfg.Platform,
+		token:           cfg.Auth.TokenSecret,
+		accessTokenTTL:  cfg.Auth.AccessTokenTTL,
+		refreshTokenTTL: cfg.Auth.RefreshTokenTTL,
+		polkaKey:        cfg.Auth.PolkaKey,
+		webhooks:        webhooks.NewDispatcher(dbQueries, nil),
+		stream:          stream.NewBroker(dbQueries, stream.NewHub()),
+		notifier:        notifications.NewNotifier(dbQueries),
+		media:           newMediaStorage(cfg.Media),
+		restoreWindow:   restoreWindow,
+		deletionGrace:   cfg.Retention.AccountDeletionGrace,
+	}
+}
+
+// runServe implements "chirpy serve": it serves the API and runs the
+// background workers until ctx is cancelled.
+func runServe(ctx context.Context, cfg *config.Config) error {
+	err := cfg.ValidateServer()
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing.Exporter)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := shutdownTracing(context.Background())
+		if err != nil {
+			slog.Error("tracing: flush spans", "err", err)
+		}
+	}()
+	dbURL := cfg.Database.URL
+	db, err := openDB(ctx, cfg.Database)
+	if err != nil {
+		return fmt.Errorf("database unavailable: %w", err)
+	}
+	defer db.Close()
+	schemaVersion, err := prepareDB(ctx, db, cfg.Database.AutoMigrate)
 	if err != nil {
-		log.Fatal()
+		return fmt.Errorf("refusing to serve: %w", err)
 	}
-	dbQueries := database.New(db)
 	mux := http.NewServeMux()
-	apiConfig := apiConfig{
-		fileserverHits: 0,
-		dbQueries:      dbQueries,
-		platform:       platform,
-		token:          tokenSecret,
-		polkaKey:       polkaKey,
+	apiConfig := newAPIConfig(cfg, db)
+	apiConfig.health = health.NewChecker(db.DB, schemaVersion)
+	// The handler reads the rate limiter per request, so the server can be
+	// built before the limiter is, and fail before any worker starts.
+	server, reloader, err := newServer(withMiddleware(unmatchedRouteProblems(mux, apiConfig.limitRequests(mux)), apiConfig.metrics), cfg.Server)
+	if err != nil {
+		return err
+	}
+	var workers sync.WaitGroup
+	// The JSON driver stores none of the tables the workers read.
+	if db.Driver != storage.JSON {
+		goWorker(ctx, &workers, apiConfig.webhooks.Run)
+		goWorker(ctx, &workers, apiConfig.stream.Run)
+		goWorker(ctx, &workers, scheduler.New(apiConfig.dbQueries, apiConfig.chirpPublished).Run)
+		goWorker(ctx, &workers, retention.NewPurger(apiConfig.dbQueries, apiConfig.media, cfg.Retention.ChirpRetention, apiConfig.deletionGrace).Run)
+		goWorker(ctx, &workers, export.NewWorker(apiConfig.dbQueries, apiConfig.media).Run)
+	}
+	if store := newRateLimitStore(apiConfig.dbQueries, cfg.RateLimit.Store); store != nil {
+		apiConfig.limiter = newRateLimiter(store, cfg.RateLimit)
+		if pg, ok := store.(*ratelimit.PostgresStore); ok {
+			goWorker(ctx, &workers, pg.Run)
+		}
 	}
-	server := http.Server{
-		Addr:    ":8080",
-		Handler: mux,
+	if db.Driver == storage.Postgres {
+		err = apiConfig.stream.Listen(ctx, dbURL)
+		if err != nil {
+			slog.Warn("stream: LISTEN unavailable, events stay local to this instance", "err", err)
+		}
+	}
+	if reloader != nil {
+		goWorker(ctx, &workers, reloader.Run)
 	}
 	handler := http.StripPrefix("/app", http.FileServer(http.Dir(".")))
 	mux.Handle("/app/", apiConfig.middlewareMetricsInc(handler))
+	if local, ok := apiConfig.media.(*media.LocalStorage); ok && strings.HasPrefix(local.BaseURL, "/") {
+		mux.Handle("GET "+local.BaseURL, http.StripPrefix(local.BaseURL, http.FileServer(http.Dir(local.Root))))
+	}
 	mux.HandleFunc("GET /api/healthz", healthz)
+	mux.HandleFunc("GET /api/readyz", apiConfig.readyz)
+	mux.Handle("GET /metrics", apiConfig.metrics.Handler())
 	mux.HandleFunc("GET /admin/metrics", apiConfig.checkHits)
 	mux.HandleFunc("/api/reset", apiConfig.resetHits)
 	mux.HandleFunc("GET /api/chirps", apiConfig.getChirps)
+	mux.HandleFunc("GET /api/chirps/stream", apiConfig.streamChirps)
+	mux.HandleFunc("GET /api/chirps/ws", apiConfig.streamChirpsWebSocket)
+	mux.HandleFunc("GET /api/chirps/scheduled", apiConfig.listScheduledChirps)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}/schedule", apiConfig.rescheduleChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/schedule", apiConfig.cancelScheduledChirp)
 	mux.HandleFunc("/api/chirps/{chirpID}", apiConfig.getChirpById)
 	mux.HandleFunc("POST /api/users", apiConfig.createUser)
 	mux.HandleFunc("PUT /api/users", apiConfig.updateUser)
+	mux.HandleFunc("GET /api/users/{handle}", apiConfig.getProfile)
+	mux.HandleFunc("PUT /api/users/me/profile", apiConfig.updateProfile)
+	mux.HandleFunc("PUT /api/users/me/avatar", apiConfig.updateAvatar)
+	mux.HandleFunc("DELETE /api/users/me", apiConfig.deleteAccount)
+	mux.HandleFunc("DELETE /api/users/me/deletion", apiConfig.cancelAccountDeletion)
+	mux.HandleFunc("GET /api/users/me/export", apiConfig.exportAccount)
+	mux.HandleFunc("GET /api/users/me/exports/{exportID}", apiConfig.getAccountExport)
 	mux.HandleFunc("POST /admin/reset", apiConfig.resetUsers)
 	mux.HandleFunc("POST /api/chirps", apiConfig.createChirp)
 	mux.HandleFunc("POST /api/login", apiConfig.loginUser)
 	mux.HandleFunc("POST /api/refresh", apiConfig.refreshToken)
 	mux.HandleFunc("POST /api/revoke", apiConfig.revokeToken)
 	mux.HandleFunc("POST /api/polka/webhooks", apiConfig.handleWebhooks)
-	fmt.Println("Server running...")
-	server.ListenAndServe()
+	mux.HandleFunc("POST /api/chirps/{chirpID}/likes", apiConfig.likeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/likes", apiConfig.unlikeChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/restore", apiConfig.restoreChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiConfig.rechirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiConfig.undoRechirp)
+	mux.HandleFunc("POST /api/users/{userID}/block", apiConfig.blockUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/block", apiConfig.unblockUser)
+	mux.HandleFunc("POST /api/users/{userID}/mute", apiConfig.muteUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", apiConfig.unmuteUser)
+	mux.HandleFunc("GET /api/users/me/blocks", apiConfig.listBlockedUsers)
+	mux.HandleFunc("GET /api/users/me/mutes", apiConfig.listMutedUsers)
+	mux.HandleFunc("POST /api/bookmarks", apiConfig.addBookmark)
+	mux.HandleFunc("GET /api/bookmarks", apiConfig.listBookmarks)
+	mux.HandleFunc("DELETE /api/bookmarks/{chirpID}", apiConfig.removeBookmark)
+	mux.HandleFunc("POST /api/bookmarks/collections", apiConfig.createBookmarkCollection)
+	mux.HandleFunc("GET /api/bookmarks/collections", apiConfig.listBookmarkCollections)
+	mux.HandleFunc("PUT /api/bookmarks/collections/{collectionID}", apiConfig.renameBookmarkCollection)
+	mux.HandleFunc("DELETE /api/bookmarks/collections/{collectionID}", apiConfig.deleteBookmarkCollection)
+	mux.HandleFunc("POST /api/conversations", apiConfig.createConversation)
+	mux.HandleFunc("GET /api/conversations", apiConfig.listConversations)
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiConfig.listMessages)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiConfig.sendMessage)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", apiConfig.markConversationRead)
+	mux.HandleFunc("GET /api/notifications", apiConfig.getNotifications)
+	mux.HandleFunc("POST /api/notifications/read", apiConfig.markAllNotificationsRead)
+	mux.HandleFunc("POST /api/notifications/{notificationID}/read", apiConfig.markNotificationRead)
+	mux.HandleFunc("GET /api/notifications/preferences", apiConfig.getNotificationPreferences)
+	mux.HandleFunc("PUT /api/notifications/preferences", apiConfig.updateNotificationPreferences)
+	mux.HandleFunc("POST /api/webhooks", apiConfig.createWebhookSubscription)
+	mux.HandleFunc("GET /api/webhooks", apiConfig.listWebhookSubscriptions)
+	mux.HandleFunc("DELETE /api/webhooks/{webhookID}", apiConfig.deleteWebhookSubscription)
+	mux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries", apiConfig.listWebhookDeliveries)
+	err = serve(ctx, server, cfg.Server.ShutdownTimeout, &workers, apiConfig.stream.Hub().Close)
+	if err != nil {
+		return fmt.Errorf("server stopped: %w", err)
+	}
+	return nil
 }
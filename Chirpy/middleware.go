@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"Chirpy/internal/metrics"
+	"Chirpy/internal/problem"
+	"Chirpy/internal/tracing"
+)
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+// requestInfo is what the access log knows about a request. Handlers fill in
+// the user once they have authenticated it, so it is shared by pointer.
+type requestInfo struct {
+	id     string
+	userID uuid.UUID
+}
+
+type requestInfoKey struct{}
+
+var httpTracer = tracing.Tracer("Chirpy")
+
+func requestInfoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+// setRequestUser records the authenticated user for the access log.
+func setRequestUser(r *http.Request, userID uuid.UUID) {
+	if info := requestInfoFrom(r.Context()); info != nil {
+		info.userID = userID
+	}
+}
+
+// newLogger builds the JSON logger, logging records at level and above.
+func newLogger(level slog.Level) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})})
+}
+
+// contextHandler adds the request and trace IDs to records logged with a
+// request's context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if info := requestInfoFrom(ctx); info != nil {
+		rec.AddAttrs(slog.String("request_id", info.id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		rec.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// withMiddleware wraps the mux in the chain every request passes through.
+func withMiddleware(h http.Handler, m *metrics.Metrics) http.Handler {
+	return requestID(traceRequests(accessLog(instrument(m, recoverPanics(h)))))
+}
+
+// requestID tags the request with the caller's X-Request-ID, or a new one
+// when it is missing or unusable, and echoes it on the response.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+			r.Header.Set(requestIDHeader, id)
+		}
+		w.Header().Set(requestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestInfoKey{}, &requestInfo{id: id})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID accepts IDs short enough and plain enough to be safe to log
+// and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// statusRecorder remembers the status and size of a response. It is shared by
+// every middleware that needs them; see recordStatus.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// recordStatus returns w's recorder, wrapping w in one if an outer middleware
+// has not already.
+func recordStatus(w http.ResponseWriter) *statusRecorder {
+	if rec, ok := w.(*statusRecorder); ok {
+		return rec
+	}
+	return &statusRecorder{ResponseWriter: w}
+}
+
+// Status is the response status, 200 if the handler never set one.
+func (rec *statusRecorder) Status() int {
+	if rec.status == 0 {
+		return 200
+	}
+	return rec.status
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = 200
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, which the
+// chirp stream needs to flush.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Hijack hands the connection to the WebSocket upgrader, which asserts
+// http.Hijacker directly instead of going through Unwrap. A hijacked
+// connection is recorded as 101 Switching Protocols.
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(rec.ResponseWriter).Hijack()
+	if err == nil && rec.status == 0 {
+		rec.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// accessLog logs every request once it has been served, including ones
+// aborted by a panic. Server errors are logged at error level so they survive
+// LOG_LEVEL=error.
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := recordStatus(w)
+		defer logRequest(r, rec, start)
+		next.ServeHTTP(rec, r)
+	})
+}
+
+func logRequest(r *http.Request, rec *statusRecorder, start time.Time) {
+	status := rec.Status()
+	level := slog.LevelInfo
+	if status >= 500 {
+		level = slog.LevelError
+	}
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", status),
+		slog.Int64("bytes", rec.bytes),
+		slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		slog.String("remote_addr", r.RemoteAddr),
+		slog.String("user_agent", r.UserAgent()),
+	}
+	if info := requestInfoFrom(r.Context()); info != nil && info.userID != uuid.Nil {
+		attrs = append(attrs, slog.String("user_id", info.userID.String()))
+	}
+	slog.LogAttrs(r.Context(), level, "request", attrs...)
+}
+
+// traceRequests starts a server span for every request, continuing the
+// caller's trace when it sends W3C trace context. The span is renamed after
+// the matched route once the mux has picked one.
+func traceRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := httpTracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+		r = r.WithContext(ctx)
+		rec := recordStatus(w)
+		defer func() {
+			if r.Pattern != "" {
+				span.SetName(r.Pattern)
+				span.SetAttributes(semconv.HTTPRoute(r.Pattern))
+			}
+			status := rec.Status()
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// instrument records request counts and latencies per route pattern. The mux
+// fills in r.Pattern on the request it is handed, which is this one.
+func instrument(m *metrics.Metrics, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		done := m.RequestStarted()
+		rec := recordStatus(w)
+		defer func() { done(r.Pattern, r.Method, rec.Status()) }()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// recoverPanics turns a panicking handler into a logged 500. If the handler
+// had already started its response, the connection is aborted instead so the
+// client does not mistake a truncated response for a complete one.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			slog.ErrorContext(r.Context(), "panic serving request", "panic", v, "stack", string(debug.Stack()))
+			if rec, ok := w.(*statusRecorder); ok && rec.status != 0 {
+				panic(http.ErrAbortHandler)
+			}
+			problem.Write(w, r, 500, problem.CodeInternal, "")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unmatchedRouteProblems makes the mux's own 404 and 405 replies, which are
+// plain text, problem details like every other error. Only requests that
+// match no pattern are touched, so handlers' responses pass through as sent.
+func unmatchedRouteProblems(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern == "" {
+			w = &routeProblemWriter{ResponseWriter: w, r: r}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routeProblemWriter replaces a 404 or 405 response with a problem and drops
+// the body the mux writes after it. Other statuses, such as the mux's
+// redirects or a 429, are written unchanged.
+type routeProblemWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	replaced bool
+}
+
+func (w *routeProblemWriter) WriteHeader(status int) {
+	switch status {
+	case 404:
+		w.replaced = true
+		problem.Write(w.ResponseWriter, w.r, 404, problem.CodeNotFound, "No route matches "+w.r.URL.Path)
+	case 405:
+		w.replaced = true
+		problem.Write(w.ResponseWriter, w.r, 405, problem.CodeMethodNotAllowed, w.r.Method+" is not allowed on "+w.r.URL.Path)
+	default:
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *routeProblemWriter) Write(b []byte) (int, error) {
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *routeProblemWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *routeProblemWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
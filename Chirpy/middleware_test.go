@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	"Chirpy/internal/metrics"
+	"Chirpy/internal/problem"
+)
+
+// newTestServer serves mux through the same middleware chain as runServe,
+// minus the rate limiter.
+func newTestServer(t *testing.T, mux *http.ServeMux) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(withMiddleware(unmatchedRouteProblems(mux, mux), metrics.New(nil)))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMiddlewareWebSocketUpgrade(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+	})
+	srv := newTestServer(t, mux)
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("dial: %v (status %d)", err, status)
+	}
+	defer conn.Close()
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(msg) != "hello" {
+		t.Errorf("got message %q, want hello", msg)
+	}
+}
+
+func TestMiddlewareUnmatchedRouteProblems(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/thing", func(w http.ResponseWriter, r *http.Request) {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Thing not found")
+	})
+	srv := newTestServer(t, mux)
+
+	tests := []struct {
+		method, path string
+		status       int
+		code         string
+		allow        string
+	}{
+		{"GET", "/nope", 404, problem.CodeNotFound, ""},
+		{"DELETE", "/api/thing", 405, problem.CodeMethodNotAllowed, "GET, HEAD"},
+		{"GET", "/api/thing", 404, problem.CodeNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != problem.ContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+			}
+			if allow := resp.Header.Get("Allow"); allow != tt.allow {
+				t.Errorf("Allow = %q, want %q", allow, tt.allow)
+			}
+			var p problem.Problem
+			err = json.NewDecoder(resp.Body).Decode(&p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Code != tt.code {
+				t.Errorf("code = %q, want %q", p.Code, tt.code)
+			}
+		})
+	}
+}
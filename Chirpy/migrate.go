@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"Chirpy/internal/config"
+	"Chirpy/internal/health"
+	"Chirpy/internal/migrations"
+	"Chirpy/internal/storage"
+)
+
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql sql/sqlite/queries/*.sql
+var schemaFiles embed.FS
+
+// newMigrator returns a migrator for the embedded migrations of db's driver:
+// sql/schema on Postgres, sql/sqlite/schema on SQLite.
+func newMigrator(db *storage.DB) (*migrations.Migrator, error) {
+	if db.Driver == storage.JSON {
+		return nil, errors.New("the json driver has no schema to migrate")
+	}
+	dir := "sql/schema"
+	if db.Driver == storage.SQLite {
+		dir = "sql/sqlite/schema"
+	}
+	fsys, err := fs.Sub(schemaFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+	return migrations.New(db.DB, db.Driver, fsys)
+}
+
+// openDB opens the database with the configured pool limits and waits for it
+// to answer.
+func openDB(ctx context.Context, c config.Database) (*storage.DB, error) {
+	queries, err := fs.Sub(schemaFiles, "sql/sqlite/queries")
+	if err != nil {
+		return nil, err
+	}
+	db, err := storage.Open(c.Driver, c.URL, queries)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	if db.Driver == storage.JSON {
+		return db, nil
+	}
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	err = health.WaitForDB(ctx, db.DB, c.ConnectTimeout)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// runMigrate implements "chirpy migrate up|down|status".
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) != 1 || (args[0] != "up" && args[0] != "down" && args[0] != "status") {
+		return errors.New("usage: chirpy migrate up|down|status")
+	}
+	db, err := openDB(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	default:
+		return migrator.Status(ctx, os.Stdout)
+	}
+}
+
+// prepareDB runs prepareSchema on db's migrations and returns the schema
+// version this binary expects. The JSON driver has no schema.
+func prepareDB(ctx context.Context, db *storage.DB, autoMigrate bool) (int64, error) {
+	if db.Driver == storage.JSON {
+		return 0, nil
+	}
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return 0, err
+	}
+	err = prepareSchema(ctx, migrator, autoMigrate)
+	if err != nil {
+		return 0, err
+	}
+	return migrator.Latest(), nil
+}
+
+// prepareSchema migrates the database when DB_AUTO_MIGRATE is set, then
+// checks it is at the version the code expects.
+func prepareSchema(ctx context.Context, migrator *migrations.Migrator, autoMigrate bool) error {
+	if autoMigrate {
+		err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("auto-migrate: %w", err)
+		}
+	}
+	err := migrator.Check(ctx)
+	if errors.Is(err, migrations.ErrSchemaBehind) {
+		return fmt.Errorf("%w; run \"chirpy migrate up\" or set DB_AUTO_MIGRATE=true", err)
+	}
+	return err
+}
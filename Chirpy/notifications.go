@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/notifications"
+	"Chirpy/internal/problem"
+)
+
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Type      string     `json:"type"`
+	ActorID   uuid.UUID  `json:"actor_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id,omitempty"`
+	Read      bool       `json:"read"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+type NotificationPreferences struct {
+	Mentions bool `json:"mentions"`
+	Replies  bool `json:"replies"`
+	Likes    bool `json:"likes"`
+}
+
+func mapNotification(n database.Notification) Notification {
+	res := Notification{
+		ID:        n.ID,
+		CreatedAt: n.CreatedAt,
+		Type:      n.Type,
+		ActorID:   n.ActorID,
+		Read:      n.ReadAt.Valid,
+	}
+	if n.ChirpID.Valid {
+		res.ChirpID = &n.ChirpID.UUID
+	}
+	if n.ReadAt.Valid {
+		res.ReadAt = &n.ReadAt.Time
+	}
+	return res
+}
+
+// notify records a notification, logging rather than surfacing failures so
+// they never fail the triggering request.
+func (cfg *apiConfig) notify(ctx context.Context, typ string, recipient, actor, chirpID uuid.UUID) {
+	if cfg.notifier == nil {
+		return
+	}
+	err := cfg.notifier.Notify(ctx, typ, recipient, actor, chirpID)
+	if err != nil {
+		slog.ErrorContext(ctx, "notifications: notify", "type", typ, "err", err)
+	}
+}
+
+// notifyChirpCreated notifies the author of the chirp being replied to and
+// every user mentioned in the body.
+func (cfg *apiConfig) notifyChirpCreated(ctx context.Context, chirp database.Chirp) {
+	if chirp.ReplyToID.Valid {
+		parent, err := cfg.dbQueries.ListChirp(ctx, chirp.ReplyToID.UUID)
+		if err == nil {
+			cfg.notify(ctx, notifications.TypeReply, parent.UserID, chirp.UserID, chirp.ID)
+		}
+	}
+	for _, mention := range notifications.Mentions(chirp.Body) {
+		user, err := cfg.dbQueries.GetUserByHandle(ctx, mention)
+		if err != nil {
+			continue
+		}
+		cfg.notify(ctx, notifications.TypeMention, user.ID, chirp.UserID, chirp.ID)
+	}
+}
+
+// pageParams reads limit and offset query parameters, writing a 400 itself
+// when either is invalid.
+func pageParams(w http.ResponseWriter, r *http.Request, defaultLimit, maxLimit int) (int32, int32, bool) {
+	query := r.URL.Query()
+	limit := defaultLimit
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxLimit {
+			respondWithError(w, r, 400, problem.CodeInvalidQuery, fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+			return 0, 0, false
+		}
+		limit = n
+	}
+	offset := 0
+	if o := query.Get("offset"); o != "" {
+		n, err := strconv.Atoi(o)
+		if err != nil || n < 0 {
+			respondWithError(w, r, 400, problem.CodeInvalidQuery, "offset must be a non-negative integer")
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return int32(limit), int32(offset), true
+}
+
+func (cfg *apiConfig) getNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pageParams(w, r, 20, 100)
+	if !ok {
+		return
+	}
+
+	var rows []database.Notification
+	var err error
+	if r.URL.Query().Get("unread") == "true" {
+		rows, err = cfg.dbQueries.ListUnreadNotificationsByUserID(r.Context(), database.ListUnreadNotificationsByUserIDParams{UserID: userID, Limit: limit, Offset: offset})
+	} else {
+		rows, err = cfg.dbQueries.ListNotificationsByUserID(r.Context(), database.ListNotificationsByUserIDParams{UserID: userID, Limit: limit, Offset: offset})
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	unread, err := cfg.dbQueries.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+
+	type response struct {
+		UnreadCount   int64          `json:"unread_count"`
+		Limit         int32          `json:"limit"`
+		Offset        int32          `json:"offset"`
+		Notifications []Notification `json:"notifications"`
+	}
+	res := response{
+		UnreadCount:   unread,
+		Limit:         limit,
+		Offset:        offset,
+		Notifications: []Notification{},
+	}
+	for _, n := range rows {
+		res.Notifications = append(res.Notifications, mapNotification(n))
+	}
+	respondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) markNotificationRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathUUID(w, r, "notificationID")
+	if !ok {
+		return
+	}
+	n, err := cfg.dbQueries.GetNotification(r.Context(), id)
+	if err != nil || n.UserID != userID {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Notification not found")
+		return
+	}
+	err = cfg.dbQueries.MarkNotificationRead(r.Context(), n.ID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) markAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.MarkAllNotificationsRead(r.Context(), userID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) getNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	prefs, err := cfg.notifier.Preferences(r.Context(), userID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, NotificationPreferences{Mentions: prefs.Mentions, Replies: prefs.Replies, Likes: prefs.Likes})
+}
+
+func (cfg *apiConfig) updateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	current, err := cfg.notifier.Preferences(r.Context(), userID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+
+	// Fields left out of the request keep their current value.
+	params := NotificationPreferences{Mentions: current.Mentions, Replies: current.Replies, Likes: current.Likes}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	prefs, err := cfg.dbQueries.UpsertNotificationPreferences(r.Context(), database.UpsertNotificationPreferencesParams{
+		UserID:   userID,
+		Mentions: params.Mentions,
+		Replies:  params.Replies,
+		Likes:    params.Likes,
+	})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, NotificationPreferences{Mentions: prefs.Mentions, Replies: prefs.Replies, Likes: prefs.Likes})
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/media"
+	"Chirpy/internal/problem"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+)
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+// reservedHandles would clash with routes under /api/users.
+var reservedHandles = map[string]bool{
+	"me":    true,
+	"admin": true,
+	"api":   true,
+}
+
+var errInvalidHandle = errors.New("handles must be 3-30 characters of a-z, 0-9 and _")
+
+// Profile is the public view of a user. It must never include the email.
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+func (cfg *apiConfig) avatarURL(user database.User) string {
+	if !user.AvatarKey.Valid {
+		return ""
+	}
+	return cfg.media.URL(user.AvatarKey.String)
+}
+
+func (cfg *apiConfig) mapProfile(user database.User) Profile {
+	return Profile{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		Handle:      user.Handle,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   cfg.avatarURL(user),
+		IsChirpyRed: user.IsChirpyRed,
+	}
+}
+
+func normalizeHandle(handle string) (string, error) {
+	handle = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+	if !handlePattern.MatchString(handle) || reservedHandles[handle] {
+		return "", errInvalidHandle
+	}
+	return handle, nil
+}
+
+// handleFromEmail derives a default handle from the local part of an email.
+func handleFromEmail(email string) string {
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	var b strings.Builder
+	for _, r := range local {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else if b.Len() > 0 {
+			b.WriteRune('_')
+		}
+		if b.Len() >= 20 {
+			break
+		}
+	}
+	handle := strings.Trim(b.String(), "_")
+	for len(handle) < 3 {
+		handle += "_"
+	}
+	if reservedHandles[handle] {
+		handle += "_"
+	}
+	return handle
+}
+
+// availableHandle returns base, or base with a random suffix if it is taken.
+func (cfg *apiConfig) availableHandle(ctx context.Context, base string) (string, error) {
+	handle := base
+	for i := 0; i < 5; i++ {
+		_, err := cfg.dbQueries.GetUserByHandle(ctx, handle)
+		if errors.Is(err, sql.ErrNoRows) {
+			return handle, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		handle = fmt.Sprintf("%s_%04d", base, n.Int64())
+	}
+	return "", errors.New("could not find an available handle")
+}
+
+// withAuthors embeds an author summary in every chirp in res.
+func (cfg *apiConfig) withAuthors(ctx context.Context, res []database.Res) []database.Res {
+	if len(res) == 0 {
+		return res
+	}
+	seen := make(map[uuid.UUID]bool)
+	ids := []uuid.UUID{}
+	for _, c := range res {
+		if !seen[c.UserID] {
+			seen[c.UserID] = true
+			ids = append(ids, c.UserID)
+		}
+	}
+	users, err := cfg.dbQueries.ListUsersByIDs(ctx, ids)
+	if err != nil {
+		return res
+	}
+	authors := make(map[uuid.UUID]*database.ResAuthor)
+	for _, u := range users {
+		authors[u.ID] = &database.ResAuthor{
+			ID:          u.ID,
+			Handle:      u.Handle,
+			DisplayName: u.DisplayName,
+			AvatarURL:   cfg.avatarURL(u),
+		}
+	}
+	for i := range res {
+		res[i].Author = authors[res[i].UserID]
+	}
+	return res
+}
+
+// chirpResponses maps chirps to their JSON form with attachments, authors,
+// rechirp counts and the chirps they rechirp or quote.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, chirps []database.Chirp) []database.Res {
+	res := cfg.decorateChirps(ctx, database.MapSqlChirpsToJsonChirps(chirps))
+	return cfg.withOriginals(ctx, res)
+}
+
+func (cfg *apiConfig) decorateChirps(ctx context.Context, res []database.Res) []database.Res {
+	return cfg.withRechirpCounts(ctx, cfg.withAuthors(ctx, cfg.withAttachments(ctx, res)))
+}
+
+func (cfg *apiConfig) getProfile(w http.ResponseWriter, r *http.Request) {
+	handle := strings.ToLower(strings.TrimPrefix(r.PathValue("handle"), "@"))
+	user, err := cfg.dbQueries.GetUserByHandle(r.Context(), handle)
+	if err != nil {
+		respondWithError(w, r, 404, problem.CodeNotFound, "User not found")
+		return
+	}
+	respondWithJSON(w, 200, cfg.mapProfile(user))
+}
+
+func (cfg *apiConfig) updateProfile(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+	}
+
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "User no longer exists")
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	// Fields left out of the request keep their current value.
+	update := database.UpdateUserProfileParams{
+		Handle:      user.Handle,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		ID:          user.ID,
+	}
+	if params.Handle != nil {
+		handle, err := normalizeHandle(*params.Handle)
+		if err != nil {
+			respondWithError(w, r, 400, problem.CodeValidationFailed, err.Error())
+			return
+		}
+		if handle != user.Handle {
+			_, err = cfg.dbQueries.GetUserByHandle(r.Context(), handle)
+			if err == nil {
+				respondWithError(w, r, 409, problem.CodeConflict, "Handle is already taken")
+				return
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				respondWithInternalError(w, r, err)
+				return
+			}
+		}
+		update.Handle = handle
+	}
+	if params.DisplayName != nil {
+		if utf8.RuneCountInString(*params.DisplayName) > maxDisplayNameLength {
+			respondWithError(w, r, 400, problem.CodeValidationFailed, "Display name is too long")
+			return
+		}
+		update.DisplayName = strings.TrimSpace(*params.DisplayName)
+	}
+	if params.Bio != nil {
+		if utf8.RuneCountInString(*params.Bio) > maxBioLength {
+			respondWithError(w, r, 400, problem.CodeValidationFailed, "Bio is too long")
+			return
+		}
+		update.Bio = strings.TrimSpace(*params.Bio)
+	}
+
+	user, err = cfg.dbQueries.UpdateUserProfile(r.Context(), update)
+	// The check above can race with another request claiming the handle.
+	if isUniqueViolation(err) {
+		respondWithError(w, r, 409, problem.CodeConflict, "Handle is already taken")
+		return
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, cfg.mapProfile(user))
+}
+
+func (cfg *apiConfig) updateAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "User no longer exists")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, media.MaxUploadBytes+1<<20)
+	file, _, err := r.FormFile("avatar")
+	if errors.Is(err, http.ErrMissingFile) || errors.Is(err, http.ErrNotMultipart) {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, `Send the image as multipart/form-data in an "avatar" field`)
+		return
+	}
+	if err != nil {
+		respondWithAttachmentError(w, r, err)
+		return
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		respondWithAttachmentError(w, r, err)
+		return
+	}
+	p, err := media.Process(data)
+	if err != nil {
+		respondWithAttachmentError(w, r, err)
+		return
+	}
+
+	// Avatars are shown small, so the thumbnail is all that is kept.
+	key := "avatars/" + uuid.New().String() + ".jpg"
+	err = cfg.media.Put(r.Context(), key, media.ThumbnailContentType, p.Thumbnail)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	previous := user.AvatarKey
+	user, err = cfg.dbQueries.UpdateUserAvatar(r.Context(), database.UpdateUserAvatarParams{
+		AvatarKey: sql.NullString{String: key, Valid: true},
+		ID:        user.ID,
+	})
+	if err != nil {
+		cfg.deleteMedia(r.Context(), key)
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if previous.Valid {
+		cfg.deleteMedia(r.Context(), previous.String)
+	}
+
+	respondWithJSON(w, 200, cfg.mapProfile(user))
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"Chirpy/internal/auth"
+	"Chirpy/internal/config"
+	"Chirpy/internal/database"
+	"Chirpy/internal/entitlements"
+	"Chirpy/internal/problem"
+	"Chirpy/internal/ratelimit"
+)
+
+// defaultRouteLimits limit the routes worth abusing, keyed by mux pattern.
+// They apply to free and anonymous callers and are scaled up for higher
+// tiers. RATE_LIMITS overrides them.
+var defaultRouteLimits = map[string]ratelimit.Limit{
+	"POST /api/login":   ratelimit.Every(10, time.Minute),
+	"POST /api/users":   ratelimit.Every(5, time.Minute),
+	"POST /api/refresh": ratelimit.Every(30, time.Minute),
+	"POST /api/chirps":  ratelimit.Every(30, time.Minute),
+	"POST /api/conversations/{conversationID}/messages": ratelimit.Every(60, time.Minute),
+	"POST /api/polka/webhooks":                          ratelimit.Every(120, time.Minute),
+}
+
+// rateLimitExempt routes are never limited: probes, scrapes and static files.
+var rateLimitExempt = map[string]bool{
+	"GET /api/healthz": true,
+	"GET /api/readyz":  true,
+	"GET /metrics":     true,
+	"/app/":            true,
+}
+
+// rateLimitRouteOnly routes skip the tier's global bucket and are limited by
+// their route limit alone. Polka retries bursts of webhooks under one key,
+// more than the free tier's global allowance.
+var rateLimitRouteOnly = map[string]bool{
+	"POST /api/polka/webhooks": true,
+}
+
+// newRateLimitStore returns the bucket store named by kind: memory, or
+// postgres to share limits between instances. "off" disables rate limiting.
+func newRateLimitStore(db database.Querier, kind string) ratelimit.Store {
+	switch kind {
+	case "postgres":
+		return ratelimit.NewPostgresStore(db)
+	case "off":
+		return nil
+	default:
+		return ratelimit.NewMemoryStore()
+	}
+}
+
+type rateLimitCheck struct {
+	key   string
+	limit ratelimit.Limit
+}
+
+type rateLimiter struct {
+	store  ratelimit.Store
+	routes map[string]ratelimit.Limit
+	// trustForwardedFor takes the client IP from the last X-Forwarded-For
+	// hop, which is only safe behind a proxy that sets it.
+	trustForwardedFor bool
+}
+
+// newRateLimiter applies the configured route limits over the defaults.
+func newRateLimiter(store ratelimit.Store, c config.RateLimit) *rateLimiter {
+	routes := make(map[string]ratelimit.Limit, len(defaultRouteLimits))
+	for pattern, limit := range defaultRouteLimits {
+		routes[pattern] = limit
+	}
+	for pattern, limit := range c.Routes {
+		routes[pattern] = limit
+	}
+	return &rateLimiter{
+		store:             store,
+		routes:            routes,
+		trustForwardedFor: c.TrustForwardedFor,
+	}
+}
+
+// limitRequests applies two buckets to every request: the caller's overall
+// allowance from their tier, and the limit of the route they hit, if it has
+// one. Limits fail open: a store error is logged and the request served.
+func (cfg *apiConfig) limitRequests(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if cfg.limiter == nil || rateLimitExempt[pattern] {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		identity, caps := cfg.rateLimitIdentity(r)
+		var checks []rateLimitCheck
+		if !rateLimitRouteOnly[pattern] {
+			checks = append(checks, rateLimitCheck{"global:" + identity, ratelimit.Every(caps.RequestsPerMinute, time.Minute)})
+		}
+		if limit, ok := cfg.limiter.routes[pattern]; ok {
+			checks = append(checks, rateLimitCheck{"route:" + pattern + ":" + identity, limit.Scale(caps.RateLimitMultiplier)})
+		}
+
+		var tightest *ratelimit.Result
+		for _, c := range checks {
+			res, err := ratelimit.Take(r.Context(), cfg.limiter.store, c.key, c.limit)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "ratelimit: take", "err", err)
+				continue
+			}
+			if tightest == nil || !res.Allowed || (tightest.Allowed && res.Remaining < tightest.Remaining) {
+				tightest = &res
+			}
+			if !res.Allowed {
+				break
+			}
+		}
+		if tightest == nil {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		setRateLimitHeaders(w.Header(), *tightest)
+		if !tightest.Allowed {
+			retryAfter := ceilSeconds(tightest.RetryAfter)
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			respondWithError(w, r, 429, problem.CodeRateLimited, fmt.Sprintf("Rate limit exceeded; retry in %d seconds", retryAfter))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// setRateLimitHeaders describes res with the RateLimit header fields of the
+// IETF httpapi draft.
+func setRateLimitHeaders(h http.Header, res ratelimit.Result) {
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit.Burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Limit.Burst, ceilSeconds(res.Limit.Window())))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// rateLimitIdentity names who a request counts against: the user of a valid
+// access token, then the holder of the Polka API key, then the client IP.
+// Users get their tier's capabilities; everyone else gets the free tier's.
+// Other API keys count as the client IP, or a caller could take a fresh
+// bucket with every request by varying the key.
+func (cfg *apiConfig) rateLimitIdentity(r *http.Request) (string, entitlements.Capabilities) {
+	free := entitlements.ForTier(entitlements.TierFree)
+	if token, err := auth.GetBearerToken(r.Header); err == nil {
+		if userID, err := auth.ValidateJWT(r.Context(), token, []byte(cfg.token)); err == nil {
+			caps, err := cfg.entitlementsFor(r.Context(), userID)
+			if err != nil {
+				caps = free
+			}
+			return "user:" + userID.String(), caps
+		}
+	}
+	if key, err := auth.GetAPIKey(r.Header); err == nil && cfg.polkaKey != "" &&
+		subtle.ConstantTimeCompare([]byte(key), []byte(cfg.polkaKey)) == 1 {
+		// Keys are hashed so bucket keys never hold a usable secret.
+		sum := sha256.Sum256([]byte(key))
+		return "apikey:" + hex.EncodeToString(sum[:8]), free
+	}
+	return "ip:" + cfg.limiter.clientIP(r), free
+}
+
+func (l *rateLimiter) clientIP(r *http.Request) string {
+	if l.trustForwardedFor {
+		if hops := r.Header.Values("X-Forwarded-For"); len(hops) > 0 {
+			last := hops[len(hops)-1]
+			if i := strings.LastIndex(last, ","); i >= 0 {
+				last = last[i+1:]
+			}
+			if ip := strings.TrimSpace(last); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/problem"
+	"Chirpy/internal/scheduler"
+	"Chirpy/internal/stream"
+	"Chirpy/internal/webhooks"
+)
+
+// chirpDeleted announces that chirp is gone to webhook subscribers and
+// stream clients.
+func (cfg *apiConfig) chirpDeleted(ctx context.Context, chirp database.Chirp) {
+	cfg.publishWebhook(ctx, webhooks.EventChirpDeleted, webhooks.ChirpData{ID: chirp.ID, UserID: chirp.UserID})
+	cfg.publishChirpEvent(ctx, stream.EventChirpDeleted, chirp)
+}
+
+// originalChirp resolves a rechirp to the chirp it shares, so rechirping or
+// quoting a rechirp always points at the original.
+func (cfg *apiConfig) originalChirp(ctx context.Context, chirp database.Chirp) (database.Chirp, error) {
+	if !chirp.RechirpOfID.Valid {
+		return chirp, nil
+	}
+	return cfg.dbQueries.ListChirp(ctx, chirp.RechirpOfID.UUID)
+}
+
+// withRechirpCounts fills in the rechirp count of every chirp in res.
+func (cfg *apiConfig) withRechirpCounts(ctx context.Context, res []database.Res) []database.Res {
+	if len(res) == 0 {
+		return res
+	}
+	ids := make([]uuid.UUID, 0, len(res))
+	for _, c := range res {
+		ids = append(ids, c.ID)
+	}
+	counts, err := cfg.dbQueries.CountRechirpsByChirpIDs(ctx, ids)
+	if err != nil {
+		slog.ErrorContext(ctx, "rechirps: count", "err", err)
+		return res
+	}
+	byChirp := make(map[uuid.UUID]int64)
+	for _, c := range counts {
+		byChirp[c.RechirpOfID.UUID] = c.RechirpCount
+	}
+	for i := range res {
+		res[i].RechirpCount = byChirp[res[i].ID]
+	}
+	return res
+}
+
+// withOriginals embeds the rechirped or quoted chirp in every chirp in res.
+// A quote whose original was deleted has no quote_of_id and is left as is.
+func (cfg *apiConfig) withOriginals(ctx context.Context, res []database.Res) []database.Res {
+	var ids []uuid.UUID
+	for _, c := range res {
+		if c.RechirpOfID != nil {
+			ids = append(ids, *c.RechirpOfID)
+		} else if c.QuoteOfID != nil {
+			ids = append(ids, *c.QuoteOfID)
+		}
+	}
+	if len(ids) == 0 {
+		return res
+	}
+	chirps, err := cfg.dbQueries.ListChirpsByIDs(ctx, ids)
+	if err != nil {
+		slog.ErrorContext(ctx, "rechirps: list originals", "err", err)
+		return res
+	}
+	originals := make(map[uuid.UUID]database.Res)
+	for _, o := range cfg.decorateChirps(ctx, database.MapSqlChirpsToJsonChirps(chirps)) {
+		originals[o.ID] = o
+	}
+	for i := range res {
+		id := res[i].QuoteOfID
+		if res[i].RechirpOfID != nil {
+			id = res[i].RechirpOfID
+		}
+		if id == nil {
+			continue
+		}
+		if o, ok := originals[*id]; ok {
+			res[i].Original = &o
+		}
+	}
+	return res
+}
+
+func (cfg *apiConfig) rechirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+	chirp, err := cfg.dbQueries.ListChirp(r.Context(), id)
+	if err == nil {
+		chirp, err = cfg.originalChirp(r.Context(), chirp)
+	}
+	if err != nil || chirp.Status != scheduler.StatusPublished {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Chirp not found")
+		return
+	}
+	blocked, err := cfg.dbQueries.IsBlocked(r.Context(), database.IsBlockedParams{BlockerID: chirp.UserID, BlockedID: userID})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if blocked {
+		respondWithError(w, r, 403, problem.CodeForbidden, "You cannot rechirp this chirp")
+		return
+	}
+
+	original := uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	status := 201
+	rechirp, err := cfg.dbQueries.CreateRechirp(r.Context(), database.CreateRechirpParams{UserID: userID, RechirpOfID: original})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Already rechirped; hand back the existing rechirp.
+		status = 200
+		rechirp, err = cfg.dbQueries.GetRechirp(r.Context(), database.GetRechirpParams{UserID: userID, RechirpOfID: original})
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if status == 201 {
+		cfg.chirpPublished(r.Context(), rechirp)
+	}
+
+	respondWithJSON(w, status, cfg.chirpResponses(r.Context(), []database.Chirp{rechirp})[0])
+}
+
+func (cfg *apiConfig) undoRechirp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+	chirp, err := cfg.dbQueries.ListChirp(r.Context(), id)
+	if err == nil {
+		chirp, err = cfg.originalChirp(r.Context(), chirp)
+	}
+	if err != nil {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Chirp not found")
+		return
+	}
+	rechirp, err := cfg.dbQueries.DeleteRechirp(r.Context(), database.DeleteRechirpParams{UserID: userID, RechirpOfID: uuid.NullUUID{UUID: chirp.ID, Valid: true}})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 404, problem.CodeNotFound, "You have not rechirped this chirp")
+		return
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	cfg.chirpDeleted(r.Context(), rechirp)
+	w.WriteHeader(204)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/auth"
+	"Chirpy/internal/database"
+	"Chirpy/internal/metrics"
+	"Chirpy/internal/problem"
+	"Chirpy/internal/storage"
+)
+
+func respondWithJSON(w http.ResponseWriter, status int, payload any) {
+	dat, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("encode response", "err", err)
+		problem.Write(w, nil, 500, problem.CodeInternal, "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(dat)
+}
+
+func respondWithError(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	problem.Write(w, r, status, code, detail)
+}
+
+// respondWithInternalError logs err and sends a 500 that does not reveal it,
+// or a 501 when the JSON database driver cannot serve the request.
+func respondWithInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, database.ErrUnsupported) || errors.Is(err, storage.ErrNoTransactions) {
+		slog.WarnContext(r.Context(), "not supported by the database driver", "method", r.Method, "path", r.URL.Path, "err", err)
+		problem.Write(w, r, 501, problem.CodeNotImplemented, "Not available with the json database driver")
+		return
+	}
+	slog.ErrorContext(r.Context(), "internal error", "method", r.Method, "path", r.URL.Path, "err", err)
+	problem.Write(w, r, 500, problem.CodeInternal, "")
+}
+
+// authenticate returns the user the request's access token belongs to,
+// writing a 401 itself when there is no valid token and a 403 when the
+// account is disabled.
+func (cfg *apiConfig) authenticate(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	authToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		cfg.metrics.AuthFailure(metrics.ReasonMissingToken)
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "Missing bearer token")
+		return uuid.Nil, false
+	}
+	userID, err := auth.ValidateJWT(r.Context(), authToken, []byte(cfg.token))
+	if err != nil {
+		cfg.metrics.AuthFailure(metrics.ReasonInvalidToken)
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "Invalid or expired access token")
+		return uuid.Nil, false
+	}
+	// Access tokens outlive a disable, so the account is checked each time.
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.metrics.AuthFailure(metrics.ReasonInvalidToken)
+		respondWithError(w, r, 401, problem.CodeUnauthorized, "Invalid or expired access token")
+		return uuid.Nil, false
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return uuid.Nil, false
+	}
+	if user.DisabledAt.Valid {
+		cfg.metrics.AuthFailure(metrics.ReasonAccountDisabled)
+		respondWithError(w, r, 403, problem.CodeForbidden, "Account is disabled")
+		return uuid.Nil, false
+	}
+	setRequestUser(r, userID)
+	return userID, true
+}
+
+// decodeJSON decodes the request body into v, writing a 400 when it is not
+// valid JSON for v.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, r, 413, problem.CodePayloadTooLarge, "Request body is too large")
+			return false
+		}
+		respondWithError(w, r, 400, problem.CodeInvalidJSON, "Request body is not valid JSON")
+		return false
+	}
+	return true
+}
+
+// pathUUID parses the named path value, writing a 400 when it is not a UUID.
+func pathUUID(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue(name))
+	if err != nil {
+		respondWithError(w, r, 400, problem.CodeInvalidID, name+" must be a UUID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/problem"
+	"Chirpy/internal/scheduler"
+	"Chirpy/internal/stream"
+	"Chirpy/internal/webhooks"
+)
+
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+// chirpPublished runs the side effects of a chirp becoming visible, either
+// straight away in createChirp or later from the scheduler.
+func (cfg *apiConfig) chirpPublished(ctx context.Context, chirp database.Chirp) {
+	cfg.notifyChirpCreated(ctx, chirp)
+	cfg.publishWebhook(ctx, webhooks.EventChirpCreated, webhooks.ChirpData{ID: chirp.ID, UserID: chirp.UserID, Body: chirp.Body})
+	cfg.publishChirpEvent(ctx, stream.EventChirpCreated, chirp)
+}
+
+// ownedScheduledChirp authenticates the request and loads the pending chirp
+// in the path, writing the error response itself on failure.
+func (cfg *apiConfig) ownedScheduledChirp(w http.ResponseWriter, r *http.Request) (database.Chirp, bool) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return database.Chirp{}, false
+	}
+	id, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return database.Chirp{}, false
+	}
+	chirp, err := cfg.dbQueries.ListChirp(r.Context(), id)
+	if err != nil || chirp.UserID != userID || chirp.Status != scheduler.StatusScheduled {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Scheduled chirp not found")
+		return database.Chirp{}, false
+	}
+	return chirp, true
+}
+
+func (cfg *apiConfig) listScheduledChirps(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pageParams(w, r, 50, 200)
+	if !ok {
+		return
+	}
+	chirps, err := cfg.dbQueries.ListScheduledChirps(r.Context(), database.ListScheduledChirpsParams{UserID: userID, Limit: limit, Offset: offset})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	res := cfg.chirpResponses(r.Context(), chirps)
+	if res == nil {
+		res = []database.Res{}
+	}
+	respondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) rescheduleChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		PublishAt time.Time `json:"publish_at"`
+	}
+
+	chirp, ok := cfg.ownedScheduledChirp(w, r)
+	if !ok {
+		return
+	}
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	if params.PublishAt.IsZero() {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "publish_at is required")
+		return
+	}
+	if params.PublishAt.After(time.Now().Add(maxScheduleAhead)) {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "Chirps can be scheduled at most a year ahead")
+		return
+	}
+
+	// A time in the past publishes the chirp on the scheduler's next pass.
+	// If the scheduler claimed the chirp in the meantime no row matches.
+	updated, err := cfg.dbQueries.RescheduleChirp(r.Context(), database.RescheduleChirpParams{
+		PublishAt: sql.NullTime{Time: params.PublishAt.UTC(), Valid: true},
+		ID:        chirp.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, 409, problem.CodeConflict, "Chirp has already been published")
+		return
+	}
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, cfg.chirpResponses(r.Context(), []database.Chirp{updated})[0])
+}
+
+// cancelScheduledChirp deletes a chirp that has not been published yet.
+func (cfg *apiConfig) cancelScheduledChirp(w http.ResponseWriter, r *http.Request) {
+	chirp, ok := cfg.ownedScheduledChirp(w, r)
+	if !ok {
+		return
+	}
+	attachments, err := cfg.dbQueries.ListAttachmentsByChirpID(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	n, err := cfg.dbQueries.DeleteScheduledChirp(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, r, 409, problem.CodeConflict, "The chirp has already been published")
+		return
+	}
+	for _, a := range attachments {
+		cfg.deleteMedia(r.Context(), a.StorageKey, a.ThumbnailKey)
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"Chirpy/internal/certs"
+	"Chirpy/internal/config"
+)
+
+// newServer builds the HTTP server. A TLS certificate enables TLS, which also
+// negotiates HTTP/2; HTTP2Cleartext serves HTTP/2 without TLS instead, for use
+// behind a proxy that terminates TLS. The returned reloader is nil without
+// TLS.
+func newServer(handler http.Handler, c config.Server) (*http.Server, *certs.Reloader, error) {
+	server := &http.Server{
+		Addr:              c.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+
+	if c.TLSCertFile == "" {
+		if c.HTTP2Cleartext {
+			server.Handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: server.IdleTimeout})
+		}
+		return server, nil, nil
+	}
+	reloader, err := certs.NewReloader(c.TLSCertFile, c.TLSKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("TLS: %w", err)
+	}
+	server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	return server, reloader, nil
+}
+
+// serve runs server until ctx is cancelled, then shuts down gracefully: the
+// listener closes, in-flight requests and the workers in wg get timeout to
+// finish, and onShutdown runs at the start so long-lived
+// streams end instead of holding the shutdown up.
+func serve(ctx context.Context, server *http.Server, timeout time.Duration, wg *sync.WaitGroup, onShutdown func()) error {
+	server.RegisterOnShutdown(onShutdown)
+	errc := make(chan error, 1)
+	go func() {
+		slog.Info("server running", "addr", server.Addr, "tls", server.TLSConfig != nil)
+		if server.TLSConfig != nil {
+			// The certificate comes from TLSConfig.GetCertificate.
+			errc <- server.ListenAndServeTLS("", "")
+			return
+		}
+		errc <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", "timeout", timeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		slog.Warn("shutdown: requests still in flight were cut off", "err", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		slog.Warn("shutdown: background workers did not finish in time")
+	}
+	return nil
+}
+
+// goWorker runs a background worker with ctx and tracks it in wg, so serve
+// can wait for it to finish.
+func goWorker(ctx context.Context, wg *sync.WaitGroup, run func(context.Context)) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		run(ctx)
+	}()
+}
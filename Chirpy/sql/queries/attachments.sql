@@ -0,0 +1,27 @@
+-- name: CreateChirpAttachment :one
+INSERT INTO chirp_attachments (id, created_at, chirp_id, position, content_type, size_bytes, width, height, storage_key, thumbnail_key)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+  )
+RETURNING *;
+
+-- name: ListAttachmentsByChirpID :many
+SELECT * FROM chirp_attachments WHERE chirp_id = $1 ORDER BY position ASC;
+
+-- name: ListAttachmentsByChirpIDs :many
+SELECT * FROM chirp_attachments WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::UUID[]) ORDER BY chirp_id, position ASC;
+
+-- name: ListAttachmentsByUserID :many
+SELECT chirp_attachments.* FROM chirp_attachments
+JOIN chirps ON chirps.id = chirp_attachments.chirp_id
+WHERE chirps.user_id = $1
+ORDER BY chirp_attachments.chirp_id, chirp_attachments.position ASC;
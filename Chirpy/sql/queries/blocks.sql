@@ -0,0 +1,68 @@
+-- name: BlockUser :exec
+INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+  )
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :execrows
+DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: IsBlocked :one
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2
+  );
+
+-- name: IsBlockedEitherWay :one
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = sqlc.arg(user_a) AND blocked_id = sqlc.arg(user_b))
+       OR (blocker_id = sqlc.arg(user_b) AND blocked_id = sqlc.arg(user_a))
+  );
+
+-- name: IsSilenced :one
+SELECT (
+    EXISTS (
+      SELECT 1 FROM user_blocks
+      WHERE (blocker_id = sqlc.arg(recipient_id) AND blocked_id = sqlc.arg(actor_id))
+         OR (blocker_id = sqlc.arg(actor_id) AND blocked_id = sqlc.arg(recipient_id))
+    )
+    OR EXISTS (
+      SELECT 1 FROM user_mutes WHERE muter_id = sqlc.arg(recipient_id) AND muted_id = sqlc.arg(actor_id)
+    )
+  )::BOOLEAN AS silenced;
+
+-- name: ListBlockedUsers :many
+SELECT users.* FROM users
+JOIN user_blocks ON user_blocks.blocked_id = users.id
+WHERE user_blocks.blocker_id = $1
+ORDER BY user_blocks.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListHiddenAuthorIDs :many
+SELECT blocked_id AS user_id FROM user_blocks WHERE user_blocks.blocker_id = sqlc.arg(viewer_id)
+UNION
+SELECT blocker_id FROM user_blocks WHERE user_blocks.blocked_id = sqlc.arg(viewer_id)
+UNION
+SELECT muted_id FROM user_mutes WHERE muter_id = sqlc.arg(viewer_id);
+
+-- name: MuteUser :exec
+INSERT INTO user_mutes (muter_id, muted_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+  )
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :execrows
+DELETE FROM user_mutes WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: ListMutedUsers :many
+SELECT users.* FROM users
+JOIN user_mutes ON user_mutes.muted_id = users.id
+WHERE user_mutes.muter_id = $1
+ORDER BY user_mutes.created_at DESC
+LIMIT $2 OFFSET $3;
@@ -0,0 +1,57 @@
+-- name: CreateBookmarkCollection :one
+INSERT INTO bookmark_collections (id, created_at, updated_at, user_id, name)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+  )
+ON CONFLICT (user_id, name) DO NOTHING
+RETURNING *;
+
+-- name: GetBookmarkCollection :one
+SELECT * FROM bookmark_collections WHERE id = $1 AND user_id = $2;
+
+-- name: ListBookmarkCollections :many
+SELECT bookmark_collections.*, COUNT(bookmarks.id) AS bookmark_count
+FROM bookmark_collections
+LEFT JOIN bookmarks ON bookmarks.collection_id = bookmark_collections.id
+WHERE bookmark_collections.user_id = $1
+GROUP BY bookmark_collections.id
+ORDER BY bookmark_collections.name ASC;
+
+-- name: RenameBookmarkCollection :one
+UPDATE bookmark_collections SET name = $1, updated_at = NOW() WHERE id = $2 AND user_id = $3 RETURNING *;
+
+-- name: DeleteBookmarkCollection :execrows
+DELETE FROM bookmark_collections WHERE id = $1 AND user_id = $2;
+
+-- name: UpsertBookmark :one
+INSERT INTO bookmarks (id, created_at, user_id, chirp_id, collection_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+  )
+ON CONFLICT (user_id, chirp_id) DO UPDATE SET collection_id = EXCLUDED.collection_id
+RETURNING *;
+
+-- name: DeleteBookmark :execrows
+DELETE FROM bookmarks WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: ListBookmarks :many
+SELECT bookmarks.* FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+WHERE bookmarks.user_id = $1 AND chirps.deleted_at IS NULL
+ORDER BY bookmarks.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListBookmarksInCollection :many
+SELECT bookmarks.* FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+WHERE bookmarks.user_id = $1 AND bookmarks.collection_id = $2 AND chirps.deleted_at IS NULL
+ORDER BY bookmarks.created_at DESC
+LIMIT $3 OFFSET $4;
@@ -0,0 +1,22 @@
+-- name: CreateChirpEvent :one
+INSERT INTO chirp_events (created_at, type, chirp_id, user_id, payload)
+VALUES (
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+  )
+RETURNING *;
+
+-- name: ListChirpEventsSince :many
+SELECT * FROM chirp_events WHERE id > $1 ORDER BY id ASC LIMIT $2;
+
+-- name: ListChirpEventsSinceByAuthorID :many
+SELECT * FROM chirp_events WHERE id > $1 AND user_id = $2 ORDER BY id ASC LIMIT $3;
+
+-- name: NotifyChirpEvent :exec
+SELECT pg_notify('chirp_events', sqlc.arg(payload)::TEXT);
+
+-- name: DeleteChirpEventsOlderThan :exec
+DELETE FROM chirp_events WHERE created_at < NOW() - make_interval(secs => sqlc.arg(retention_seconds)::float8);
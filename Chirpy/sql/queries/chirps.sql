@@ -1,31 +1,131 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to_id, quote_of_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
+    $2,
+    $3,
+    $4
+  )
+RETURNING *;
+
+-- name: CreateScheduledChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to_id, quote_of_id, publish_at, status)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    'scheduled'
+  )
+RETURNING *;
+
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
     $2
   )
+ON CONFLICT (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL AND deleted_at IS NULL DO NOTHING
 RETURNING *;
 
+-- name: GetRechirp :one
+SELECT * FROM chirps WHERE user_id = $1 AND rechirp_of_id = $2 AND deleted_at IS NULL;
+
+-- name: DeleteRechirp :one
+DELETE FROM chirps WHERE user_id = $1 AND rechirp_of_id = $2 AND deleted_at IS NULL RETURNING *;
+
+-- name: ListRechirpsOf :many
+SELECT * FROM chirps WHERE rechirp_of_id = $1 AND deleted_at IS NULL;
+
+-- name: ListChirpsByIDs :many
+SELECT * FROM chirps WHERE id = ANY(sqlc.arg(ids)::UUID[]) AND deleted_at IS NULL;
+
+-- name: CountRechirpsByChirpIDs :many
+SELECT rechirp_of_id, COUNT(*) AS rechirp_count FROM chirps
+WHERE deleted_at IS NULL AND rechirp_of_id = ANY(sqlc.arg(chirp_ids)::UUID[])
+GROUP BY rechirp_of_id;
+
 -- name: ListChirps :many
-SELECT * FROM chirps ORDER BY created_at ASC;
+SELECT * FROM chirps WHERE status = 'published' AND deleted_at IS NULL ORDER BY created_at ASC;
 
 -- name: ListChirpsDesc :many
-SELECT * FROM chirps ORDER BY created_at DESC;
+SELECT * FROM chirps WHERE status = 'published' AND deleted_at IS NULL ORDER BY created_at DESC;
 
 -- name: ListChirp :one
-SELECT * FROM chirps WHERE id = $1;
+SELECT * FROM chirps WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: ListChirpByAuthorID :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at ASC;
+SELECT * FROM chirps WHERE user_id = $1 AND status = 'published' AND deleted_at IS NULL ORDER BY created_at ASC;
 
 -- name: ListChirpByAuthorIDDesc :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at DESC;
+SELECT * FROM chirps WHERE user_id = $1 AND status = 'published' AND deleted_at IS NULL ORDER BY created_at DESC;
+
+-- name: ListScheduledChirps :many
+SELECT * FROM chirps
+WHERE user_id = $1 AND status = 'scheduled' AND deleted_at IS NULL
+ORDER BY publish_at ASC
+LIMIT $2 OFFSET $3;
+
+-- name: RescheduleChirp :one
+UPDATE chirps SET publish_at = $1, updated_at = NOW() WHERE id = $2 AND status = 'scheduled' AND deleted_at IS NULL RETURNING *;
+
+-- name: PublishDueChirps :many
+UPDATE chirps SET status = 'published', created_at = NOW(), updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM chirps
+    WHERE status = 'scheduled' AND publish_at <= NOW() AND deleted_at IS NULL
+    ORDER BY publish_at ASC
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING *;
 
 -- name: ResetChirps :exec
 DELETE FROM chirps;
 
--- name: DeleteChirpById :exec
-DELETE FROM chirps WHERE id = $1;
\ No newline at end of file
+-- name: DeleteChirpById :one
+UPDATE chirps SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING *;
+
+-- name: SoftDeleteRechirpsOf :exec
+UPDATE chirps SET deleted_at = $2 WHERE rechirp_of_id = $1 AND deleted_at IS NULL;
+
+-- name: GetDeletedChirp :one
+SELECT * FROM chirps WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: RestoreChirp :one
+UPDATE chirps SET deleted_at = NULL WHERE id = sqlc.arg(id) AND deleted_at > NOW() - make_interval(secs => sqlc.arg(window_seconds)::float8) RETURNING *;
+
+-- name: RestoreRechirpsOf :exec
+UPDATE chirps SET deleted_at = NULL WHERE rechirp_of_id = $1 AND deleted_at = $2;
+
+-- name: ListPurgeableChirpIDs :many
+SELECT id FROM chirps
+WHERE deleted_at < NOW() - make_interval(secs => sqlc.arg(retention_seconds)::float8)
+ORDER BY deleted_at ASC
+LIMIT sqlc.arg(max_rows);
+
+-- name: PurgeChirps :execrows
+DELETE FROM chirps WHERE id = ANY(sqlc.arg(ids)::UUID[]) AND deleted_at IS NOT NULL;
+
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM chirps WHERE id = $1 AND status = 'scheduled';
+
+-- name: UpdateChirpBody :one
+UPDATE chirps SET body = $1, updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL RETURNING *;
+
+-- name: ListAllChirpsByAuthorID :many
+SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at ASC;
+
+-- name: CountChirpsByAuthorID :one
+SELECT COUNT(*) FROM chirps WHERE user_id = $1;
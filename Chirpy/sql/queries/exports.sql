@@ -0,0 +1,40 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, created_at, updated_at, user_id, status)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    'pending'
+  )
+RETURNING *;
+
+-- name: GetDataExport :one
+SELECT * FROM data_exports WHERE id = $1 AND user_id = $2;
+
+-- name: GetActiveDataExport :one
+SELECT * FROM data_exports
+WHERE user_id = $1 AND status IN ('pending', 'processing')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ClaimPendingDataExports :many
+UPDATE data_exports SET status = 'processing', updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM data_exports
+    WHERE status = 'pending'
+       OR (status = 'processing' AND updated_at < NOW() - make_interval(secs => sqlc.arg(stale_after_seconds)::float8))
+    ORDER BY created_at ASC
+    LIMIT sqlc.arg(max_rows)
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING *;
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports SET status = 'ready', archive = sqlc.arg(archive), completed_at = NOW(), expires_at = NOW() + make_interval(secs => sqlc.arg(expires_in_seconds)::float8), updated_at = NOW() WHERE id = sqlc.arg(id);
+
+-- name: FailDataExport :exec
+UPDATE data_exports SET status = 'failed', error = sqlc.arg(error), completed_at = NOW(), expires_at = NOW() + make_interval(secs => sqlc.arg(expires_in_seconds)::float8), updated_at = NOW() WHERE id = sqlc.arg(id);
+
+-- name: DeleteExpiredDataExports :execrows
+DELETE FROM data_exports WHERE expires_at < NOW();
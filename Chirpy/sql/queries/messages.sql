@@ -0,0 +1,64 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, is_group)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1
+  )
+RETURNING *;
+
+-- name: GetConversation :one
+SELECT * FROM conversations WHERE id = $1;
+
+-- name: FindDirectConversation :one
+SELECT conversations.* FROM conversations
+WHERE conversations.is_group = FALSE
+  AND EXISTS (SELECT 1 FROM conversation_participants WHERE conversation_id = conversations.id AND user_id = sqlc.arg(user_a))
+  AND EXISTS (SELECT 1 FROM conversation_participants WHERE conversation_id = conversations.id AND user_id = sqlc.arg(user_b))
+LIMIT 1;
+
+-- name: ListConversationsByUserID :many
+SELECT conversations.* FROM conversations
+JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+WHERE conversation_participants.user_id = $1
+ORDER BY conversations.updated_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: TouchConversation :exec
+UPDATE conversations SET updated_at = NOW() WHERE id = $1;
+
+-- name: AddConversationParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at, last_read_at)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    null
+  );
+
+-- name: GetConversationParticipant :one
+SELECT * FROM conversation_participants WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: ListConversationParticipants :many
+SELECT * FROM conversation_participants WHERE conversation_id = $1 ORDER BY joined_at ASC;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_participants SET last_read_at = NOW() WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+  )
+RETURNING *;
+
+-- name: ListMessagesByConversationID :many
+SELECT * FROM messages WHERE conversation_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3;
+
+-- name: ListMessagesBySenderID :many
+SELECT * FROM messages WHERE sender_id = $1 ORDER BY created_at ASC;
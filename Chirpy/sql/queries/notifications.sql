@@ -0,0 +1,45 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, created_at, user_id, actor_id, type, chirp_id, read_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    null
+  )
+RETURNING *;
+
+-- name: GetNotification :one
+SELECT * FROM notifications WHERE id = $1;
+
+-- name: ListNotificationsByUserID :many
+SELECT * FROM notifications WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3;
+
+-- name: ListUnreadNotificationsByUserID :many
+SELECT * FROM notifications WHERE user_id = $1 AND read_at IS NULL ORDER BY created_at DESC LIMIT $2 OFFSET $3;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationRead :exec
+UPDATE notifications SET read_at = NOW() WHERE id = $1 AND read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: GetNotificationPreferences :one
+SELECT * FROM notification_preferences WHERE user_id = $1;
+
+-- name: UpsertNotificationPreferences :one
+INSERT INTO notification_preferences (user_id, updated_at, mentions, replies, likes)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4
+  )
+ON CONFLICT (user_id) DO UPDATE SET updated_at = NOW(), mentions = EXCLUDED.mentions, replies = EXCLUDED.replies, likes = EXCLUDED.likes
+RETURNING *;
@@ -0,0 +1,12 @@
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at)
+VALUES (sqlc.arg(key), sqlc.arg(burst)::FLOAT8 - 1, TRUE, NOW())
+ON CONFLICT (key) DO UPDATE SET
+    allowed = LEAST(sqlc.arg(burst)::FLOAT8, rate_limit_buckets.tokens + sqlc.arg(rate)::FLOAT8 * EXTRACT(EPOCH FROM NOW() - rate_limit_buckets.updated_at)) >= 1,
+    tokens = LEAST(sqlc.arg(burst)::FLOAT8, rate_limit_buckets.tokens + sqlc.arg(rate)::FLOAT8 * EXTRACT(EPOCH FROM NOW() - rate_limit_buckets.updated_at))
+        - CASE WHEN LEAST(sqlc.arg(burst)::FLOAT8, rate_limit_buckets.tokens + sqlc.arg(rate)::FLOAT8 * EXTRACT(EPOCH FROM NOW() - rate_limit_buckets.updated_at)) >= 1 THEN 1 ELSE 0 END,
+    updated_at = NOW()
+RETURNING tokens, allowed;
+
+-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets WHERE updated_at < NOW() - make_interval(secs => sqlc.arg(idle_seconds)::float8);
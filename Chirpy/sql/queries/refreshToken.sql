@@ -1,11 +1,11 @@
 -- name: CreateRefreshToken :one
 INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
 VALUES (
-    $1,
+    sqlc.arg(token),
     NOW(),
     NOW(),
-    $2,
-    NOW() + INTERVAL '60 days',
+    sqlc.arg(user_id),
+    NOW() + make_interval(secs => sqlc.arg(expires_in_seconds)::float8),
     null
 )
 RETURNING *;
@@ -17,4 +17,10 @@ SELECT * FROM refresh_tokens WHERE token = $1;
 SELECT user_id FROM refresh_tokens WHERE token = $1;
 
 -- name: UpdateRefreshToken :exec
-UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW() WHERE token = $1;
\ No newline at end of file
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW() WHERE token = $1;
+
+-- name: ListRefreshTokensByUserID :many
+SELECT * FROM refresh_tokens WHERE user_id = $1 ORDER BY created_at ASC;
+
+-- name: RevokeRefreshTokensByUserID :exec
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL;
@@ -1,22 +1,59 @@
 -- name: CreateUser :one
-INSERT INTO users (id, hashed_password, created_at, updated_at, email)
+INSERT INTO users (id, hashed_password, created_at, updated_at, email, handle)
 VALUES (
     gen_random_uuid(),
     $1,
     NOW(),
     NOW(),
-    $2
+    $2,
+    $3
   )
 RETURNING *;
 
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
 
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: GetUserByHandle :one
+SELECT * FROM users WHERE handle = $1;
+
+-- name: ListUsersByIDs :many
+SELECT * FROM users WHERE id = ANY(sqlc.arg(ids)::UUID[]);
+
 -- name: UpdateUserByID :one
 UPDATE users SET email = $1, hashed_password = $2 WHERE id = $3 RETURNING *;
 
+-- name: UpdateUserProfile :one
+UPDATE users SET handle = $1, display_name = $2, bio = $3, updated_at = NOW() WHERE id = $4 RETURNING *;
+
+-- name: UpdateUserAvatar :one
+UPDATE users SET avatar_key = $1, updated_at = NOW() WHERE id = $2 RETURNING *;
+
 -- name: UpdateUserChirpyRedByID :one
 UPDATE users SET is_chirpy_red = $1 WHERE id = $2 RETURNING *;
 
+-- name: UpdateUserAdminByID :one
+UPDATE users SET is_admin = $1, updated_at = NOW() WHERE id = $2 RETURNING *;
+
+-- name: DisableUser :one
+UPDATE users SET disabled_at = NOW(), updated_at = NOW() WHERE id = $1 AND disabled_at IS NULL RETURNING *;
+
 -- name: ResetUsers :exec
-DELETE FROM users;
\ No newline at end of file
+DELETE FROM users;
+
+-- name: RequestUserDeletion :one
+UPDATE users SET deletion_requested_at = NOW(), updated_at = NOW() WHERE id = $1 RETURNING *;
+
+-- name: CancelUserDeletion :one
+UPDATE users SET deletion_requested_at = NULL, updated_at = NOW() WHERE id = $1 AND deletion_requested_at IS NOT NULL RETURNING *;
+
+-- name: ListUsersDueForDeletion :many
+SELECT * FROM users
+WHERE deletion_requested_at < NOW() - make_interval(secs => sqlc.arg(grace_seconds)::float8)
+ORDER BY deletion_requested_at ASC
+LIMIT sqlc.arg(max_rows);
+
+-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = sqlc.arg(id) AND deletion_requested_at < NOW() - make_interval(secs => sqlc.arg(grace_seconds)::float8);
@@ -0,0 +1,6 @@
+-- +goose Up
+CREATE TABLE chirp_events (id BIGSERIAL PRIMARY KEY, created_at TIMESTAMP NOT NULL, type TEXT NOT NULL, chirp_id UUID NOT NULL, user_id UUID NOT NULL, payload JSONB NOT NULL, CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE);
+CREATE INDEX chirp_events_user_id_idx ON chirp_events (user_id, id);
+
+-- +goose Down
+DROP TABLE chirp_events;
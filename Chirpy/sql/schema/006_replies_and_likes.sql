@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN reply_to_id UUID, ADD CONSTRAINT fk_reply_to FOREIGN KEY (reply_to_id) REFERENCES chirps(id) ON DELETE SET NULL;
+CREATE TABLE chirp_likes (chirp_id UUID NOT NULL, user_id UUID NOT NULL, created_at TIMESTAMP NOT NULL, PRIMARY KEY (chirp_id, user_id), CONSTRAINT fk_chirp FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE, CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE);
+
+-- +goose Down
+DROP TABLE chirp_likes;
+ALTER TABLE chirps DROP CONSTRAINT fk_reply_to, DROP COLUMN reply_to_id;
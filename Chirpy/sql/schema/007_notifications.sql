@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE notifications (id UUID PRIMARY KEY, created_at TIMESTAMP NOT NULL, user_id UUID NOT NULL, actor_id UUID NOT NULL, type TEXT NOT NULL, chirp_id UUID, read_at TIMESTAMP, CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE, CONSTRAINT fk_actor FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE, CONSTRAINT fk_chirp FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE);
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, created_at DESC);
+CREATE TABLE notification_preferences (user_id UUID PRIMARY KEY, updated_at TIMESTAMP NOT NULL, mentions BOOLEAN NOT NULL DEFAULT TRUE, replies BOOLEAN NOT NULL DEFAULT TRUE, likes BOOLEAN NOT NULL DEFAULT TRUE, CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE);
+
+-- +goose Down
+DROP TABLE notification_preferences;
+DROP TABLE notifications;
@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE TABLE user_blocks (blocker_id UUID NOT NULL, blocked_id UUID NOT NULL, created_at TIMESTAMP NOT NULL, PRIMARY KEY (blocker_id, blocked_id), CONSTRAINT fk_blocker FOREIGN KEY (blocker_id) REFERENCES users(id) ON DELETE CASCADE, CONSTRAINT fk_blocked FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE);
+
+-- +goose Down
+DROP TABLE user_blocks;
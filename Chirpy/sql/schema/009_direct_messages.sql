@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE conversations (id UUID PRIMARY KEY, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, is_group BOOLEAN NOT NULL DEFAULT FALSE);
+CREATE TABLE conversation_participants (conversation_id UUID NOT NULL, user_id UUID NOT NULL, joined_at TIMESTAMP NOT NULL, last_read_at TIMESTAMP, PRIMARY KEY (conversation_id, user_id), CONSTRAINT fk_conversation FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE, CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE);
+CREATE INDEX conversation_participants_user_id_idx ON conversation_participants (user_id);
+CREATE TABLE messages (id UUID PRIMARY KEY, created_at TIMESTAMP NOT NULL, conversation_id UUID NOT NULL, sender_id UUID NOT NULL, body TEXT NOT NULL, CONSTRAINT fk_conversation FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE, CONSTRAINT fk_sender FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE);
+CREATE INDEX messages_conversation_id_idx ON messages (conversation_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE messages;
+DROP TABLE conversation_participants;
+DROP TABLE conversations;
@@ -0,0 +1,6 @@
+-- +goose Up
+CREATE TABLE chirp_attachments (id UUID PRIMARY KEY, created_at TIMESTAMP NOT NULL, chirp_id UUID NOT NULL, position INTEGER NOT NULL, content_type TEXT NOT NULL, size_bytes BIGINT NOT NULL, width INTEGER NOT NULL, height INTEGER NOT NULL, storage_key TEXT NOT NULL, thumbnail_key TEXT NOT NULL, CONSTRAINT fk_chirp FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE);
+CREATE INDEX chirp_attachments_chirp_id_idx ON chirp_attachments (chirp_id, position);
+
+-- +goose Down
+DROP TABLE chirp_attachments;
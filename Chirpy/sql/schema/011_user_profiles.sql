@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN handle TEXT, ADD COLUMN display_name TEXT NOT NULL DEFAULT '', ADD COLUMN bio TEXT NOT NULL DEFAULT '', ADD COLUMN avatar_key TEXT;
+UPDATE users SET handle = 'user_' || substr(replace(id::TEXT, '-', ''), 1, 12);
+ALTER TABLE users ALTER COLUMN handle SET NOT NULL, ADD CONSTRAINT users_handle_key UNIQUE (handle);
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN avatar_key, DROP COLUMN bio, DROP COLUMN display_name, DROP COLUMN handle;
@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE TABLE user_mutes (muter_id UUID NOT NULL, muted_id UUID NOT NULL, created_at TIMESTAMP NOT NULL, PRIMARY KEY (muter_id, muted_id), CONSTRAINT fk_muter FOREIGN KEY (muter_id) REFERENCES users(id) ON DELETE CASCADE, CONSTRAINT fk_muted FOREIGN KEY (muted_id) REFERENCES users(id) ON DELETE CASCADE);
+
+-- +goose Down
+DROP TABLE user_mutes;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN publish_at TIMESTAMP, ADD COLUMN status TEXT NOT NULL DEFAULT 'published';
+CREATE INDEX chirps_scheduled_idx ON chirps (publish_at) WHERE status = 'scheduled';
+
+-- +goose Down
+DROP INDEX chirps_scheduled_idx;
+ALTER TABLE chirps DROP COLUMN status, DROP COLUMN publish_at;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN rechirp_of_id UUID, ADD COLUMN quote_of_id UUID, ADD CONSTRAINT fk_rechirp_of FOREIGN KEY (rechirp_of_id) REFERENCES chirps(id) ON DELETE CASCADE, ADD CONSTRAINT fk_quote_of FOREIGN KEY (quote_of_id) REFERENCES chirps(id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX chirps_rechirp_once_idx ON chirps (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_rechirp_once_idx;
+ALTER TABLE chirps DROP CONSTRAINT fk_quote_of, DROP CONSTRAINT fk_rechirp_of, DROP COLUMN quote_of_id, DROP COLUMN rechirp_of_id;
@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE bookmark_collections (id UUID PRIMARY KEY, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, user_id UUID NOT NULL, name TEXT NOT NULL, UNIQUE (user_id, name), CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE);
+CREATE TABLE bookmarks (id UUID PRIMARY KEY, created_at TIMESTAMP NOT NULL, user_id UUID NOT NULL, chirp_id UUID NOT NULL, collection_id UUID, UNIQUE (user_id, chirp_id), CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE, CONSTRAINT fk_chirp FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE, CONSTRAINT fk_collection FOREIGN KEY (collection_id) REFERENCES bookmark_collections(id) ON DELETE SET NULL);
+CREATE INDEX bookmarks_user_created_idx ON bookmarks (user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE bookmarks;
+DROP TABLE bookmark_collections;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX chirps_deleted_idx ON chirps (deleted_at) WHERE deleted_at IS NOT NULL;
+DROP INDEX chirps_rechirp_once_idx;
+CREATE UNIQUE INDEX chirps_rechirp_once_idx ON chirps (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX chirps_rechirp_once_idx;
+CREATE UNIQUE INDEX chirps_rechirp_once_idx ON chirps (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL;
+DROP INDEX chirps_deleted_idx;
+ALTER TABLE chirps DROP COLUMN deleted_at;
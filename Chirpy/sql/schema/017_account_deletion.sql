@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN deletion_requested_at TIMESTAMP;
+CREATE INDEX users_deletion_requested_idx ON users (deletion_requested_at) WHERE deletion_requested_at IS NOT NULL;
+CREATE TABLE data_exports (id UUID PRIMARY KEY, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, user_id UUID NOT NULL, status TEXT NOT NULL DEFAULT 'pending', archive BYTEA, error TEXT, completed_at TIMESTAMP, expires_at TIMESTAMP, CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE);
+CREATE INDEX data_exports_pending_idx ON data_exports (created_at) WHERE status IN ('pending', 'processing');
+
+-- +goose Down
+DROP TABLE data_exports;
+DROP INDEX users_deletion_requested_idx;
+ALTER TABLE users DROP COLUMN deletion_requested_at;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- is_admin used to be added by 016, so databases migrated before it moved
+-- here already have it.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN is_admin;
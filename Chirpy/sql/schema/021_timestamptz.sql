@@ -0,0 +1,46 @@
+-- +goose Up
+-- Timestamps were TIMESTAMP, which holds no time zone: NOW() wrote the
+-- server's local time while the app wrote UTC, so comparisons between the
+-- two were off by the server's UTC offset. Existing values are read as
+-- server-local time, which is how NOW() wrote them, except publish_at, which
+-- only the app wrote.
+ALTER TABLE users ALTER COLUMN created_at TYPE TIMESTAMPTZ, ALTER COLUMN updated_at TYPE TIMESTAMPTZ, ALTER COLUMN deletion_requested_at TYPE TIMESTAMPTZ, ALTER COLUMN disabled_at TYPE TIMESTAMPTZ;
+ALTER TABLE chirps ALTER COLUMN created_at TYPE TIMESTAMPTZ, ALTER COLUMN updated_at TYPE TIMESTAMPTZ, ALTER COLUMN publish_at TYPE TIMESTAMPTZ USING publish_at AT TIME ZONE 'UTC', ALTER COLUMN deleted_at TYPE TIMESTAMPTZ;
+ALTER TABLE refresh_tokens ALTER COLUMN created_at TYPE TIMESTAMPTZ, ALTER COLUMN updated_at TYPE TIMESTAMPTZ, ALTER COLUMN expires_at TYPE TIMESTAMPTZ, ALTER COLUMN revoked_at TYPE TIMESTAMPTZ;
+ALTER TABLE webhook_subscriptions ALTER COLUMN created_at TYPE TIMESTAMPTZ, ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+ALTER TABLE webhook_deliveries ALTER COLUMN created_at TYPE TIMESTAMPTZ, ALTER COLUMN updated_at TYPE TIMESTAMPTZ, ALTER COLUMN next_attempt_at TYPE TIMESTAMPTZ, ALTER COLUMN delivered_at TYPE TIMESTAMPTZ;
+ALTER TABLE chirp_events ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+ALTER TABLE chirp_likes ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+ALTER TABLE notifications ALTER COLUMN created_at TYPE TIMESTAMPTZ, ALTER COLUMN read_at TYPE TIMESTAMPTZ;
+ALTER TABLE notification_preferences ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+ALTER TABLE user_blocks ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+ALTER TABLE conversations ALTER COLUMN created_at TYPE TIMESTAMPTZ, ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+ALTER TABLE conversation_participants ALTER COLUMN joined_at TYPE TIMESTAMPTZ, ALTER COLUMN last_read_at TYPE TIMESTAMPTZ;
+ALTER TABLE messages ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+ALTER TABLE chirp_attachments ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+ALTER TABLE user_mutes ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+ALTER TABLE bookmark_collections ALTER COLUMN created_at TYPE TIMESTAMPTZ, ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+ALTER TABLE bookmarks ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+ALTER TABLE data_exports ALTER COLUMN created_at TYPE TIMESTAMPTZ, ALTER COLUMN updated_at TYPE TIMESTAMPTZ, ALTER COLUMN completed_at TYPE TIMESTAMPTZ, ALTER COLUMN expires_at TYPE TIMESTAMPTZ;
+ALTER TABLE rate_limit_buckets ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE users ALTER COLUMN created_at TYPE TIMESTAMP, ALTER COLUMN updated_at TYPE TIMESTAMP, ALTER COLUMN deletion_requested_at TYPE TIMESTAMP, ALTER COLUMN disabled_at TYPE TIMESTAMP;
+ALTER TABLE chirps ALTER COLUMN created_at TYPE TIMESTAMP, ALTER COLUMN updated_at TYPE TIMESTAMP, ALTER COLUMN publish_at TYPE TIMESTAMP USING publish_at AT TIME ZONE 'UTC', ALTER COLUMN deleted_at TYPE TIMESTAMP;
+ALTER TABLE refresh_tokens ALTER COLUMN created_at TYPE TIMESTAMP, ALTER COLUMN updated_at TYPE TIMESTAMP, ALTER COLUMN expires_at TYPE TIMESTAMP, ALTER COLUMN revoked_at TYPE TIMESTAMP;
+ALTER TABLE webhook_subscriptions ALTER COLUMN created_at TYPE TIMESTAMP, ALTER COLUMN updated_at TYPE TIMESTAMP;
+ALTER TABLE webhook_deliveries ALTER COLUMN created_at TYPE TIMESTAMP, ALTER COLUMN updated_at TYPE TIMESTAMP, ALTER COLUMN next_attempt_at TYPE TIMESTAMP, ALTER COLUMN delivered_at TYPE TIMESTAMP;
+ALTER TABLE chirp_events ALTER COLUMN created_at TYPE TIMESTAMP;
+ALTER TABLE chirp_likes ALTER COLUMN created_at TYPE TIMESTAMP;
+ALTER TABLE notifications ALTER COLUMN created_at TYPE TIMESTAMP, ALTER COLUMN read_at TYPE TIMESTAMP;
+ALTER TABLE notification_preferences ALTER COLUMN updated_at TYPE TIMESTAMP;
+ALTER TABLE user_blocks ALTER COLUMN created_at TYPE TIMESTAMP;
+ALTER TABLE conversations ALTER COLUMN created_at TYPE TIMESTAMP, ALTER COLUMN updated_at TYPE TIMESTAMP;
+ALTER TABLE conversation_participants ALTER COLUMN joined_at TYPE TIMESTAMP, ALTER COLUMN last_read_at TYPE TIMESTAMP;
+ALTER TABLE messages ALTER COLUMN created_at TYPE TIMESTAMP;
+ALTER TABLE chirp_attachments ALTER COLUMN created_at TYPE TIMESTAMP;
+ALTER TABLE user_mutes ALTER COLUMN created_at TYPE TIMESTAMP;
+ALTER TABLE bookmark_collections ALTER COLUMN created_at TYPE TIMESTAMP, ALTER COLUMN updated_at TYPE TIMESTAMP;
+ALTER TABLE bookmarks ALTER COLUMN created_at TYPE TIMESTAMP;
+ALTER TABLE data_exports ALTER COLUMN created_at TYPE TIMESTAMP, ALTER COLUMN updated_at TYPE TIMESTAMP, ALTER COLUMN completed_at TYPE TIMESTAMP, ALTER COLUMN expires_at TYPE TIMESTAMP;
+ALTER TABLE rate_limit_buckets ALTER COLUMN updated_at TYPE TIMESTAMP;
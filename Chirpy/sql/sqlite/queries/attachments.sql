@@ -0,0 +1,27 @@
+-- name: CreateChirpAttachment :one
+INSERT INTO chirp_attachments (id, created_at, chirp_id, position, content_type, size_bytes, width, height, storage_key, thumbnail_key)
+VALUES (
+    (SELECT uuid FROM gen_random_uuid),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7,
+    ?8
+  )
+RETURNING id, created_at, chirp_id, position, content_type, size_bytes, width, height, storage_key, thumbnail_key;
+
+-- name: ListAttachmentsByChirpID :many
+SELECT id, created_at, chirp_id, position, content_type, size_bytes, width, height, storage_key, thumbnail_key FROM chirp_attachments WHERE chirp_id = ?1 ORDER BY position ASC;
+
+-- name: ListAttachmentsByChirpIDs :many
+SELECT id, created_at, chirp_id, position, content_type, size_bytes, width, height, storage_key, thumbnail_key FROM chirp_attachments WHERE chirp_id IN (SELECT value FROM json_each('[' || substr(?1, 2, length(?1) - 2) || ']')) ORDER BY chirp_id, position ASC;
+
+-- name: ListAttachmentsByUserID :many
+SELECT chirp_attachments.id, chirp_attachments.created_at, chirp_attachments.chirp_id, chirp_attachments.position, chirp_attachments.content_type, chirp_attachments.size_bytes, chirp_attachments.width, chirp_attachments.height, chirp_attachments.storage_key, chirp_attachments.thumbnail_key FROM chirp_attachments
+JOIN chirps ON chirps.id = chirp_attachments.chirp_id
+WHERE chirps.user_id = ?1
+ORDER BY chirp_attachments.chirp_id, chirp_attachments.position ASC;
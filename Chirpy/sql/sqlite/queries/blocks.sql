@@ -0,0 +1,68 @@
+-- name: BlockUser :exec
+INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+VALUES (
+    ?1,
+    ?2,
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+  )
+ON CONFLICT DO NOTHING;
+
+-- name: IsBlocked :one
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks WHERE blocker_id = ?1 AND blocked_id = ?2
+  );
+
+-- name: IsBlockedEitherWay :one
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (blocker_id = ?1 AND blocked_id = ?2)
+       OR (blocker_id = ?2 AND blocked_id = ?1)
+  );
+
+-- name: IsSilenced :one
+SELECT (
+    EXISTS (
+      SELECT 1 FROM user_blocks
+      WHERE (blocker_id = ?1 AND blocked_id = ?2)
+         OR (blocker_id = ?2 AND blocked_id = ?1)
+    )
+    OR EXISTS (
+      SELECT 1 FROM user_mutes WHERE muter_id = ?1 AND muted_id = ?2
+    )
+  ) AS silenced;
+
+-- name: ListBlockedUsers :many
+SELECT users.id, users.hashed_password, users.created_at, users.updated_at, users.email, users.is_chirpy_red, users.handle, users.display_name, users.bio, users.avatar_key, users.is_admin, users.deletion_requested_at, users.disabled_at FROM users
+JOIN user_blocks ON user_blocks.blocked_id = users.id
+WHERE user_blocks.blocker_id = ?1
+ORDER BY user_blocks.created_at DESC
+LIMIT ?2 OFFSET ?3;
+
+-- name: ListHiddenAuthorIDs :many
+SELECT blocked_id AS user_id FROM user_blocks WHERE user_blocks.blocker_id = ?1
+UNION
+SELECT blocker_id FROM user_blocks WHERE user_blocks.blocked_id = ?1
+UNION
+SELECT muted_id FROM user_mutes WHERE muter_id = ?1;
+
+-- name: ListMutedUsers :many
+SELECT users.id, users.hashed_password, users.created_at, users.updated_at, users.email, users.is_chirpy_red, users.handle, users.display_name, users.bio, users.avatar_key, users.is_admin, users.deletion_requested_at, users.disabled_at FROM users
+JOIN user_mutes ON user_mutes.muted_id = users.id
+WHERE user_mutes.muter_id = ?1
+ORDER BY user_mutes.created_at DESC
+LIMIT ?2 OFFSET ?3;
+
+-- name: MuteUser :exec
+INSERT INTO user_mutes (muter_id, muted_id, created_at)
+VALUES (
+    ?1,
+    ?2,
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+  )
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :execrows
+DELETE FROM user_blocks WHERE blocker_id = ?1 AND blocked_id = ?2;
+
+-- name: UnmuteUser :execrows
+DELETE FROM user_mutes WHERE muter_id = ?1 AND muted_id = ?2;
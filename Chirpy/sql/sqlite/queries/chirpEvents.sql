@@ -0,0 +1,24 @@
+-- name: CreateChirpEvent :one
+INSERT INTO chirp_events (created_at, type, chirp_id, user_id, payload)
+VALUES (
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?1,
+    ?2,
+    ?3,
+    ?4
+  )
+RETURNING id, created_at, type, chirp_id, user_id, payload;
+
+-- name: DeleteChirpEventsOlderThan :exec
+DELETE FROM chirp_events WHERE created_at < strftime('%Y-%m-%d %H:%M:%f', 'now', '-' || ?1 || ' seconds');
+
+-- name: ListChirpEventsSince :many
+SELECT id, created_at, type, chirp_id, user_id, payload FROM chirp_events WHERE id > ?1 ORDER BY id ASC LIMIT ?2;
+
+-- name: ListChirpEventsSinceByAuthorID :many
+SELECT id, created_at, type, chirp_id, user_id, payload FROM chirp_events WHERE id > ?1 AND user_id = ?2 ORDER BY id ASC LIMIT ?3;
+
+-- name: NotifyChirpEvent :exec
+-- SQLite has no LISTEN/NOTIFY; the broker only notifies when listening,
+-- which it never does on SQLite.
+SELECT ?1;
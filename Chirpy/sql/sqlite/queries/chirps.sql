@@ -0,0 +1,130 @@
+-- name: CountChirpsByAuthorID :one
+SELECT COUNT(*) FROM chirps WHERE user_id = ?1;
+
+-- name: CountRechirpsByChirpIDs :many
+SELECT rechirp_of_id, COUNT(*) AS rechirp_count FROM chirps
+WHERE deleted_at IS NULL AND rechirp_of_id IN (SELECT value FROM json_each('[' || substr(?1, 2, length(?1) - 2) || ']'))
+GROUP BY rechirp_of_id;
+
+-- name: CreateChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to_id, quote_of_id)
+VALUES (
+    (SELECT uuid FROM gen_random_uuid),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?1,
+    ?2,
+    ?3,
+    ?4
+  )
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at;
+
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of_id)
+VALUES (
+    (SELECT uuid FROM gen_random_uuid),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    '',
+    ?1,
+    ?2
+  )
+ON CONFLICT (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL AND deleted_at IS NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at;
+
+-- name: CreateScheduledChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to_id, quote_of_id, publish_at, status)
+VALUES (
+    (SELECT uuid FROM gen_random_uuid),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    'scheduled'
+  )
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at;
+
+-- name: DeleteChirpById :one
+UPDATE chirps SET deleted_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?1 AND deleted_at IS NULL RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at;
+
+-- name: DeleteRechirp :one
+DELETE FROM chirps WHERE user_id = ?1 AND rechirp_of_id = ?2 AND deleted_at IS NULL RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at;
+
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM chirps WHERE id = ?1 AND status = 'scheduled';
+
+-- name: GetDeletedChirp :one
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE id = ?1 AND deleted_at IS NOT NULL;
+
+-- name: GetRechirp :one
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE user_id = ?1 AND rechirp_of_id = ?2 AND deleted_at IS NULL;
+
+-- name: ListAllChirpsByAuthorID :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE user_id = ?1 ORDER BY created_at ASC;
+
+-- name: ListChirp :one
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE id = ?1 AND deleted_at IS NULL;
+
+-- name: ListChirpByAuthorID :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE user_id = ?1 AND status = 'published' AND deleted_at IS NULL ORDER BY created_at ASC;
+
+-- name: ListChirpByAuthorIDDesc :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE user_id = ?1 AND status = 'published' AND deleted_at IS NULL ORDER BY created_at DESC;
+
+-- name: ListChirps :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE status = 'published' AND deleted_at IS NULL ORDER BY created_at ASC;
+
+-- name: ListChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE id IN (SELECT value FROM json_each('[' || substr(?1, 2, length(?1) - 2) || ']')) AND deleted_at IS NULL;
+
+-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE status = 'published' AND deleted_at IS NULL ORDER BY created_at DESC;
+
+-- name: ListPurgeableChirpIDs :many
+SELECT id FROM chirps
+WHERE deleted_at < strftime('%Y-%m-%d %H:%M:%f', 'now', '-' || ?1 || ' seconds')
+ORDER BY deleted_at ASC
+LIMIT ?2;
+
+-- name: ListRechirpsOf :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE rechirp_of_id = ?1 AND deleted_at IS NULL;
+
+-- name: ListScheduledChirps :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at FROM chirps
+WHERE user_id = ?1 AND status = 'scheduled' AND deleted_at IS NULL
+ORDER BY publish_at ASC
+LIMIT ?2 OFFSET ?3;
+
+-- name: PublishDueChirps :many
+UPDATE chirps SET status = 'published', created_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id IN (
+    SELECT id FROM chirps
+    WHERE status = 'scheduled' AND publish_at <= strftime('%Y-%m-%d %H:%M:%f', 'now') AND deleted_at IS NULL
+    ORDER BY publish_at ASC
+    LIMIT ?1
+  )
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at;
+
+-- name: PurgeChirps :execrows
+DELETE FROM chirps WHERE id IN (SELECT value FROM json_each('[' || substr(?1, 2, length(?1) - 2) || ']')) AND deleted_at IS NOT NULL;
+
+-- name: RescheduleChirp :one
+UPDATE chirps SET publish_at = ?1, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?2 AND status = 'scheduled' AND deleted_at IS NULL RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at;
+
+-- name: ResetChirps :exec
+DELETE FROM chirps;
+
+-- name: RestoreChirp :one
+UPDATE chirps SET deleted_at = NULL WHERE id = ?1 AND deleted_at > strftime('%Y-%m-%d %H:%M:%f', 'now', '-' || ?2 || ' seconds') RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at;
+
+-- name: RestoreRechirpsOf :exec
+UPDATE chirps SET deleted_at = NULL WHERE rechirp_of_id = ?1 AND deleted_at = ?2;
+
+-- name: SoftDeleteRechirpsOf :exec
+UPDATE chirps SET deleted_at = ?2 WHERE rechirp_of_id = ?1 AND deleted_at IS NULL;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps SET body = ?1, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?2 AND deleted_at IS NULL RETURNING id, created_at, updated_at, body, user_id, reply_to_id, publish_at, status, rechirp_of_id, quote_of_id, deleted_at;
@@ -0,0 +1,39 @@
+-- name: ClaimPendingDataExports :many
+UPDATE data_exports SET status = 'processing', updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id IN (
+    SELECT id FROM data_exports
+    WHERE status = 'pending'
+       OR (status = 'processing' AND updated_at < strftime('%Y-%m-%d %H:%M:%f', 'now', '-' || ?1 || ' seconds'))
+    ORDER BY created_at ASC
+    LIMIT ?2
+  )
+RETURNING id, created_at, updated_at, user_id, status, archive, error, completed_at, expires_at;
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports SET status = 'ready', archive = ?1, completed_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), expires_at = strftime('%Y-%m-%d %H:%M:%f', 'now', '+' || ?2 || ' seconds'), updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?3;
+
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, created_at, updated_at, user_id, status)
+VALUES (
+    (SELECT uuid FROM gen_random_uuid),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?1,
+    'pending'
+  )
+RETURNING id, created_at, updated_at, user_id, status, archive, error, completed_at, expires_at;
+
+-- name: DeleteExpiredDataExports :execrows
+DELETE FROM data_exports WHERE expires_at < strftime('%Y-%m-%d %H:%M:%f', 'now');
+
+-- name: FailDataExport :exec
+UPDATE data_exports SET status = 'failed', error = ?1, completed_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), expires_at = strftime('%Y-%m-%d %H:%M:%f', 'now', '+' || ?2 || ' seconds'), updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?3;
+
+-- name: GetActiveDataExport :one
+SELECT id, created_at, updated_at, user_id, status, archive, error, completed_at, expires_at FROM data_exports
+WHERE user_id = ?1 AND status IN ('pending', 'processing')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetDataExport :one
+SELECT id, created_at, updated_at, user_id, status, archive, error, completed_at, expires_at FROM data_exports WHERE id = ?1 AND user_id = ?2;
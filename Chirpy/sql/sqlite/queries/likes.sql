@@ -0,0 +1,14 @@
+-- name: LikeChirp :execrows
+INSERT INTO chirp_likes (chirp_id, user_id, created_at)
+VALUES (
+    ?1,
+    ?2,
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+  )
+ON CONFLICT DO NOTHING;
+
+-- name: ListLikesByUserID :many
+SELECT chirp_id, user_id, created_at FROM chirp_likes WHERE user_id = ?1 ORDER BY created_at ASC;
+
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes WHERE chirp_id = ?1 AND user_id = ?2;
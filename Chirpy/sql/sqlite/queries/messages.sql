@@ -0,0 +1,64 @@
+-- name: AddConversationParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at, last_read_at)
+VALUES (
+    ?1,
+    ?2,
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    null
+  );
+
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, is_group)
+VALUES (
+    (SELECT uuid FROM gen_random_uuid),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?1
+  )
+RETURNING id, created_at, updated_at, is_group;
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    (SELECT uuid FROM gen_random_uuid),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?1,
+    ?2,
+    ?3
+  )
+RETURNING id, created_at, conversation_id, sender_id, body;
+
+-- name: FindDirectConversation :one
+SELECT conversations.id, conversations.created_at, conversations.updated_at, conversations.is_group FROM conversations
+WHERE conversations.is_group = FALSE
+  AND EXISTS (SELECT 1 FROM conversation_participants WHERE conversation_id = conversations.id AND user_id = ?1)
+  AND EXISTS (SELECT 1 FROM conversation_participants WHERE conversation_id = conversations.id AND user_id = ?2)
+LIMIT 1;
+
+-- name: GetConversation :one
+SELECT id, created_at, updated_at, is_group FROM conversations WHERE id = ?1;
+
+-- name: GetConversationParticipant :one
+SELECT conversation_id, user_id, joined_at, last_read_at FROM conversation_participants WHERE conversation_id = ?1 AND user_id = ?2;
+
+-- name: ListConversationParticipants :many
+SELECT conversation_id, user_id, joined_at, last_read_at FROM conversation_participants WHERE conversation_id = ?1 ORDER BY joined_at ASC;
+
+-- name: ListConversationsByUserID :many
+SELECT conversations.id, conversations.created_at, conversations.updated_at, conversations.is_group FROM conversations
+JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+WHERE conversation_participants.user_id = ?1
+ORDER BY conversations.updated_at DESC
+LIMIT ?2 OFFSET ?3;
+
+-- name: ListMessagesByConversationID :many
+SELECT id, created_at, conversation_id, sender_id, body FROM messages WHERE conversation_id = ?1 ORDER BY created_at DESC LIMIT ?2 OFFSET ?3;
+
+-- name: ListMessagesBySenderID :many
+SELECT id, created_at, conversation_id, sender_id, body FROM messages WHERE sender_id = ?1 ORDER BY created_at ASC;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_participants SET last_read_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE conversation_id = ?1 AND user_id = ?2;
+
+-- name: TouchConversation :exec
+UPDATE conversations SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?1;
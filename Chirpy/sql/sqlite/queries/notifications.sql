@@ -0,0 +1,45 @@
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications WHERE user_id = ?1 AND read_at IS NULL;
+
+-- name: CreateNotification :one
+INSERT INTO notifications (id, created_at, user_id, actor_id, type, chirp_id, read_at)
+VALUES (
+    (SELECT uuid FROM gen_random_uuid),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    null
+  )
+RETURNING id, created_at, user_id, actor_id, type, chirp_id, read_at;
+
+-- name: GetNotification :one
+SELECT id, created_at, user_id, actor_id, type, chirp_id, read_at FROM notifications WHERE id = ?1;
+
+-- name: GetNotificationPreferences :one
+SELECT user_id, updated_at, mentions, replies, likes FROM notification_preferences WHERE user_id = ?1;
+
+-- name: ListNotificationsByUserID :many
+SELECT id, created_at, user_id, actor_id, type, chirp_id, read_at FROM notifications WHERE user_id = ?1 ORDER BY created_at DESC LIMIT ?2 OFFSET ?3;
+
+-- name: ListUnreadNotificationsByUserID :many
+SELECT id, created_at, user_id, actor_id, type, chirp_id, read_at FROM notifications WHERE user_id = ?1 AND read_at IS NULL ORDER BY created_at DESC LIMIT ?2 OFFSET ?3;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications SET read_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE user_id = ?1 AND read_at IS NULL;
+
+-- name: MarkNotificationRead :exec
+UPDATE notifications SET read_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?1 AND read_at IS NULL;
+
+-- name: UpsertNotificationPreferences :one
+INSERT INTO notification_preferences (user_id, updated_at, mentions, replies, likes)
+VALUES (
+    ?1,
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?2,
+    ?3,
+    ?4
+  )
+ON CONFLICT (user_id) DO UPDATE SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), mentions = EXCLUDED.mentions, replies = EXCLUDED.replies, likes = EXCLUDED.likes
+RETURNING user_id, updated_at, mentions, replies, likes;
@@ -0,0 +1,12 @@
+-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets WHERE updated_at < strftime('%Y-%m-%d %H:%M:%f', 'now', '-' || ?1 || ' seconds');
+
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at)
+VALUES (?1, ?2 - 1, TRUE, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+ON CONFLICT (key) DO UPDATE SET
+    allowed = min(?2, rate_limit_buckets.tokens + ?3 * (julianday('now') - julianday(rate_limit_buckets.updated_at)) * 86400) >= 1,
+    tokens = min(?2, rate_limit_buckets.tokens + ?3 * (julianday('now') - julianday(rate_limit_buckets.updated_at)) * 86400)
+        - CASE WHEN min(?2, rate_limit_buckets.tokens + ?3 * (julianday('now') - julianday(rate_limit_buckets.updated_at)) * 86400) >= 1 THEN 1 ELSE 0 END,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+RETURNING tokens, allowed;
@@ -0,0 +1,26 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+VALUES (
+    ?1,
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?2,
+    strftime('%Y-%m-%d %H:%M:%f', 'now', '+' || ?3 || ' seconds'),
+    null
+)
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at;
+
+-- name: GetRefreshToken :one
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at FROM refresh_tokens WHERE token = ?1;
+
+-- name: GetUserByRefreshToken :one
+SELECT user_id FROM refresh_tokens WHERE token = ?1;
+
+-- name: ListRefreshTokensByUserID :many
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at FROM refresh_tokens WHERE user_id = ?1 ORDER BY created_at ASC;
+
+-- name: RevokeRefreshTokensByUserID :exec
+UPDATE refresh_tokens SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), revoked_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE user_id = ?1 AND revoked_at IS NULL;
+
+-- name: UpdateRefreshToken :exec
+UPDATE refresh_tokens SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), revoked_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE token = ?1;
@@ -0,0 +1,59 @@
+-- name: CancelUserDeletion :one
+UPDATE users SET deletion_requested_at = NULL, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?1 AND deletion_requested_at IS NOT NULL RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at;
+
+-- name: CreateUser :one
+INSERT INTO users (id, hashed_password, created_at, updated_at, email, handle)
+VALUES (
+    (SELECT uuid FROM gen_random_uuid),
+    ?1,
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?2,
+    ?3
+  )
+RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at;
+
+-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = ?1 AND deletion_requested_at < strftime('%Y-%m-%d %H:%M:%f', 'now', '-' || ?2 || ' seconds');
+
+-- name: DisableUser :one
+UPDATE users SET disabled_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?1 AND disabled_at IS NULL RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at;
+
+-- name: GetUserByEmail :one
+SELECT id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at FROM users WHERE email = ?1;
+
+-- name: GetUserByHandle :one
+SELECT id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at FROM users WHERE handle = ?1;
+
+-- name: GetUserByID :one
+SELECT id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at FROM users WHERE id = ?1;
+
+-- name: ListUsersByIDs :many
+SELECT id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at FROM users WHERE id IN (SELECT value FROM json_each('[' || substr(?1, 2, length(?1) - 2) || ']'));
+
+-- name: ListUsersDueForDeletion :many
+SELECT id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at FROM users
+WHERE deletion_requested_at < strftime('%Y-%m-%d %H:%M:%f', 'now', '-' || ?1 || ' seconds')
+ORDER BY deletion_requested_at ASC
+LIMIT ?2;
+
+-- name: RequestUserDeletion :one
+UPDATE users SET deletion_requested_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?1 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at;
+
+-- name: ResetUsers :exec
+DELETE FROM users;
+
+-- name: UpdateUserAdminByID :one
+UPDATE users SET is_admin = ?1, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?2 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at;
+
+-- name: UpdateUserAvatar :one
+UPDATE users SET avatar_key = ?1, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?2 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at;
+
+-- name: UpdateUserByID :one
+UPDATE users SET email = ?1, hashed_password = ?2 WHERE id = ?3 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at;
+
+-- name: UpdateUserChirpyRedByID :one
+UPDATE users SET is_chirpy_red = ?1 WHERE id = ?2 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at;
+
+-- name: UpdateUserProfile :one
+UPDATE users SET handle = ?1, display_name = ?2, bio = ?3, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?4 RETURNING id, hashed_password, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, avatar_key, is_admin, deletion_requested_at, disabled_at;
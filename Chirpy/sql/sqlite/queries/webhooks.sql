@@ -0,0 +1,59 @@
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries SET next_attempt_at = strftime('%Y-%m-%d %H:%M:%f', 'now', '+1 minute'), updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= strftime('%Y-%m-%d %H:%M:%f', 'now')
+    ORDER BY next_attempt_at ASC
+    LIMIT ?1
+  )
+RETURNING id, created_at, updated_at, subscription_id, event, payload, status, attempts, next_attempt_at, last_status_code, last_error, delivered_at;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (id, created_at, updated_at, subscription_id, event, payload, status, attempts, next_attempt_at)
+VALUES (
+    (SELECT uuid FROM gen_random_uuid),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?1,
+    ?2,
+    ?3,
+    'pending',
+    0,
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+  )
+RETURNING id, created_at, updated_at, subscription_id, event, payload, status, attempts, next_attempt_at, last_status_code, last_error, delivered_at;
+
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (id, created_at, updated_at, user_id, url, secret, events, active)
+VALUES (
+    (SELECT uuid FROM gen_random_uuid),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    TRUE
+  )
+RETURNING id, created_at, updated_at, user_id, url, secret, events, active;
+
+-- name: DeleteWebhookSubscription :exec
+DELETE FROM webhook_subscriptions WHERE id = ?1;
+
+-- name: GetWebhookSubscription :one
+SELECT id, created_at, updated_at, user_id, url, secret, events, active FROM webhook_subscriptions WHERE id = ?1;
+
+-- name: ListWebhookDeliveriesBySubscriptionID :many
+SELECT id, created_at, updated_at, subscription_id, event, payload, status, attempts, next_attempt_at, last_status_code, last_error, delivered_at FROM webhook_deliveries WHERE subscription_id = ?1 ORDER BY created_at DESC LIMIT ?2;
+
+-- name: ListWebhookSubscriptionsByUserID :many
+SELECT id, created_at, updated_at, user_id, url, secret, events, active FROM webhook_subscriptions WHERE user_id = ?1 ORDER BY created_at ASC;
+
+-- name: ListWebhookSubscriptionsForEvent :many
+SELECT id, created_at, updated_at, user_id, url, secret, events, active FROM webhook_subscriptions WHERE active = TRUE AND instr(events, '"' || ?1 || '"') > 0;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries SET status = ?1, attempts = attempts + 1, last_status_code = ?2, last_error = ?3, next_attempt_at = strftime('%Y-%m-%d %H:%M:%f', 'now', '+' || ?4 || ' seconds'), updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?5;
+
+-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries SET status = 'delivered', attempts = attempts + 1, last_status_code = ?2, last_error = NULL, delivered_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = ?1;
@@ -0,0 +1,74 @@
+-- The SQLite schema starts from the state of the Postgres migrations up to
+-- 019_user_disabled.sql. Later schema changes need a migration in both
+-- directories. UUIDs and arrays are stored as text, timestamps as text in
+-- UTC with milliseconds.
+
+-- +goose Up
+CREATE VIEW gen_random_uuid AS SELECT lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))) AS uuid;
+
+CREATE TABLE users (id TEXT PRIMARY KEY, hashed_password TEXT NOT NULL, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, email TEXT NOT NULL UNIQUE, is_chirpy_red BOOLEAN NOT NULL DEFAULT FALSE, handle TEXT NOT NULL UNIQUE, display_name TEXT NOT NULL DEFAULT '', bio TEXT NOT NULL DEFAULT '', avatar_key TEXT, is_admin BOOLEAN NOT NULL DEFAULT FALSE, deletion_requested_at TIMESTAMP, disabled_at TIMESTAMP);
+CREATE INDEX users_deletion_requested_idx ON users (deletion_requested_at) WHERE deletion_requested_at IS NOT NULL;
+
+CREATE TABLE chirps (id TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, body TEXT NOT NULL, user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, reply_to_id TEXT REFERENCES chirps(id) ON DELETE SET NULL, publish_at TIMESTAMP, status TEXT NOT NULL DEFAULT 'published', rechirp_of_id TEXT REFERENCES chirps(id) ON DELETE CASCADE, quote_of_id TEXT REFERENCES chirps(id) ON DELETE SET NULL, deleted_at TIMESTAMP);
+CREATE INDEX chirps_scheduled_idx ON chirps (publish_at) WHERE status = 'scheduled';
+CREATE INDEX chirps_deleted_idx ON chirps (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE UNIQUE INDEX chirps_rechirp_once_idx ON chirps (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL AND deleted_at IS NULL;
+
+CREATE TABLE refresh_tokens (token TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, expires_at TIMESTAMP NOT NULL, revoked_at TIMESTAMP);
+
+CREATE TABLE webhook_subscriptions (id TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, url TEXT NOT NULL, secret TEXT NOT NULL, events TEXT NOT NULL, active BOOLEAN NOT NULL DEFAULT TRUE);
+CREATE TABLE webhook_deliveries (id TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, subscription_id TEXT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE, event TEXT NOT NULL, payload BLOB NOT NULL, status TEXT NOT NULL DEFAULT 'pending', attempts INTEGER NOT NULL DEFAULT 0, next_attempt_at TIMESTAMP NOT NULL, last_status_code INTEGER, last_error TEXT, delivered_at TIMESTAMP);
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE chirp_events (id INTEGER PRIMARY KEY AUTOINCREMENT, created_at TIMESTAMP NOT NULL, type TEXT NOT NULL, chirp_id TEXT NOT NULL, user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, payload BLOB NOT NULL);
+CREATE INDEX chirp_events_user_id_idx ON chirp_events (user_id, id);
+
+CREATE TABLE chirp_likes (chirp_id TEXT NOT NULL REFERENCES chirps(id) ON DELETE CASCADE, user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, created_at TIMESTAMP NOT NULL, PRIMARY KEY (chirp_id, user_id));
+
+CREATE TABLE notifications (id TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL, user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, actor_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, type TEXT NOT NULL, chirp_id TEXT REFERENCES chirps(id) ON DELETE CASCADE, read_at TIMESTAMP);
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, created_at DESC);
+CREATE TABLE notification_preferences (user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE, updated_at TIMESTAMP NOT NULL, mentions BOOLEAN NOT NULL DEFAULT TRUE, replies BOOLEAN NOT NULL DEFAULT TRUE, likes BOOLEAN NOT NULL DEFAULT TRUE);
+
+CREATE TABLE user_blocks (blocker_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, blocked_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, created_at TIMESTAMP NOT NULL, PRIMARY KEY (blocker_id, blocked_id));
+CREATE TABLE user_mutes (muter_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, muted_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, created_at TIMESTAMP NOT NULL, PRIMARY KEY (muter_id, muted_id));
+
+CREATE TABLE conversations (id TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, is_group BOOLEAN NOT NULL DEFAULT FALSE);
+CREATE TABLE conversation_participants (conversation_id TEXT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE, user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, joined_at TIMESTAMP NOT NULL, last_read_at TIMESTAMP, PRIMARY KEY (conversation_id, user_id));
+CREATE INDEX conversation_participants_user_id_idx ON conversation_participants (user_id);
+CREATE TABLE messages (id TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL, conversation_id TEXT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE, sender_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, body TEXT NOT NULL);
+CREATE INDEX messages_conversation_id_idx ON messages (conversation_id, created_at DESC);
+
+CREATE TABLE chirp_attachments (id TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL, chirp_id TEXT NOT NULL REFERENCES chirps(id) ON DELETE CASCADE, position INTEGER NOT NULL, content_type TEXT NOT NULL, size_bytes INTEGER NOT NULL, width INTEGER NOT NULL, height INTEGER NOT NULL, storage_key TEXT NOT NULL, thumbnail_key TEXT NOT NULL);
+CREATE INDEX chirp_attachments_chirp_id_idx ON chirp_attachments (chirp_id, position);
+
+CREATE TABLE bookmark_collections (id TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, name TEXT NOT NULL, UNIQUE (user_id, name));
+CREATE TABLE bookmarks (id TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL, user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, chirp_id TEXT NOT NULL REFERENCES chirps(id) ON DELETE CASCADE, collection_id TEXT REFERENCES bookmark_collections(id) ON DELETE SET NULL, UNIQUE (user_id, chirp_id));
+CREATE INDEX bookmarks_user_created_idx ON bookmarks (user_id, created_at DESC);
+
+CREATE TABLE data_exports (id TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE, status TEXT NOT NULL DEFAULT 'pending', archive BLOB, error TEXT, completed_at TIMESTAMP, expires_at TIMESTAMP);
+CREATE INDEX data_exports_pending_idx ON data_exports (created_at) WHERE status IN ('pending', 'processing');
+
+CREATE TABLE rate_limit_buckets (key TEXT PRIMARY KEY, tokens REAL NOT NULL, allowed BOOLEAN NOT NULL, updated_at TIMESTAMP NOT NULL);
+CREATE INDEX rate_limit_buckets_updated_idx ON rate_limit_buckets (updated_at);
+
+-- +goose Down
+DROP TABLE rate_limit_buckets;
+DROP TABLE data_exports;
+DROP TABLE bookmarks;
+DROP TABLE bookmark_collections;
+DROP TABLE chirp_attachments;
+DROP TABLE messages;
+DROP TABLE conversation_participants;
+DROP TABLE conversations;
+DROP TABLE user_mutes;
+DROP TABLE user_blocks;
+DROP TABLE notification_preferences;
+DROP TABLE notifications;
+DROP TABLE chirp_likes;
+DROP TABLE chirp_events;
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_subscriptions;
+DROP TABLE refresh_tokens;
+DROP TABLE chirps;
+DROP TABLE users;
+DROP VIEW gen_random_uuid;
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"Chirpy/internal/database"
+	"Chirpy/internal/problem"
+	"Chirpy/internal/webhooks"
+)
+
+type WebhookSubscription struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode int32           `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+func mapWebhookSubscription(sub database.WebhookSubscription) WebhookSubscription {
+	return WebhookSubscription{
+		ID:        sub.ID,
+		CreatedAt: sub.CreatedAt,
+		UpdatedAt: sub.UpdatedAt,
+		URL:       sub.Url,
+		Events:    sub.Events,
+		Active:    sub.Active,
+	}
+}
+
+func mapWebhookDelivery(d database.WebhookDelivery) WebhookDelivery {
+	res := WebhookDelivery{
+		ID:             d.ID,
+		CreatedAt:      d.CreatedAt,
+		Event:          d.Event,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode.Int32,
+		LastError:      d.LastError.String,
+		Payload:        d.Payload,
+	}
+	if d.Status == webhooks.StatusPending {
+		res.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.DeliveredAt.Valid {
+		res.DeliveredAt = &d.DeliveredAt.Time
+	}
+	return res
+}
+
+// publishWebhook queues an event for outbound webhook subscribers. Failures
+// are logged rather than surfaced so they never fail the triggering request.
+func (cfg *apiConfig) publishWebhook(ctx context.Context, event string, data any) {
+	if cfg.webhooks == nil {
+		return
+	}
+	err := cfg.webhooks.Publish(ctx, event, data)
+	if err != nil {
+		slog.ErrorContext(ctx, "webhooks: publish", "event", event, "err", err)
+	}
+}
+
+func (cfg *apiConfig) createWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	u, err := url.Parse(params.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "url must be an absolute http or https URL")
+		return
+	}
+	err = webhooks.CheckURL(r.Context(), u)
+	if errors.Is(err, webhooks.ErrForbiddenTarget) {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "url must not point to a loopback, private or link-local address")
+		return
+	}
+	if err != nil {
+		respondWithError(w, r, 400, problem.CodeValidationFailed, "url host could not be resolved")
+		return
+	}
+	if len(params.Events) == 0 {
+		params.Events = webhooks.Events
+	}
+	for _, event := range params.Events {
+		if !webhooks.IsEvent(event) {
+			respondWithError(w, r, 400, problem.CodeValidationFailed, "Unknown event "+event)
+			return
+		}
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	sub, err := cfg.dbQueries.CreateWebhookSubscription(r.Context(), database.CreateWebhookSubscriptionParams{
+		UserID: userID,
+		Url:    u.String(),
+		Secret: secret,
+		Events: params.Events,
+	})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+
+	// The secret is only ever returned when the subscription is created.
+	res := mapWebhookSubscription(sub)
+	res.Secret = sub.Secret
+	respondWithJSON(w, 201, res)
+}
+
+func (cfg *apiConfig) listWebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	subs, err := cfg.dbQueries.ListWebhookSubscriptionsByUserID(r.Context(), userID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	res := []WebhookSubscription{}
+	for _, sub := range subs {
+		res = append(res, mapWebhookSubscription(sub))
+	}
+	respondWithJSON(w, 200, res)
+}
+
+// ownedWebhookSubscription loads the subscription named in the path and
+// checks that it belongs to the caller, writing the error response if not.
+func (cfg *apiConfig) ownedWebhookSubscription(w http.ResponseWriter, r *http.Request) (database.WebhookSubscription, bool) {
+	userID, ok := cfg.authenticate(w, r)
+	if !ok {
+		return database.WebhookSubscription{}, false
+	}
+	id, ok := pathUUID(w, r, "webhookID")
+	if !ok {
+		return database.WebhookSubscription{}, false
+	}
+	sub, err := cfg.dbQueries.GetWebhookSubscription(r.Context(), id)
+	if err != nil {
+		respondWithError(w, r, 404, problem.CodeNotFound, "Webhook subscription not found")
+		return database.WebhookSubscription{}, false
+	}
+	if sub.UserID != userID {
+		respondWithError(w, r, 403, problem.CodeForbidden, "Webhook subscription belongs to another user")
+		return database.WebhookSubscription{}, false
+	}
+	return sub, true
+}
+
+func (cfg *apiConfig) deleteWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, ok := cfg.ownedWebhookSubscription(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.DeleteWebhookSubscription(r.Context(), sub.ID)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	sub, ok := cfg.ownedWebhookSubscription(w, r)
+	if !ok {
+		return
+	}
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 500 {
+			respondWithError(w, r, 400, problem.CodeInvalidQuery, "limit must be between 1 and 500")
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := cfg.dbQueries.ListWebhookDeliveriesBySubscriptionID(r.Context(), database.ListWebhookDeliveriesBySubscriptionIDParams{
+		SubscriptionID: sub.ID,
+		Limit:          int32(limit),
+	})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+	res := []WebhookDelivery{}
+	for _, d := range deliveries {
+		res = append(res, mapWebhookDelivery(d))
+	}
+	respondWithJSON(w, 200, res)
+}